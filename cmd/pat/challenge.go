@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+func runChallenge(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "usage: pat challenge new|decode [arguments]\n")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "new":
+		return runChallengeNew(args[1:], stdout, stderr)
+	case "decode":
+		return runChallengeDecode(args[1:], stdout, stderr)
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+func runChallengeNew(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("challenge new", stderr)
+	tokenTypeFlag := fs.String("type", "", "token type in hex: 0001, 0002, 0003 or f91a")
+	issuerName := fs.String("issuer", "", "issuer name")
+	nonceFlag := fs.String("nonce", "", "redemption nonce in hex, or \"random\" for a fresh 32-byte nonce")
+	origins := fs.String("origins", "", "comma-separated list of origin names")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	tokenType, err := parseTokenType(*tokenTypeFlag)
+	if err != nil {
+		return err
+	}
+
+	var nonce []byte
+	switch *nonceFlag {
+	case "":
+	case "random":
+		nonce = make([]byte, 32)
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+	default:
+		nonce, err = decodeHex("nonce", *nonceFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	var originInfo []string
+	if *origins != "" {
+		originInfo = strings.Split(*origins, ",")
+	}
+
+	challenge, err := tokens.NewTokenChallenge(tokenType, *issuerName, nonce, originInfo)
+	if err != nil {
+		return err
+	}
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, encodeBase64(challengeEnc))
+	return nil
+}
+
+func runChallengeDecode(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("challenge decode", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(stderr, "usage: pat challenge decode <challenge>\n")
+		return errUsage
+	}
+
+	challenge, _, err := decodeChallenge(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "token_type:       %s\n", formatTokenType(challenge.TokenType))
+	fmt.Fprintf(stdout, "issuer_name:      %s\n", challenge.IssuerName)
+	fmt.Fprintf(stdout, "redemption_nonce: %s\n", hex.EncodeToString(challenge.RedemptionNonce))
+	fmt.Fprintf(stdout, "origin_info:      %s\n", strings.Join(challenge.OriginInfo, ","))
+	return nil
+}
+
+// decodeChallenge parses a base64url-encoded TokenChallenge.
+func decodeChallenge(s string) (tokens.TokenChallenge, []byte, error) {
+	challengeEnc, err := decodeBase64(s)
+	if err != nil {
+		return tokens.TokenChallenge{}, nil, err
+	}
+	challenge, err := tokens.UnmarshalTokenChallenge(challengeEnc)
+	if err != nil {
+		return tokens.TokenChallenge{}, nil, err
+	}
+	return challenge, challengeEnc, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type2"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+)
+
+// errUsage is returned by commands whose arguments could not be parsed. The
+// flag package has already reported the problem, so it is not printed again.
+var errUsage = errors.New("usage error")
+
+// errHelp is returned by commands invoked with -h or -help. The flag package
+// has already printed the usage, and the command exits successfully.
+var errHelp = errors.New("help requested")
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("pat "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// encodeBase64 encodes data as unpadded base64url.
+func encodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeBase64 decodes base64url, accepting input with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(s), "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64url encoding: %v", err)
+	}
+	return data, nil
+}
+
+// parseTokenType parses a token type given in hex, with or without a 0x
+// prefix, such as "0002", "0x0003" or "f91a".
+func parseTokenType(s string) (uint16, error) {
+	if s == "" {
+		return 0, fmt.Errorf("missing token type")
+	}
+	tokenType, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid token type %q", s)
+	}
+	switch uint16(tokenType) {
+	case type1.BasicPrivateTokenType, type2.BasicPublicTokenType, type3.RateLimitedTokenType, typeF91A.BatchedPrivateTokenType:
+		return uint16(tokenType), nil
+	default:
+		return 0, fmt.Errorf("unsupported token type %04x", tokenType)
+	}
+}
+
+func formatTokenType(tokenType uint16) string {
+	return fmt.Sprintf("%04x", tokenType)
+}
+
+func decodeHex(name, s string) ([]byte, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s encoding: %v", name, err)
+	}
+	return data, nil
+}
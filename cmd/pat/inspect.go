@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type2"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+	"github.com/cloudflare/pat-go/util"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// Kinds of wire objects recognized by inspect.
+const (
+	kindTokenChallenge = "TokenChallenge"
+	kindTokenRequest   = "TokenRequest"
+	kindTokenResponse  = "TokenResponse"
+	kindToken          = "Token"
+	kindEncapKey       = "EncapKey"
+	kindTokenKey       = "TokenKey"
+)
+
+// truncatedLength is the number of bytes of long identifiers shown unless
+// the full encoding is requested.
+const truncatedLength = 8
+
+// authenticatorLengths maps each token type to its authenticator size Nk, or
+// to zero for the RSA-based types, whose Nk is the size of the token key.
+var authenticatorLengths = map[uint16]int{
+	type1.BasicPrivateTokenType:      48,
+	type2.BasicPublicTokenType:       0,
+	type3.RateLimitedTokenType:       0,
+	typeF91A.BatchedPrivateTokenType: 64,
+}
+
+// isAuthenticatorLength reports whether nk is a valid authenticator size for
+// tokenType.
+func isAuthenticatorLength(tokenType uint16, nk int) bool {
+	expected, ok := authenticatorLengths[tokenType]
+	if !ok {
+		return false
+	}
+	if expected == 0 {
+		return util.IsSupportedTokenKeySize(nk)
+	}
+	return nk == expected
+}
+
+type inspectedField struct {
+	name  string
+	value string
+}
+
+// inspection is the field-by-field breakdown of a decoded wire object.
+type inspection struct {
+	Kind      string
+	TokenType uint16
+	Length    int
+	Note      string
+	fields    []inspectedField
+	full      bool
+}
+
+func (in *inspection) add(name, format string, args ...interface{}) {
+	in.fields = append(in.fields, inspectedField{name, fmt.Sprintf(format, args...)})
+}
+
+func (in *inspection) addHex(name string, value []byte) {
+	in.fields = append(in.fields, inspectedField{name, hex.EncodeToString(value)})
+}
+
+// addID adds an identifier, truncated to its first bytes unless the full
+// encoding was requested.
+func (in *inspection) addID(name string, value []byte) {
+	if in.full || len(value) <= truncatedLength {
+		in.addHex(name, value)
+		return
+	}
+	in.add(name, "%s...", hex.EncodeToString(value[:truncatedLength]))
+}
+
+func (in *inspection) addLength(name string, value []byte) {
+	in.add(name, "%d", len(value))
+}
+
+func (in inspection) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%-24s %s\n", "kind:", in.Kind)
+	if in.TokenType != 0 {
+		fmt.Fprintf(w, "%-24s %s\n", "token_type:", formatTokenType(in.TokenType))
+	}
+	fmt.Fprintf(w, "%-24s %d\n", "length:", in.Length)
+	for _, f := range in.fields {
+		fmt.Fprintf(w, "%-24s %s\n", f.name+":", f.value)
+	}
+	if in.Note != "" {
+		fmt.Fprintf(w, "%-24s %s\n", "note:", in.Note)
+	}
+}
+
+// MarshalJSON encodes the inspection as a JSON object with the fields in
+// the order they appear in the wire encoding.
+func (in inspection) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	writeMember := func(name string, value interface{}) {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		nameEnc, _ := json.Marshal(name)
+		valueEnc, _ := json.Marshal(value)
+		b.Write(nameEnc)
+		b.WriteByte(':')
+		b.Write(valueEnc)
+	}
+
+	b.WriteByte('{')
+	writeMember("kind", in.Kind)
+	if in.TokenType != 0 {
+		writeMember("token_type", formatTokenType(in.TokenType))
+	}
+	writeMember("length", in.Length)
+	for _, f := range in.fields {
+		writeMember(f.name, f.value)
+	}
+	if in.Note != "" {
+		writeMember("note", in.Note)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func runInspect(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	jsonOutput := fs.Bool("json", false, "print the breakdown as JSON")
+	full := fs.Bool("full", false, "print key IDs and other long identifiers in full")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var input string
+	switch fs.NArg() {
+	case 0:
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		input = string(data)
+	case 1:
+		input = fs.Arg(0)
+	default:
+		fmt.Fprintf(stderr, "usage: pat inspect [-json] [-full] [--] [<base64>]\n")
+		return errUsage
+	}
+
+	data, err := decodeBlob(input)
+	if err != nil {
+		return err
+	}
+	in, err := inspect(data, *full)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		enc, err := json.MarshalIndent(in, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(enc))
+		return nil
+	}
+	in.writeText(stdout)
+	return nil
+}
+
+// decodeBlob decodes base64 in either the URL-safe or standard alphabet,
+// with or without padding.
+func decodeBlob(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.Join(strings.Fields(s), ""), "=")
+	if data, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return data, nil
+	}
+	data, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 encoding: %v", err)
+	}
+	return data, nil
+}
+
+// inspect detects which Privacy Pass wire object data holds. Objects that
+// carry a token type are tried first, from the most to the least tightly
+// constrained encoding; token responses carry no type and are recognized by
+// their length and structure alone.
+func inspect(data []byte, full bool) (inspection, error) {
+	detectors := []func([]byte, *inspection) bool{
+		inspectTokenKey,
+		inspectToken,
+		inspectTokenRequest,
+		inspectTokenChallenge,
+		inspectEncapKey,
+		inspectTokenResponse,
+	}
+	for _, detect := range detectors {
+		in := inspection{Length: len(data), full: full}
+		if detect(data, &in) {
+			return in, nil
+		}
+	}
+	return inspection{}, fmt.Errorf("unrecognized object (%d bytes)", len(data))
+}
+
+func inspectTokenKey(data []byte, in *inspection) bool {
+	if len(data) == 0 || data[0] != 0x30 {
+		return false
+	}
+	publicKey, err := util.UnmarshalTokenKeyLenient(data)
+	if err != nil {
+		return false
+	}
+
+	var algorithm string
+	if enc, err := util.MarshalTokenKeyPSSOID(publicKey); err == nil && bytes.Equal(enc, data) {
+		algorithm = "RSASSA-PSS"
+	} else if enc, err := util.MarshalTokenKeyRSAEncryptionOID(publicKey); err == nil && bytes.Equal(enc, data) {
+		algorithm = "rsaEncryption"
+		in.Note = "legacy SPKI encoding; the key ID is computed over the RSASSA-PSS encoding"
+	} else {
+		return false
+	}
+
+	in.Kind = kindTokenKey
+	in.add("algorithm", "%s", algorithm)
+	in.add("modulus_bits", "%d", publicKey.N.BitLen())
+	in.add("public_exponent", "%d", publicKey.E)
+	if enc, err := util.MarshalTokenKeyPSSOID(publicKey); err == nil {
+		keyID := sha256.Sum256(enc)
+		in.addID("key_id", keyID[:])
+	}
+	return true
+}
+
+func inspectToken(data []byte, in *inspection) bool {
+	s := cryptobyte.String(data)
+	var tokenType uint16
+	if !s.ReadUint16(&tokenType) {
+		return false
+	}
+	if !isAuthenticatorLength(tokenType, len(data)-(2+32+32+32)) {
+		return false
+	}
+	token, err := unmarshalToken(tokenType, data)
+	if err != nil {
+		return false
+	}
+
+	in.Kind = kindToken
+	in.TokenType = token.TokenType
+	in.addHex("nonce", token.Nonce)
+	in.addHex("context", token.Context)
+	in.addID("key_id", token.KeyID)
+	in.addLength("authenticator_length", token.Authenticator)
+	return true
+}
+
+func inspectTokenRequest(data []byte, in *inspection) bool {
+	s := cryptobyte.String(data)
+	var tokenType uint16
+	if !s.ReadUint16(&tokenType) {
+		return false
+	}
+
+	switch tokenType {
+	case type1.BasicPrivateTokenType:
+		var req type1.BasicPrivateTokenRequest
+		if !req.Unmarshal(data) {
+			return false
+		}
+		in.add("truncated_key_id", "%d", req.TokenKeyID)
+		in.addLength("blinded_element_length", req.BlindedReq)
+	case type2.BasicPublicTokenType:
+		var req type2.BasicPublicTokenRequest
+		if !req.Unmarshal(data) {
+			return false
+		}
+		in.add("truncated_key_id", "%d", req.TokenKeyID)
+		in.addLength("blinded_message_length", req.BlindedReq)
+	case type3.RateLimitedTokenType:
+		var req type3.RateLimitedTokenRequest
+		if !req.Unmarshal(data) || !bytes.Equal(req.Marshal(), data) {
+			return false
+		}
+		in.addID("request_key", req.RequestKey)
+		in.addID("name_key_id", req.NameKeyID)
+		in.addLength("encrypted_token_request_length", req.EncryptedTokenRequest)
+		in.addLength("signature_length", req.Signature)
+	case typeF91A.BatchedPrivateTokenType:
+		var req typeF91A.BatchedPrivateTokenRequest
+		if !req.Unmarshal(data) || !bytes.Equal(req.Marshal(), data) {
+			return false
+		}
+		in.add("truncated_key_id", "%d", req.TokenKeyID)
+		in.add("blinded_element_count", "%d", len(req.BlindedReq))
+	default:
+		return false
+	}
+
+	in.Kind = kindTokenRequest
+	in.TokenType = tokenType
+	return true
+}
+
+func inspectTokenChallenge(data []byte, in *inspection) bool {
+	challenge, err := tokens.UnmarshalTokenChallenge(data)
+	if err != nil {
+		return false
+	}
+	if _, ok := authenticatorLengths[challenge.TokenType]; !ok {
+		in.Note = "unknown token type"
+	}
+
+	in.Kind = kindTokenChallenge
+	in.TokenType = challenge.TokenType
+	in.add("issuer_name", "%s", challenge.IssuerName)
+	in.addHex("redemption_nonce", challenge.RedemptionNonce)
+	in.add("origin_info", "%s", strings.Join(challenge.OriginInfo, ","))
+	context := sha256.Sum256(data)
+	in.addHex("token_context", context[:])
+	return true
+}
+
+func inspectEncapKey(data []byte, in *inspection) bool {
+	encapKey, err := type3.UnmarshalEncapKey(data)
+	if err != nil || !bytes.Equal(encapKey.Marshal(), data) {
+		return false
+	}
+
+	s := cryptobyte.String(data)
+	var keyID uint8
+	var kemID, kdfID, aeadID uint16
+	var publicKey []byte
+	if !s.ReadUint8(&keyID) ||
+		!s.ReadUint16(&kemID) ||
+		!s.ReadBytes(&publicKey, len(s)-4) ||
+		!s.ReadUint16(&kdfID) ||
+		!s.ReadUint16(&aeadID) {
+		return false
+	}
+
+	in.Kind = kindEncapKey
+	in.add("key_id", "%d", keyID)
+	in.add("kem_id", "0x%04x", kemID)
+	in.addHex("public_key", publicKey)
+	in.add("kdf_id", "0x%04x", kdfID)
+	in.add("aead_id", "0x%04x", aeadID)
+	nameKeyID := sha256.Sum256(data)
+	in.addID("name_key_id", nameKeyID[:])
+	return true
+}
+
+// Token response sizes. Basic public responses are a blind RSA signature of
+// the token key size Nk, and rate-limited responses add the response nonce and
+// AEAD tag around it.
+const (
+	basicPrivateTokenResponseLength  = 49 + 2*48 // compressed P-384 element and DLEQ proof
+	rateLimitedTokenResponseOverhead = 16 + 16   // response nonce and AEAD tag
+)
+
+func inspectTokenResponse(data []byte, in *inspection) bool {
+	in.Kind = kindTokenResponse
+	in.Note = "token responses carry no token type; detected by length and structure"
+
+	// Batched responses are a length-prefixed list of Ristretto255 elements
+	// followed by a DLEQ proof.
+	elementLength := int(group.Ristretto255.Params().CompressedElementLength)
+	proofLength := int(2 * group.Ristretto255.Params().ScalarLength)
+	s := cryptobyte.String(data)
+	var elements cryptobyte.String
+	if s.ReadUint16LengthPrefixed(&elements) && !elements.Empty() && len(elements)%elementLength == 0 && len(s) == proofLength {
+		in.TokenType = typeF91A.BatchedPrivateTokenType
+		in.add("evaluated_element_count", "%d", len(elements)/elementLength)
+		in.add("proof_length", "%d", proofLength)
+		return true
+	}
+
+	switch {
+	case len(data) == basicPrivateTokenResponseLength:
+		element := group.P384.NewElement()
+		if element.UnmarshalBinary(data[:49]) != nil {
+			return false
+		}
+		in.TokenType = type1.BasicPrivateTokenType
+		in.add("evaluated_element_length", "%d", 49)
+		in.add("proof_length", "%d", 2*48)
+	case util.IsSupportedTokenKeySize(len(data)):
+		in.TokenType = type2.BasicPublicTokenType
+		in.add("blind_signature_length", "%d", len(data))
+	case util.IsSupportedTokenKeySize(len(data) - rateLimitedTokenResponseOverhead):
+		in.TokenType = type3.RateLimitedTokenType
+		in.addHex("response_nonce", data[:16])
+		in.add("encrypted_response_length", "%d", len(data)-16)
+	default:
+		return false
+	}
+	return true
+}
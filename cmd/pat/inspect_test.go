@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cloudflare/pat-go/ecdsa"
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type2"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+	"github.com/cloudflare/pat-go/util"
+)
+
+type inspectCase struct {
+	name      string
+	data      []byte
+	kind      string
+	tokenType uint16
+}
+
+func mustGenerateIssuerKey(t *testing.T, tokenType uint16) *issuerKey {
+	t.Helper()
+	key, err := generateIssuerKey(tokenType, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustChallenge(t *testing.T, tokenType uint16) []byte {
+	t.Helper()
+	challenge, err := tokens.NewTokenChallenge(tokenType, "issuer.example", make([]byte, 32), []string{"origin.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challengeEnc
+}
+
+func mustIssueToken(t *testing.T, key *issuerKey, challengeEnc []byte) []byte {
+	t.Helper()
+	issued, err := issueTokens(key, challengeEnc, "origin.example", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issued[0].Marshal()
+}
+
+func inspectCases(t *testing.T) []inspectCase {
+	nonce := make([]byte, 32)
+	nonces := [][]byte{nonce, nonce, nonce}
+	var cases []inspectCase
+
+	// Type 0x0001
+	key := mustGenerateIssuerKey(t, type1.BasicPrivateTokenType)
+	challengeEnc := mustChallenge(t, type1.BasicPrivateTokenType)
+	basicPrivateIssuer := type1.NewBasicPrivateIssuer(key.oprfKey)
+	basicPrivateState, err := type1.NewBasicPrivateClient().CreateTokenRequest(challengeEnc, nonce, basicPrivateIssuer.TokenKeyID(), basicPrivateIssuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicPrivateResponse, err := basicPrivateIssuer.Evaluate(basicPrivateState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases = append(cases,
+		inspectCase{"type1 challenge", challengeEnc, kindTokenChallenge, type1.BasicPrivateTokenType},
+		inspectCase{"type1 request", basicPrivateState.Request().Marshal(), kindTokenRequest, type1.BasicPrivateTokenType},
+		inspectCase{"type1 response", basicPrivateResponse, kindTokenResponse, type1.BasicPrivateTokenType},
+		inspectCase{"type1 token", mustIssueToken(t, key, challengeEnc), kindToken, type1.BasicPrivateTokenType},
+	)
+
+	// Type 0x0002
+	key = mustGenerateIssuerKey(t, type2.BasicPublicTokenType)
+	challengeEnc = mustChallenge(t, type2.BasicPublicTokenType)
+	basicPublicIssuer := type2.NewBasicPublicIssuer(key.rsaKey)
+	basicPublicState, err := type2.NewBasicPublicClient().CreateTokenRequest(challengeEnc, nonce, basicPublicIssuer.TokenKeyID(), basicPublicIssuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicPublicResponse, err := basicPublicIssuer.Evaluate(basicPublicState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenKeyEnc, err := util.MarshalTokenKeyPSSOID(key.rsaPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyTokenKeyEnc, err := util.MarshalTokenKeyRSAEncryptionOID(key.rsaPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases = append(cases,
+		inspectCase{"type2 request", basicPublicState.Request().Marshal(), kindTokenRequest, type2.BasicPublicTokenType},
+		inspectCase{"type2 response", basicPublicResponse, kindTokenResponse, type2.BasicPublicTokenType},
+		inspectCase{"type2 token", mustIssueToken(t, key, challengeEnc), kindToken, type2.BasicPublicTokenType},
+		inspectCase{"token key", tokenKeyEnc, kindTokenKey, 0},
+		inspectCase{"legacy token key", legacyTokenKeyEnc, kindTokenKey, 0},
+	)
+
+	// Type 0x0003
+	key = mustGenerateIssuerKey(t, type3.RateLimitedTokenType)
+	challengeEnc = mustChallenge(t, type3.RateLimitedTokenType)
+	rateLimitedIssuer := type3.NewRateLimitedIssuerWithNameKey(key.rsaKey, *key.nameKey)
+	if err := rateLimitedIssuer.AddOrigin("origin.example"); err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rateLimitedState, err := type3.NewRateLimitedClientFromSecret(clientKey.D.Bytes()).CreateTokenRequest(challengeEnc, nonce, requestKey.D.Bytes(), rateLimitedIssuer.TokenKeyID(), rateLimitedIssuer.TokenKey(), "origin.example", rateLimitedIssuer.NameKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rateLimitedResponse, _, err := rateLimitedIssuer.Evaluate(rateLimitedState.Request().Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases = append(cases,
+		inspectCase{"type3 request", rateLimitedState.Request().Marshal(), kindTokenRequest, type3.RateLimitedTokenType},
+		inspectCase{"type3 response", rateLimitedResponse, kindTokenResponse, type3.RateLimitedTokenType},
+		inspectCase{"type3 token", mustIssueToken(t, key, challengeEnc), kindToken, type3.RateLimitedTokenType},
+		inspectCase{"encap key", rateLimitedIssuer.NameKey().Marshal(), kindEncapKey, 0},
+	)
+
+	// Type 0xF91A
+	key = mustGenerateIssuerKey(t, typeF91A.BatchedPrivateTokenType)
+	challengeEnc = mustChallenge(t, typeF91A.BatchedPrivateTokenType)
+	batchedIssuer := typeF91A.NewBatchedPrivateIssuer(key.oprfKey)
+	batchedState, err := typeF91A.NewBatchedPrivateClient().CreateTokenRequest(challengeEnc, nonces, batchedIssuer.TokenKeyID(), batchedIssuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	batchedResponse, err := batchedIssuer.Evaluate(batchedState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases = append(cases,
+		inspectCase{"typeF91A request", batchedState.Request().Marshal(), kindTokenRequest, typeF91A.BatchedPrivateTokenType},
+		inspectCase{"typeF91A response", batchedResponse, kindTokenResponse, typeF91A.BatchedPrivateTokenType},
+		inspectCase{"typeF91A token", mustIssueToken(t, key, challengeEnc), kindToken, typeF91A.BatchedPrivateTokenType},
+	)
+
+	return cases
+}
+
+func TestInspectDetectsObjects(t *testing.T) {
+	for _, c := range inspectCases(t) {
+		t.Run(c.name, func(t *testing.T) {
+			in, err := inspect(c.data, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if in.Kind != c.kind || in.TokenType != c.tokenType {
+				t.Fatalf("detected %s %04x, expected %s %04x", in.Kind, in.TokenType, c.kind, c.tokenType)
+			}
+
+			out := mustRunCommand(t, "inspect", "-json", "--", base64.RawURLEncoding.EncodeToString(c.data))
+			var decoded map[string]interface{}
+			if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded["kind"] != c.kind {
+				t.Fatalf("JSON output has kind %v, expected %s", decoded["kind"], c.kind)
+			}
+
+			out = mustRunCommand(t, "inspect", "--", base64.StdEncoding.EncodeToString(c.data))
+			if !strings.Contains(out, c.kind) {
+				t.Fatalf("text output missing kind %s:\n%s", c.kind, out)
+			}
+		})
+	}
+}
+
+func TestInspectTruncatesKeyID(t *testing.T) {
+	key := mustGenerateIssuerKey(t, type2.BasicPublicTokenType)
+	token := mustIssueToken(t, key, mustChallenge(t, type2.BasicPublicTokenType))
+	keyID, err := key.keyID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(token)
+
+	out := mustRunCommand(t, "inspect", encoded)
+	if strings.Contains(out, hex.EncodeToString(keyID)) || !strings.Contains(out, hex.EncodeToString(keyID[:truncatedLength])+"...") {
+		t.Fatalf("key ID not truncated:\n%s", out)
+	}
+	out = mustRunCommand(t, "inspect", "-full", encoded)
+	if !strings.Contains(out, hex.EncodeToString(keyID)) {
+		t.Fatalf("key ID not printed in full:\n%s", out)
+	}
+}
+
+func TestInspectRejectsUnknownObjects(t *testing.T) {
+	for _, data := range [][]byte{
+		{},
+		{0x00, 0x02},
+		make([]byte, 100),
+	} {
+		if in, err := inspect(data, false); err == nil {
+			t.Fatalf("detected %s in %x", in.Kind, data)
+		}
+	}
+	if _, err := runCommand(t, "inspect", "not base64!"); err == nil {
+		t.Fatal("inspected invalid base64")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/pat-go/ecdsa"
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type2"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+)
+
+func runIssue(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("issue", stderr)
+	keyPath := fs.String("key", "", "issuer key file with private keys, as written by keygen")
+	challengeFlag := fs.String("challenge", "", "base64url-encoded TokenChallenge")
+	origin := fs.String("origin", "", "origin name for type 0003 issuance (default: first origin in the challenge)")
+	count := fs.Int("count", 1, "number of tokens to issue")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	key, err := loadIssuerKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	challenge, challengeEnc, err := decodeChallenge(*challengeFlag)
+	if err != nil {
+		return err
+	}
+	if challenge.TokenType != key.tokenType {
+		return fmt.Errorf("challenge token type %04x does not match key token type %04x", challenge.TokenType, key.tokenType)
+	}
+	if *origin == "" && len(challenge.OriginInfo) > 0 {
+		*origin = challenge.OriginInfo[0]
+	}
+
+	issued, err := issueTokens(key, challengeEnc, *origin, *count)
+	if err != nil {
+		return err
+	}
+	for _, token := range issued {
+		fmt.Fprintln(stdout, encodeBase64(token.Marshal()))
+	}
+	return nil
+}
+
+// issueTokens plays the client, issuer and, for rate-limited tokens, attester
+// roles to produce count tokens for the given challenge. Batched tokens are
+// issued in a single request; other types run one round trip per token.
+func issueTokens(key *issuerKey, challengeEnc []byte, origin string, count int) ([]tokens.Token, error) {
+	if !key.hasPrivateKey() {
+		return nil, fmt.Errorf("issuance requires a private issuer key")
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("invalid token count: %d", count)
+	}
+
+	if key.tokenType == typeF91A.BatchedPrivateTokenType {
+		return issueBatchedPrivateTokens(key, challengeEnc, count)
+	}
+
+	issued := make([]tokens.Token, count)
+	for i := range issued {
+		nonce := make([]byte, 32)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+
+		var token tokens.Token
+		var err error
+		switch key.tokenType {
+		case type1.BasicPrivateTokenType:
+			token, err = issueBasicPrivateToken(key, challengeEnc, nonce)
+		case type2.BasicPublicTokenType:
+			token, err = issueBasicPublicToken(key, challengeEnc, nonce)
+		case type3.RateLimitedTokenType:
+			token, err = issueRateLimitedToken(key, challengeEnc, nonce, origin)
+		default:
+			err = fmt.Errorf("unsupported token type %04x", key.tokenType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		issued[i] = token
+	}
+
+	return issued, nil
+}
+
+func issueBasicPrivateToken(key *issuerKey, challengeEnc, nonce []byte) (tokens.Token, error) {
+	issuer := type1.NewBasicPrivateIssuer(key.oprfKey)
+	client := type1.NewBasicPrivateClient()
+
+	requestState, err := client.CreateTokenRequest(challengeEnc, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	tokenResponse, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	return requestState.FinalizeToken(tokenResponse)
+}
+
+func issueBasicPublicToken(key *issuerKey, challengeEnc, nonce []byte) (tokens.Token, error) {
+	issuer := type2.NewBasicPublicIssuer(key.rsaKey)
+	client := type2.NewBasicPublicClient()
+
+	requestState, err := client.CreateTokenRequest(challengeEnc, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	blindSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	return requestState.FinalizeToken(blindSignature)
+}
+
+func issueBatchedPrivateTokens(key *issuerKey, challengeEnc []byte, count int) ([]tokens.Token, error) {
+	issuer := typeF91A.NewBatchedPrivateIssuer(key.oprfKey)
+	client := typeF91A.NewBatchedPrivateClient()
+
+	nonces := make([][]byte, count)
+	for i := range nonces {
+		nonces[i] = make([]byte, 32)
+		if _, err := rand.Read(nonces[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	requestState, err := client.CreateTokenRequest(challengeEnc, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		return nil, err
+	}
+	tokenResponse, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		return nil, err
+	}
+	return requestState.FinalizeTokens(tokenResponse)
+}
+
+// memoryClientStateCache is an in-memory ClientStateCache for the local
+// attester, which only lives for a single issuance.
+type memoryClientStateCache map[string]*type3.ClientState
+
+func (c memoryClientStateCache) Get(clientID string) (*type3.ClientState, bool) {
+	state, ok := c[clientID]
+	return state, ok
+}
+
+func (c memoryClientStateCache) Put(clientID string, state *type3.ClientState) {
+	c[clientID] = state
+}
+
+func issueRateLimitedToken(key *issuerKey, challengeEnc, nonce []byte, origin string) (tokens.Token, error) {
+	if origin == "" {
+		return tokens.Token{}, fmt.Errorf("type %04x issuance requires an origin name", type3.RateLimitedTokenType)
+	}
+	if key.nameKey == nil {
+		return tokens.Token{}, fmt.Errorf("key file is missing the name key seed")
+	}
+
+	issuer := type3.NewRateLimitedIssuerWithNameKey(key.rsaKey, *key.nameKey)
+	if err := issuer.AddOrigin(origin); err != nil {
+		return tokens.Token{}, err
+	}
+	attester := type3.NewRateLimitedAttester(memoryClientStateCache{})
+
+	curve := elliptic.P384()
+	clientSecretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	requestKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	clientKeyEnc, err := ecdsa.MarshalCompressed(&clientSecretKey.PublicKey)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	client := type3.NewRateLimitedClientFromSecret(clientSecretKey.D.Bytes())
+
+	anonymousOriginID := make([]byte, 32)
+	if _, err := rand.Read(anonymousOriginID); err != nil {
+		return tokens.Token{}, err
+	}
+
+	requestState, err := client.CreateTokenRequest(challengeEnc, nonce, requestKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), origin, issuer.NameKey())
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	if err := attester.VerifyRequest(*requestState.Request(), requestKey.D.Bytes(), clientKeyEnc, anonymousOriginID); err != nil {
+		return tokens.Token{}, err
+	}
+	blindSignature, blindedRequestKey, err := issuer.Evaluate(requestState.Request().Marshal())
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	if _, err := attester.FinalizeIndex(clientKeyEnc, requestKey.D.Bytes(), blindedRequestKey, anonymousOriginID); err != nil {
+		return tokens.Token{}, err
+	}
+	return requestState.FinalizeToken(blindSignature)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+func runKeygen(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keygen", stderr)
+	tokenTypeFlag := fs.String("type", "", "token type in hex: 0001, 0002, 0003 or f91a")
+	rsaBits := fs.Int("bits", 2048, "RSA modulus size for token types 0002 and 0003: 2048, 3072 or 4096")
+	out := fs.String("out", "", "write the private key file here instead of stdout")
+	publicOut := fs.String("public-out", "", "also write a key file without private keys here")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	tokenType, err := parseTokenType(*tokenTypeFlag)
+	if err != nil {
+		return err
+	}
+	key, err := generateIssuerKey(tokenType, *rsaBits)
+	if err != nil {
+		return err
+	}
+
+	privateEnc, err := key.marshalJSON(true)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(*out, privateEnc, stdout); err != nil {
+		return err
+	}
+
+	if *publicOut != "" {
+		publicEnc, err := key.marshalJSON(false)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*publicOut, append(publicEnc, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeOutput writes data followed by a newline to path, or to stdout if path
+// is empty. Files are created readable only by the owner since they may hold
+// private keys.
+func writeOutput(path string, data []byte, stdout io.Writer) error {
+	data = append(data, '\n')
+	if path == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	return nil
+}
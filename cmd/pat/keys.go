@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cloudflare/circl/oprf"
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+	"github.com/cloudflare/pat-go/util"
+)
+
+// issuerKeyFile is the JSON encoding of an issuer key. The private fields are
+// omitted from files that only carry the public verification key.
+type issuerKeyFile struct {
+	TokenType   string `json:"token_type"`
+	PrivateKey  string `json:"private_key,omitempty"`
+	PublicKey   string `json:"public_key"`
+	KeyID       string `json:"key_id"`
+	NameKeySeed string `json:"name_key_seed,omitempty"`
+	NameKey     string `json:"name_key,omitempty"`
+}
+
+// issuerKey holds the key material for a single token type. Types 0x0001 and
+// 0xF91A use the OPRF keys, types 0x0002 and 0x0003 the RSA keys, and type
+// 0x0003 additionally carries the HPKE name key used for origin encryption.
+type issuerKey struct {
+	tokenType     uint16
+	oprfKey       *oprf.PrivateKey
+	oprfPublicKey *oprf.PublicKey
+	rsaKey        *rsa.PrivateKey
+	rsaPublicKey  *rsa.PublicKey
+	nameKeySeed   []byte
+	nameKey       *type3.PrivateEncapKey
+}
+
+func oprfSuite(tokenType uint16) (oprf.Suite, bool) {
+	switch tokenType {
+	case type1.BasicPrivateTokenType:
+		return oprf.SuiteP384, true
+	case typeF91A.BatchedPrivateTokenType:
+		return oprf.SuiteRistretto255, true
+	default:
+		return nil, false
+	}
+}
+
+func generateIssuerKey(tokenType uint16, rsaBits int) (*issuerKey, error) {
+	key := &issuerKey{tokenType: tokenType}
+
+	if suite, ok := oprfSuite(tokenType); ok {
+		oprfKey, err := oprf.GenerateKey(suite, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.oprfKey = oprfKey
+		key.oprfPublicKey = oprfKey.Public()
+		return key, nil
+	}
+
+	if rsaBits%8 != 0 || !util.IsSupportedTokenKeySize(rsaBits/8) {
+		return nil, fmt.Errorf("unsupported RSA modulus size %d: use 2048, 3072 or 4096", rsaBits)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, rsaBits)
+	if err != nil {
+		return nil, err
+	}
+	key.rsaKey = rsaKey
+	key.rsaPublicKey = &rsaKey.PublicKey
+
+	if tokenType == type3.RateLimitedTokenType {
+		seed := make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		if err := key.setNameKeySeed(seed); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func (k *issuerKey) setNameKeySeed(seed []byte) error {
+	nameKey, err := type3.CreatePrivateEncapKeyFromSeed(seed)
+	if err != nil {
+		return err
+	}
+	k.nameKeySeed = seed
+	k.nameKey = &nameKey
+	return nil
+}
+
+func (k *issuerKey) hasPrivateKey() bool {
+	return k.oprfKey != nil || k.rsaKey != nil
+}
+
+func (k *issuerKey) publicKeyBytes() ([]byte, error) {
+	if k.oprfPublicKey != nil {
+		return k.oprfPublicKey.MarshalBinary()
+	}
+	return util.MarshalTokenKeyPSSOID(k.rsaPublicKey)
+}
+
+// keyID returns the token key ID, the SHA-256 digest of the encoded public key.
+func (k *issuerKey) keyID() ([]byte, error) {
+	publicKeyEnc, err := k.publicKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	keyID := sha256.Sum256(publicKeyEnc)
+	return keyID[:], nil
+}
+
+func (k *issuerKey) marshalJSON(includePrivate bool) ([]byte, error) {
+	publicKeyEnc, err := k.publicKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := k.keyID()
+	if err != nil {
+		return nil, err
+	}
+
+	file := issuerKeyFile{
+		TokenType: formatTokenType(k.tokenType),
+		PublicKey: hex.EncodeToString(publicKeyEnc),
+		KeyID:     hex.EncodeToString(keyID),
+	}
+	if k.nameKey != nil {
+		file.NameKey = hex.EncodeToString(k.nameKey.Public().Marshal())
+	}
+	if includePrivate && k.hasPrivateKey() {
+		var privateKeyEnc []byte
+		if k.oprfKey != nil {
+			privateKeyEnc, err = k.oprfKey.MarshalBinary()
+		} else {
+			privateKeyEnc, err = x509.MarshalPKCS8PrivateKey(k.rsaKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+		file.PrivateKey = hex.EncodeToString(privateKeyEnc)
+		if k.nameKeySeed != nil {
+			file.NameKeySeed = hex.EncodeToString(k.nameKeySeed)
+		}
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+func unmarshalIssuerKey(data []byte) (*issuerKey, error) {
+	var file issuerKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid key file: %v", err)
+	}
+
+	tokenType, err := parseTokenType(file.TokenType)
+	if err != nil {
+		return nil, err
+	}
+	key := &issuerKey{tokenType: tokenType}
+
+	publicKeyEnc, err := decodeHex("public key", file.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var privateKeyEnc []byte
+	if file.PrivateKey != "" {
+		privateKeyEnc, err = decodeHex("private key", file.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if suite, ok := oprfSuite(tokenType); ok {
+		key.oprfPublicKey = new(oprf.PublicKey)
+		if err := key.oprfPublicKey.UnmarshalBinary(suite, publicKeyEnc); err != nil {
+			return nil, fmt.Errorf("invalid OPRF public key: %v", err)
+		}
+		if privateKeyEnc != nil {
+			key.oprfKey = new(oprf.PrivateKey)
+			if err := key.oprfKey.UnmarshalBinary(suite, privateKeyEnc); err != nil {
+				return nil, fmt.Errorf("invalid OPRF private key: %v", err)
+			}
+			key.oprfPublicKey = key.oprfKey.Public()
+		}
+	} else {
+		key.rsaPublicKey, err = util.UnmarshalTokenKey(publicKeyEnc)
+		if err != nil {
+			return nil, err
+		}
+		if privateKeyEnc != nil {
+			privateKey, err := x509.ParsePKCS8PrivateKey(privateKeyEnc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA private key: %v", err)
+			}
+			rsaKey, ok := privateKey.(*rsa.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("invalid RSA private key: unexpected key type %T", privateKey)
+			}
+			key.rsaKey = rsaKey
+			key.rsaPublicKey = &rsaKey.PublicKey
+		}
+	}
+
+	if file.NameKeySeed != "" {
+		seed, err := decodeHex("name key seed", file.NameKeySeed)
+		if err != nil {
+			return nil, err
+		}
+		if err := key.setNameKeySeed(seed); err != nil {
+			return nil, err
+		}
+	}
+
+	if file.KeyID != "" {
+		keyID, err := key.keyID()
+		if err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(keyID) != file.KeyID {
+			return nil, fmt.Errorf("key ID does not match public key")
+		}
+	}
+
+	return key, nil
+}
+
+func loadIssuerKey(path string) (*issuerKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("missing -key")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalIssuerKey(data)
+}
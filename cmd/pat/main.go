@@ -0,0 +1,82 @@
+// Command pat is a small tool for experimenting with Privacy Pass tokens. It
+// can generate issuer keys, build and decode token challenges, run a local
+// issuance round trip, and verify the resulting tokens.
+//
+// Usage:
+//
+//	pat keygen -type 0002 -out issuer.json
+//	pat challenge new -type 0002 -issuer issuer.example -nonce random -origins origin.example
+//	pat challenge decode <challenge>
+//	pat issue -key issuer.json -challenge <challenge>
+//	pat verify -key issuer.json -challenge <challenge> -token <token>
+//	pat inspect [-json] [--] <base64>
+//	pat vectors generate -dir vectors [-family type1,type2]
+//	pat vectors verify -dir vectors [-family type1,type2]
+//
+// Challenges and tokens are exchanged in unpadded base64url, the encoding used
+// in the PrivateToken HTTP authentication scheme. Binary values in key files
+// and decoded output are hex encoded.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"keygen", "generate an issuer key for a token type", runKeygen},
+	{"challenge", "build or decode a TokenChallenge", runChallenge},
+	{"issue", "run a local issuance round trip and print the tokens", runIssue},
+	{"verify", "verify a token against an issuer key and challenge", runVerify},
+	{"inspect", "detect and describe a base64-encoded Privacy Pass object", runInspect},
+	{"vectors", "generate or verify test vectors", runVectors},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: pat <command> [arguments]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(stdout)
+		return 0
+	}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(args[1:], stdout, stderr); err != nil {
+			if err == errHelp {
+				return 0
+			}
+			if err != errUsage {
+				fmt.Fprintf(stderr, "pat %s: %v\n", c.name, err)
+			}
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "pat: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	for _, c := range commands {
+		if c.name == args[0] {
+			err := c.run(args[1:], &stdout, &stderr)
+			return strings.TrimSpace(stdout.String()), err
+		}
+	}
+	t.Fatalf("unknown command %q", args[0])
+	return "", nil
+}
+
+func mustRunCommand(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := runCommand(t, args...)
+	if err != nil {
+		t.Fatalf("pat %s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+func TestIssueAndVerifyRoundTrip(t *testing.T) {
+	for _, tokenType := range []string{"0001", "0002", "0003", "f91a"} {
+		t.Run(tokenType, func(t *testing.T) {
+			dir := t.TempDir()
+			keyPath := filepath.Join(dir, "issuer.json")
+			publicKeyPath := filepath.Join(dir, "issuer.pub.json")
+
+			mustRunCommand(t, "keygen", "-type", tokenType, "-out", keyPath, "-public-out", publicKeyPath)
+			challenge := mustRunCommand(t, "challenge", "new", "-type", tokenType, "-issuer", "issuer.example", "-nonce", "random", "-origins", "origin.example,other.example")
+
+			issued := strings.Split(mustRunCommand(t, "issue", "-key", keyPath, "-challenge", challenge, "-count", "2"), "\n")
+			if len(issued) != 2 {
+				t.Fatalf("expected 2 tokens, got %d", len(issued))
+			}
+
+			verifyKeyPath := publicKeyPath
+			if tokenType == "0001" || tokenType == "f91a" {
+				verifyKeyPath = keyPath
+			}
+			for _, token := range issued {
+				mustRunCommand(t, "verify", "-key", verifyKeyPath, "-challenge", challenge, "-token", token)
+				mustRunCommand(t, "verify", "-key", verifyKeyPath, "-challenge", challenge, "-token", token, "-origin", "other.example")
+				if _, err := runCommand(t, "verify", "-key", verifyKeyPath, "-challenge", challenge, "-token", token, "-origin", "unknown.example"); err == nil {
+					t.Fatal("token verified for an origin not in the challenge")
+				}
+			}
+
+			otherChallenge := mustRunCommand(t, "challenge", "new", "-type", tokenType, "-issuer", "issuer.example", "-origins", "origin.example")
+			if _, err := runCommand(t, "verify", "-key", verifyKeyPath, "-challenge", otherChallenge, "-token", issued[0]); err == nil {
+				t.Fatal("token verified against a different challenge")
+			}
+		})
+	}
+}
+
+func TestChallengeDecode(t *testing.T) {
+	nonce := strings.Repeat("ab", 32)
+	challenge := mustRunCommand(t, "challenge", "new", "-type", "0x0002", "-issuer", "issuer.example", "-nonce", nonce, "-origins", "a.example,b.example")
+
+	decoded := mustRunCommand(t, "challenge", "decode", challenge)
+	for _, want := range []string{"0002", "issuer.example", nonce, "a.example,b.example"} {
+		if !strings.Contains(decoded, want) {
+			t.Fatalf("decoded challenge missing %q:\n%s", want, decoded)
+		}
+	}
+
+	if _, err := runCommand(t, "challenge", "decode", challenge[:len(challenge)-4]); err == nil {
+		t.Fatal("decoded a truncated challenge")
+	}
+	if _, err := runCommand(t, "challenge", "new", "-type", "0002", "-issuer", "issuer.example", "-nonce", "abcd"); err == nil {
+		t.Fatal("built a challenge with a short redemption nonce")
+	}
+	if _, err := runCommand(t, "challenge", "new", "-type", "0005", "-issuer", "issuer.example"); err == nil {
+		t.Fatal("built a challenge with an unsupported token type")
+	}
+}
+
+func TestKeygenPublicKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "issuer.json")
+	publicKeyPath := filepath.Join(dir, "issuer.pub.json")
+	mustRunCommand(t, "keygen", "-type", "0003", "-out", keyPath, "-public-out", publicKeyPath)
+
+	key, err := loadIssuerKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.rsaKey == nil || key.nameKey == nil {
+		t.Fatal("private key file is missing private keys")
+	}
+
+	publicKey, err := loadIssuerKey(publicKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKey.hasPrivateKey() || publicKey.nameKey != nil {
+		t.Fatal("public key file contains private keys")
+	}
+	if publicKey.rsaPublicKey.N.Cmp(key.rsaPublicKey.N) != 0 {
+		t.Fatal("public key mismatch")
+	}
+
+	challenge := mustRunCommand(t, "challenge", "new", "-type", "0003", "-issuer", "issuer.example", "-origins", "origin.example")
+	if _, err := runCommand(t, "issue", "-key", publicKeyPath, "-challenge", challenge); err == nil {
+		t.Fatal("issued a token without a private key")
+	}
+}
+
+func TestKeygenRejectsUnsupportedBits(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "issuer.json")
+	for _, bits := range []string{"1024", "2047", "8192"} {
+		if _, err := runCommand(t, "keygen", "-type", "0002", "-bits", bits, "-out", keyPath); err == nil {
+			t.Errorf("generated a %s-bit token key", bits)
+		}
+	}
+}
+
+func TestUsageErrorsGoToStderr(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"keygen", "-no-such-flag"}, &stdout, &stderr); code == 0 {
+		t.Fatal("accepted an unknown flag")
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("usage error written to stdout: %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "-no-such-flag") {
+		t.Fatalf("usage error missing from stderr: %q", stderr.String())
+	}
+}
+
+func TestHelpExitsSuccessfully(t *testing.T) {
+	for _, args := range [][]string{{"keygen", "-h"}, {"challenge", "new", "-help"}, {"vectors", "verify", "-h"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 0 {
+			t.Errorf("pat %s exited with status %d", strings.Join(args, " "), code)
+		}
+		if !strings.Contains(stderr.String(), "Usage of pat "+args[0]) {
+			t.Errorf("pat %s did not print usage: %q", strings.Join(args, " "), stderr.String())
+		}
+	}
+}
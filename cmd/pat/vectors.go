@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudflare/pat-go/vectors"
+)
+
+func runVectors(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "usage: pat vectors generate|verify|list [arguments]\n")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "generate":
+		return runVectorsGenerate(args[1:], stdout, stderr)
+	case "verify":
+		return runVectorsVerify(args[1:], stdout, stderr)
+	case "list":
+		for _, f := range vectors.Families() {
+			fmt.Fprintf(stdout, "%-22s %s\n", f.Name, f.FileName)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// selectFamilies returns the families named in the comma-separated list, or
+// every family if the list is empty.
+func selectFamilies(names string) ([]vectors.Family, error) {
+	if names == "" {
+		return vectors.Families(), nil
+	}
+
+	var families []vectors.Family
+	for _, name := range strings.Split(names, ",") {
+		f, err := vectors.Lookup(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, f)
+	}
+	return families, nil
+}
+
+func runVectorsGenerate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("vectors generate", stderr)
+	dir := fs.String("dir", "", "directory to write the vector files to")
+	familyNames := fs.String("family", "", "comma-separated list of families to generate (default: all)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("missing -dir")
+	}
+
+	families, err := selectFamilies(*familyNames)
+	if err != nil {
+		return err
+	}
+	if err := vectors.GenerateDir(*dir, families); err != nil {
+		return err
+	}
+	for _, f := range families {
+		fmt.Fprintf(stdout, "wrote %s\n", f.FileName)
+	}
+	return nil
+}
+
+func runVectorsVerify(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("vectors verify", stderr)
+	dir := fs.String("dir", "", "directory containing the vector files")
+	familyNames := fs.String("family", "", "comma-separated list of families to verify (default: all)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("missing -dir")
+	}
+
+	families, err := selectFamilies(*familyNames)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	results := vectors.VerifyDir(*dir, families)
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		if result.Index < 0 {
+			fmt.Fprintf(stdout, "%s %s\n", status, result.Family)
+		} else {
+			fmt.Fprintf(stdout, "%s %s #%d\n", status, result.Family, result.Index)
+		}
+		if result.Err != nil {
+			fmt.Fprintf(stdout, "    error: %v\n", result.Err)
+		}
+		for _, diff := range result.Diffs {
+			fmt.Fprintf(stdout, "    %s:\n      expected %s\n      got      %s\n", diff.Field, diff.Expected, diff.Actual)
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d passed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d vectors failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVectorsGenerateAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	mustRunCommand(t, "vectors", "generate", "-dir", dir, "-family", "type2,anon-origin-id")
+
+	out := mustRunCommand(t, "vectors", "verify", "-dir", dir, "-family", "type2,anon-origin-id")
+	if strings.Contains(out, "FAIL") || !strings.Contains(out, "PASS type2 #4") || !strings.Contains(out, "6 passed, 0 failed") {
+		t.Fatalf("unexpected verify output:\n%s", out)
+	}
+
+	// Flip a nibble of the first token authenticator
+	path := filepath.Join(dir, "type2-issuance-test-vectors.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := strings.Index(string(data), `"token":"`) + len(`"token":"`) + 200
+	replacement := byte('0')
+	if data[i] == '0' {
+		replacement = '1'
+	}
+	data[i] = replacement
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = runCommand(t, "vectors", "verify", "-dir", dir, "-family", "type2")
+	if err == nil {
+		t.Fatal("tampered vectors verified")
+	}
+	if !strings.Contains(out, "FAIL type2 #0") || !strings.Contains(out, "    token:") || !strings.Contains(out, "4 passed, 1 failed") {
+		t.Fatalf("unexpected verify output:\n%s", out)
+	}
+
+	out, err = runCommand(t, "vectors", "verify", "-dir", dir, "-family", "token")
+	if err == nil || !strings.Contains(out, "FAIL token\n    error:") {
+		t.Fatalf("missing vector file not reported:\n%s", out)
+	}
+	if _, err := runCommand(t, "vectors", "verify", "-dir", dir, "-family", "type4"); err == nil {
+		t.Fatal("verified an unknown family")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type2"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+)
+
+func runVerify(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("verify", stderr)
+	keyPath := fs.String("key", "", "issuer key file; types 0001 and f91a need the private key")
+	challengeFlag := fs.String("challenge", "", "base64url-encoded TokenChallenge")
+	tokenFlag := fs.String("token", "", "base64url-encoded Token")
+	origin := fs.String("origin", "", "origin at which the token is redeemed (default: first origin in the challenge)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	key, err := loadIssuerKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	challenge, _, err := decodeChallenge(*challengeFlag)
+	if err != nil {
+		return err
+	}
+	tokenEnc, err := decodeBase64(*tokenFlag)
+	if err != nil {
+		return err
+	}
+	token, err := unmarshalToken(key.tokenType, tokenEnc)
+	if err != nil {
+		return err
+	}
+	if *origin == "" && len(challenge.OriginInfo) > 0 {
+		*origin = challenge.OriginInfo[0]
+	}
+
+	if err := verifyToken(key, token, challenge, *origin); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "token is valid")
+	return nil
+}
+
+// unmarshalToken decodes a token of the given type, which determines the
+// authenticator length.
+func unmarshalToken(tokenType uint16, data []byte) (tokens.Token, error) {
+	switch tokenType {
+	case type1.BasicPrivateTokenType:
+		return type1.UnmarshalPrivateToken(data)
+	case type2.BasicPublicTokenType:
+		return type2.UnmarshalToken(data)
+	case type3.RateLimitedTokenType:
+		return type3.UnmarshalToken(data)
+	case typeF91A.BatchedPrivateTokenType:
+		return typeF91A.UnmarshalBatchedPrivateToken(data)
+	default:
+		return tokens.Token{}, fmt.Errorf("unsupported token type %04x", tokenType)
+	}
+}
+
+// verifyToken checks that token was issued under key for challenge, and that
+// it may be redeemed at origin.
+func verifyToken(key *issuerKey, token tokens.Token, challenge tokens.TokenChallenge, origin string) error {
+	keyID, err := key.keyID()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(token.KeyID, keyID) {
+		return fmt.Errorf("token key ID does not match issuer key")
+	}
+
+	switch key.tokenType {
+	case type1.BasicPrivateTokenType:
+		if key.oprfKey == nil {
+			return fmt.Errorf("type %04x verification requires the private issuer key", key.tokenType)
+		}
+		return type1.NewBasicPrivateIssuer(key.oprfKey).VerifyForOrigin(token, challenge, origin)
+	case type2.BasicPublicTokenType:
+		return type2.NewBasicPublicVerifier(key.rsaPublicKey).VerifyForOrigin(token, challenge, origin)
+	case type3.RateLimitedTokenType:
+		return type3.NewRateLimitedVerifier(key.rsaPublicKey).VerifyForOrigin(token, challenge, origin)
+	case typeF91A.BatchedPrivateTokenType:
+		if key.oprfKey == nil {
+			return fmt.Errorf("type %04x verification requires the private issuer key", key.tokenType)
+		}
+		return typeF91A.NewBatchedPrivateIssuer(key.oprfKey).VerifyForOrigin(token, challenge, origin)
+	default:
+		return fmt.Errorf("unsupported token type %04x", key.tokenType)
+	}
+}
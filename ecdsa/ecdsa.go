@@ -174,24 +174,27 @@ func GenerateKey(c elliptic.Curve, rand io.Reader) (*PrivateKey, error) {
 	return priv, nil
 }
 
-func hashBlind(c elliptic.Curve, sk *PrivateKey, context []byte) (*big.Int, error) {
-	var h crypto.Hash
-	var L uint
+// hashToScalarParams returns the hash function and expansion length used to
+// hash to scalars of the curve.
+func hashToScalarParams(c elliptic.Curve) (crypto.Hash, uint, error) {
 	switch c.Params().Name {
 	case "P-224":
-		h = crypto.SHA256
-		L = 32
+		return crypto.SHA256, 32, nil
 	case "P-256":
-		h = crypto.SHA256
-		L = 48
+		return crypto.SHA256, 48, nil
 	case "P-384":
-		h = crypto.SHA384
-		L = 72
+		return crypto.SHA384, 72, nil
 	case "P-521":
-		h = crypto.SHA512
-		L = 98
+		return crypto.SHA512, 98, nil
 	default:
-		return nil, fmt.Errorf("Unsupported curve")
+		return 0, 0, fmt.Errorf("Unsupported curve")
+	}
+}
+
+func hashBlind(c elliptic.Curve, sk *PrivateKey, context []byte) (*big.Int, error) {
+	h, L, err := hashToScalarParams(c)
+	if err != nil {
+		return nil, err
 	}
 	xmd := expander.NewExpanderMD(h, []byte("ECDSA Key Blind"))
 	var u [1]big.Int
@@ -209,7 +212,13 @@ func BlindPublicKeyWithContext(c elliptic.Curve, pk *PublicKey, bk *PrivateKey,
 	if err != nil {
 		return nil, err
 	}
-	X, Y := c.ScalarMult(pk.X, pk.Y, skBlind.Bytes())
+	return blindPublicKey(c, pk, skBlind)
+}
+
+// blindPublicKeyGeneric returns [blind]pk using the variable-time
+// elliptic.Curve implementation.
+func blindPublicKeyGeneric(c elliptic.Curve, pk *PublicKey, blind *big.Int) (*PublicKey, error) {
+	X, Y := c.ScalarMult(pk.X, pk.Y, blind.Bytes())
 	return &PublicKey{
 		c, X, Y,
 	}, nil
@@ -226,7 +235,16 @@ func UnblindPublicKeyWithContext(c elliptic.Curve, pk *PublicKey, bk *PrivateKey
 	if err != nil {
 		return nil, err
 	}
-	kInv := new(big.Int).ModInverse(skBlind, c.Params().N)
+	return unblindPublicKey(c, pk, skBlind)
+}
+
+// unblindPublicKeyGeneric returns [blind^-1]pk using the variable-time
+// elliptic.Curve and big.Int implementations.
+func unblindPublicKeyGeneric(c elliptic.Curve, pk *PublicKey, blind *big.Int) (*PublicKey, error) {
+	kInv := new(big.Int).ModInverse(blind, c.Params().N)
+	if kInv == nil {
+		return nil, errors.New("ecdsa: blind is not invertible")
+	}
 	X, Y := c.ScalarMult(pk.X, pk.Y, kInv.Bytes())
 	return &PublicKey{
 		c, X, Y,
@@ -238,22 +256,39 @@ func UnblindPublicKey(c elliptic.Curve, pk *PublicKey, bk *PrivateKey) (*PublicK
 	return UnblindPublicKeyWithContext(c, pk, bk, nil)
 }
 
-// BlindKeySignWithContext blinds the signing key by a blind, with a context string, and then produces a signature over the hashed input.
-func BlindKeySignWithContext(rand io.Reader, skS *PrivateKey, skB *PrivateKey, hash []byte, context []byte) (r, s *big.Int, err error) {
+// blindPrivateKey returns the signing key skS blinded by skB and the context string.
+func blindPrivateKey(skS *PrivateKey, skB *PrivateKey, context []byte) (*PrivateKey, error) {
 	pkB, err := BlindPublicKeyWithContext(skS.Curve, &skS.PublicKey, skB, context)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	skBlind, err := hashBlind(skS.Curve, skB, context)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	Db := new(big.Int).Mul(skS.D, skBlind)
-	Db.Mod(Db, skS.Curve.Params().N)
-	skR := &PrivateKey{
+	Db, err := blindPrivateScalar(skS.Curve, skS.D, skBlind)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{
 		*pkB,
 		Db,
+	}, nil
+}
+
+// blindPrivateScalarGeneric returns d * blind mod N using the variable-time
+// big.Int implementation.
+func blindPrivateScalarGeneric(c elliptic.Curve, d, blind *big.Int) *big.Int {
+	Db := new(big.Int).Mul(d, blind)
+	return Db.Mod(Db, c.Params().N)
+}
+
+// BlindKeySignWithContext blinds the signing key by a blind, with a context string, and then produces a signature over the hashed input.
+func BlindKeySignWithContext(rand io.Reader, skS *PrivateKey, skB *PrivateKey, hash []byte, context []byte) (r, s *big.Int, err error) {
+	skR, err := blindPrivateKey(skS, skB, context)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return Sign(rand, skR, hash)
@@ -264,6 +299,29 @@ func BlindKeySign(rand io.Reader, skS *PrivateKey, skB *PrivateKey, hash []byte)
 	return BlindKeySignWithContext(rand, skS, skB, hash, nil)
 }
 
+// VerifyBlindKeySignatureWithContext checks that pkR is the blinding of pkS by bk
+// with the context string, and that (r, s) is a valid signature of hash under pkR,
+// as produced by BlindKeySignWithContext. It returns nil only if both checks pass.
+func VerifyBlindKeySignatureWithContext(pkS, pkR *PublicKey, bk *PrivateKey, hash []byte, r, s *big.Int, context []byte) error {
+	expected, err := BlindPublicKeyWithContext(pkS.Curve, pkS, bk, context)
+	if err != nil {
+		return err
+	}
+	if !expected.Equal(pkR) {
+		return errors.New("ecdsa: blinded public key mismatch")
+	}
+	if !Verify(pkR, hash, r, s) {
+		return errors.New("ecdsa: invalid blinded key signature")
+	}
+	return nil
+}
+
+// VerifyBlindKeySignature checks that pkR is the blinding of pkS by bk with an
+// empty context string, and that (r, s) is a valid signature of hash under pkR.
+func VerifyBlindKeySignature(pkS, pkR *PublicKey, bk *PrivateKey, hash []byte, r, s *big.Int) error {
+	return VerifyBlindKeySignatureWithContext(pkS, pkR, bk, hash, r, s, nil)
+}
+
 // hashToInt converts a hash value to an integer. There is some disagreement
 // about how this is done. [NSA] suggests that this is done in the obvious
 // manner, but [SECG] truncates the hash to the bit-length of the curve order
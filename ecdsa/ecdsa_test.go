@@ -121,6 +121,52 @@ func testBlindKeySign(t *testing.T, c elliptic.Curve) {
 	}
 }
 
+func TestVerifyBlindKeySignature(t *testing.T) {
+	testAllCurves(t, testVerifyBlindKeySignature)
+}
+
+func testVerifyBlindKeySignature(t *testing.T, c elliptic.Curve) {
+	skS, _ := GenerateKey(c, rand.Reader)
+	skB, _ := GenerateKey(c, rand.Reader)
+	other, _ := GenerateKey(c, rand.Reader)
+	context := []byte("context")
+
+	hashed := []byte("testing")
+	r, s, err := BlindKeySignWithContext(rand.Reader, skS, skB, hashed, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkR, err := BlindPublicKeyWithContext(c, &skS.PublicKey, skB, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyBlindKeySignatureWithContext(&skS.PublicKey, pkR, skB, hashed, r, s, context); err != nil {
+		t.Errorf("valid blinded signature rejected: %v", err)
+	}
+	if err := VerifyBlindKeySignatureWithContext(&other.PublicKey, pkR, skB, hashed, r, s, context); err == nil {
+		t.Errorf("blinded signature accepted for a different client key")
+	}
+	if err := VerifyBlindKeySignatureWithContext(&skS.PublicKey, pkR, other, hashed, r, s, context); err == nil {
+		t.Errorf("blinded signature accepted for a different blind")
+	}
+	if err := VerifyBlindKeySignatureWithContext(&skS.PublicKey, pkR, skB, hashed, r, s, nil); err == nil {
+		t.Errorf("blinded signature accepted for a different context")
+	}
+	if err := VerifyBlindKeySignatureWithContext(&skS.PublicKey, pkR, skB, []byte("wrong"), r, s, context); err == nil {
+		t.Errorf("blinded signature of a different hash accepted")
+	}
+
+	r, s, err = BlindKeySign(rand.Reader, skS, skB, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkR, _ = BlindPublicKey(c, &skS.PublicKey, skB)
+	if err := VerifyBlindKeySignature(&skS.PublicKey, pkR, skB, hashed, r, s); err != nil {
+		t.Errorf("valid blinded signature rejected: %v", err)
+	}
+}
+
 func TestBlindPublicKey(t *testing.T) {
 	testAllCurves(t, testBlindPublicKey)
 }
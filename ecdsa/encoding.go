@@ -0,0 +1,366 @@
+package ecdsa
+
+import (
+	stdecdsa "crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cloudflare/pat-go/util"
+)
+
+// This file implements encoding and decoding of the package's key types. Points
+// are encoded as specified in SEC 1, Version 2.0, Section 2.3.3, public keys as
+// PKIX SubjectPublicKeyInfo structures (RFC 5480), and private keys as PKCS #8
+// structures (RFC 5208) wrapping SEC 1 ECPrivateKey structures (RFC 5915).
+// The latter two are also available as PEM blocks, and keys can be encoded as
+// "EC" JSON Web Keys (RFC 7518, Section 6.2).
+//
+// All decoding functions reject points that are not on the curve, as well as
+// the point at infinity, which is never a valid public key.
+
+var (
+	errPointAtInfinity = errors.New("ecdsa: public key is the point at infinity")
+	errPointNotOnCurve = errors.New("ecdsa: public key is not on the curve")
+)
+
+// checkPublicKey returns an error if pub is not a valid, non-identity point
+// on its curve.
+func checkPublicKey(pub *PublicKey) error {
+	if pub == nil || pub.Curve == nil || pub.X == nil || pub.Y == nil {
+		return errors.New("ecdsa: incomplete public key")
+	}
+	if pub.X.Sign() == 0 && pub.Y.Sign() == 0 {
+		return errPointAtInfinity
+	}
+	p := pub.Curve.Params().P
+	if pub.X.Sign() < 0 || pub.X.Cmp(p) >= 0 || pub.Y.Sign() < 0 || pub.Y.Cmp(p) >= 0 {
+		return errPointNotOnCurve
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return errPointNotOnCurve
+	}
+	return nil
+}
+
+// checkPrivateKey returns an error if priv does not hold a scalar in [1, N-1]
+// matching its public key.
+func checkPrivateKey(priv *PrivateKey) error {
+	if err := checkPublicKey(&priv.PublicKey); err != nil {
+		return err
+	}
+	if priv.D == nil || priv.D.Sign() <= 0 || priv.D.Cmp(priv.Curve.Params().N) >= 0 {
+		return errors.New("ecdsa: invalid private key scalar")
+	}
+	x, y := priv.Curve.ScalarBaseMult(priv.D.Bytes())
+	if x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+		return errors.New("ecdsa: private key does not match public key")
+	}
+	return nil
+}
+
+// MarshalCompressed encodes pub as a compressed SEC 1 point.
+func MarshalCompressed(pub *PublicKey) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y), nil
+}
+
+// MarshalUncompressed encodes pub as an uncompressed SEC 1 point.
+func MarshalUncompressed(pub *PublicKey) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return elliptic.Marshal(pub.Curve, pub.X, pub.Y), nil
+}
+
+// UnmarshalCompressed decodes a public key on curve c from a compressed SEC 1
+// point.
+func UnmarshalCompressed(c elliptic.Curve, data []byte) (*PublicKey, error) {
+	if len(data) == 1 && data[0] == 0 {
+		return nil, errPointAtInfinity
+	}
+	x, y := elliptic.UnmarshalCompressed(c, data)
+	if x == nil || y == nil {
+		return nil, fmt.Errorf("ecdsa: invalid compressed %s point encoding", c.Params().Name)
+	}
+	pub := &PublicKey{
+		Curve: c,
+		X:     x,
+		Y:     y,
+	}
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// UnmarshalPublicKey decodes a public key on curve c from a compressed or
+// uncompressed SEC 1 point.
+func UnmarshalPublicKey(c elliptic.Curve, data []byte) (*PublicKey, error) {
+	byteLen := (c.Params().BitSize + 7) / 8
+	switch {
+	case len(data) == 1 && data[0] == 0:
+		return nil, errPointAtInfinity
+	case len(data) == 1+byteLen && (data[0] == 2 || data[0] == 3):
+		return UnmarshalCompressed(c, data)
+	case len(data) == 1+2*byteLen && data[0] == 4:
+		pub := &PublicKey{
+			Curve: c,
+			X:     new(big.Int).SetBytes(data[1 : 1+byteLen]),
+			Y:     new(big.Int).SetBytes(data[1+byteLen:]),
+		}
+		if err := checkPublicKey(pub); err != nil {
+			return nil, err
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("ecdsa: invalid %s point encoding", c.Params().Name)
+	}
+}
+
+// MarshalPKIXPublicKey encodes pub as a DER PKIX SubjectPublicKeyInfo
+// structure with an uncompressed point.
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKIXPublicKey(&stdecdsa.PublicKey{
+		Curve: pub.Curve,
+		X:     pub.X,
+		Y:     pub.Y,
+	})
+}
+
+// UnmarshalPKIXPublicKey decodes a public key from a DER PKIX
+// SubjectPublicKeyInfo structure.
+func UnmarshalPKIXPublicKey(der []byte) (*PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	stdPub, ok := key.(*stdecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ecdsa: unexpected public key type %T", key)
+	}
+	pub := &PublicKey{
+		Curve: stdPub.Curve,
+		X:     stdPub.X,
+		Y:     stdPub.Y,
+	}
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// MarshalPKCS8PrivateKey encodes priv as a DER PKCS #8 PrivateKeyInfo
+// structure.
+func MarshalPKCS8PrivateKey(priv *PrivateKey) ([]byte, error) {
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS8PrivateKey(&stdecdsa.PrivateKey{
+		PublicKey: stdecdsa.PublicKey{
+			Curve: priv.Curve,
+			X:     priv.X,
+			Y:     priv.Y,
+		},
+		D: priv.D,
+	})
+}
+
+// UnmarshalPKCS8PrivateKey decodes a private key from a DER PKCS #8
+// PrivateKeyInfo structure.
+func UnmarshalPKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	stdPriv, ok := key.(*stdecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("ecdsa: unexpected private key type %T", key)
+	}
+	priv := &PrivateKey{
+		PublicKey: PublicKey{
+			Curve: stdPriv.Curve,
+			X:     stdPriv.X,
+			Y:     stdPriv.Y,
+		},
+		D: stdPriv.D,
+	}
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+// MarshalPublicKeyPEM encodes pub as a PEM "PUBLIC KEY" block holding its PKIX
+// encoding.
+func MarshalPublicKeyPEM(pub *PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// UnmarshalPublicKeyPEM decodes a public key from a PEM "PUBLIC KEY" block.
+func UnmarshalPublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("ecdsa: invalid PEM public key encoding")
+	}
+	return UnmarshalPKIXPublicKey(block.Bytes)
+}
+
+// MarshalPrivateKeyPEM encodes priv as a PEM "PRIVATE KEY" block holding its
+// PKCS #8 encoding.
+func MarshalPrivateKeyPEM(priv *PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// UnmarshalPrivateKeyPEM decodes a private key from a PEM "PRIVATE KEY" block.
+func UnmarshalPrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("ecdsa: invalid PEM private key encoding")
+	}
+	return UnmarshalPKCS8PrivateKey(block.Bytes)
+}
+
+// jwkCurves maps JWK curve names to the curves they identify.
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func jwkCurveName(c elliptic.Curve) (string, error) {
+	for name, curve := range jwkCurves {
+		if c == curve {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("ecdsa: unsupported JWK curve %s", c.Params().Name)
+}
+
+// Coordinates and the private scalar are encoded at their full length.
+func encodeJWKInt(n *big.Int, byteLen int) string {
+	return util.EncodeJWKMember(n.FillBytes(make([]byte, byteLen)))
+}
+
+func decodeJWKInt(name, value string, byteLen int) (*big.Int, error) {
+	b, err := util.DecodeJWKMember(name, value, byteLen)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa: %v", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func publicJWK(pub *PublicKey) (*util.JWK, error) {
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	name, err := jwkCurveName(pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+	byteLen := (pub.Curve.Params().BitSize + 7) / 8
+	return &util.JWK{
+		KeyType: "EC",
+		Curve:   name,
+		X:       encodeJWKInt(pub.X, byteLen),
+		Y:       encodeJWKInt(pub.Y, byteLen),
+	}, nil
+}
+
+func parsePublicJWK(k *util.JWK) (*PublicKey, error) {
+	if k.KeyType != "EC" {
+		return nil, fmt.Errorf("ecdsa: unexpected JWK key type %q", k.KeyType)
+	}
+	c, ok := jwkCurves[k.Curve]
+	if !ok {
+		return nil, fmt.Errorf("ecdsa: unsupported JWK curve %q", k.Curve)
+	}
+	byteLen := (c.Params().BitSize + 7) / 8
+	x, err := decodeJWKInt("x", k.X, byteLen)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJWKInt("y", k.Y, byteLen)
+	if err != nil {
+		return nil, err
+	}
+	pub := &PublicKey{Curve: c, X: x, Y: y}
+	if err := checkPublicKey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// MarshalPublicKeyJWK encodes pub as an "EC" JSON Web Key.
+func MarshalPublicKeyJWK(pub *PublicKey) ([]byte, error) {
+	k, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(k)
+}
+
+// UnmarshalPublicKeyJWK decodes a public key from an "EC" JSON Web Key. Any
+// private scalar is ignored.
+func UnmarshalPublicKeyJWK(data []byte) (*PublicKey, error) {
+	k, err := util.UnmarshalJWK(data)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa: %v", err)
+	}
+	return parsePublicJWK(k)
+}
+
+// MarshalPrivateKeyJWK encodes priv as an "EC" JSON Web Key.
+func MarshalPrivateKeyJWK(priv *PrivateKey) ([]byte, error) {
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	k, err := publicJWK(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	k.D = encodeJWKInt(priv.D, (priv.Curve.Params().N.BitLen()+7)/8)
+	return json.Marshal(k)
+}
+
+// UnmarshalPrivateKeyJWK decodes a private key from an "EC" JSON Web Key,
+// which must hold a private scalar matching its public key.
+func UnmarshalPrivateKeyJWK(data []byte) (*PrivateKey, error) {
+	k, err := util.UnmarshalJWK(data)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa: %v", err)
+	}
+	pub, err := parsePublicJWK(k)
+	if err != nil {
+		return nil, err
+	}
+	if k.D == "" {
+		return nil, errors.New("ecdsa: JWK has no private key")
+	}
+	d, err := decodeJWKInt("d", k.D, (pub.Curve.Params().N.BitLen()+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	priv := &PrivateKey{PublicKey: *pub, D: d}
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
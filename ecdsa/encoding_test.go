@@ -0,0 +1,298 @@
+package ecdsa
+
+import (
+	stdecdsa "crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestPointEncoding(t *testing.T) {
+	testAllCurves(t, testPointEncoding)
+}
+
+func testPointEncoding(t *testing.T, c elliptic.Curve) {
+	skS, _ := GenerateKey(c, rand.Reader)
+	skB, _ := GenerateKey(c, rand.Reader)
+	pkR, err := BlindPublicKey(c, &skS.PublicKey, skB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pub := range []*PublicKey{&skS.PublicKey, pkR} {
+		compressed, err := MarshalCompressed(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byteLen := (c.Params().BitSize + 7) / 8
+		if len(compressed) != 1+byteLen {
+			t.Errorf("unexpected compressed length %d", len(compressed))
+		}
+		uncompressed, err := MarshalUncompressed(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(uncompressed) != 1+2*byteLen {
+			t.Errorf("unexpected uncompressed length %d", len(uncompressed))
+		}
+
+		got, err := UnmarshalCompressed(c, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(pub) {
+			t.Error("compressed round-trip mismatch")
+		}
+		for _, enc := range [][]byte{compressed, uncompressed} {
+			got, err := UnmarshalPublicKey(c, enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(pub) {
+				t.Error("round-trip mismatch")
+			}
+		}
+		if _, err := UnmarshalCompressed(c, uncompressed); err == nil {
+			t.Error("UnmarshalCompressed accepted an uncompressed point")
+		}
+	}
+}
+
+func TestInvalidPointEncoding(t *testing.T) {
+	testAllCurves(t, testInvalidPointEncoding)
+}
+
+func testInvalidPointEncoding(t *testing.T, c elliptic.Curve) {
+	sk, _ := GenerateKey(c, rand.Reader)
+	uncompressed, _ := MarshalUncompressed(&sk.PublicKey)
+	compressed, _ := MarshalCompressed(&sk.PublicKey)
+	byteLen := (c.Params().BitSize + 7) / 8
+
+	offCurve := append([]byte{}, uncompressed...)
+	offCurve[len(offCurve)-1] ^= 1
+
+	// An x-coordinate equal to the field modulus.
+	xTooLarge := append([]byte{2}, c.Params().P.FillBytes(make([]byte, byteLen))...)
+
+	badType := append([]byte{}, compressed...)
+	badType[0] = 5
+
+	for name, enc := range map[string][]byte{
+		"infinity":  {0},
+		"empty":     {},
+		"off-curve": offCurve,
+		"x >= p":    xTooLarge,
+		"truncated": compressed[:len(compressed)-1],
+		"bad type":  badType,
+	} {
+		if _, err := UnmarshalPublicKey(c, enc); err == nil {
+			t.Errorf("%s: UnmarshalPublicKey succeeded", name)
+		}
+		if _, err := UnmarshalCompressed(c, enc); err == nil {
+			t.Errorf("%s: UnmarshalCompressed succeeded", name)
+		}
+	}
+
+	for name, pub := range map[string]*PublicKey{
+		"infinity":  {Curve: c, X: new(big.Int), Y: new(big.Int)},
+		"off-curve": {Curve: c, X: sk.X, Y: new(big.Int).Add(sk.Y, one)},
+		"negative":  {Curve: c, X: new(big.Int).Neg(sk.X), Y: sk.Y},
+		"nil":       {Curve: c},
+	} {
+		if _, err := MarshalCompressed(pub); err == nil {
+			t.Errorf("%s: MarshalCompressed succeeded", name)
+		}
+		if _, err := MarshalUncompressed(pub); err == nil {
+			t.Errorf("%s: MarshalUncompressed succeeded", name)
+		}
+		if _, err := MarshalPKIXPublicKey(pub); err == nil {
+			t.Errorf("%s: MarshalPKIXPublicKey succeeded", name)
+		}
+	}
+}
+
+func TestPKIXAndPKCS8Encoding(t *testing.T) {
+	testAllCurves(t, testPKIXAndPKCS8Encoding)
+}
+
+func testPKIXAndPKCS8Encoding(t *testing.T, c elliptic.Curve) {
+	skS, _ := GenerateKey(c, rand.Reader)
+	skB, _ := GenerateKey(c, rand.Reader)
+	skR, err := blindPrivateKey(skS, skB, []byte("context"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, priv := range []*PrivateKey{skS, skR} {
+		spki, err := MarshalPKIXPublicKey(&priv.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := UnmarshalPKIXPublicKey(spki)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(&priv.PublicKey) {
+			t.Error("PKIX round-trip mismatch")
+		}
+
+		// The encoding must interoperate with crypto/x509.
+		stdPub, err := x509.ParsePKIXPublicKey(spki)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k := stdPub.(*stdecdsa.PublicKey); k.X.Cmp(priv.X) != 0 || k.Y.Cmp(priv.Y) != 0 {
+			t.Error("crypto/x509 decoded a different public key")
+		}
+
+		pkcs8, err := MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := UnmarshalPKCS8PrivateKey(pkcs8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(priv) {
+			t.Error("PKCS #8 round-trip mismatch")
+		}
+	}
+
+	mismatched := &PrivateKey{PublicKey: skS.PublicKey, D: skB.D}
+	if _, err := MarshalPKCS8PrivateKey(mismatched); err == nil {
+		t.Error("MarshalPKCS8PrivateKey accepted a mismatched key pair")
+	}
+	zero := &PrivateKey{PublicKey: skS.PublicKey, D: new(big.Int)}
+	if _, err := MarshalPKCS8PrivateKey(zero); err == nil {
+		t.Error("MarshalPKCS8PrivateKey accepted a zero scalar")
+	}
+}
+
+func TestParseWrongKeyType(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	spki, _ := x509.MarshalPKIXPublicKey(pub)
+	if _, err := UnmarshalPKIXPublicKey(spki); err == nil {
+		t.Error("UnmarshalPKIXPublicKey accepted an Ed25519 key")
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(priv)
+	if _, err := UnmarshalPKCS8PrivateKey(pkcs8); err == nil {
+		t.Error("UnmarshalPKCS8PrivateKey accepted an Ed25519 key")
+	}
+	if _, err := UnmarshalPKIXPublicKey(spki[:len(spki)-1]); err == nil {
+		t.Error("UnmarshalPKIXPublicKey accepted a truncated encoding")
+	}
+}
+
+func TestPEMEncoding(t *testing.T) {
+	testAllCurves(t, testPEMEncoding)
+}
+
+func testPEMEncoding(t *testing.T, c elliptic.Curve) {
+	priv, _ := GenerateKey(c, rand.Reader)
+
+	pubPEM, err := MarshalPublicKeyPEM(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := UnmarshalPublicKeyPEM(pubPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(&priv.PublicKey) {
+		t.Error("PEM public key round-trip mismatch")
+	}
+
+	privPEM, err := MarshalPrivateKeyPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalPrivateKeyPEM(privPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(priv) {
+		t.Error("PEM private key round-trip mismatch")
+	}
+
+	if _, err := UnmarshalPublicKeyPEM(privPEM); err == nil {
+		t.Error("UnmarshalPublicKeyPEM accepted a private key block")
+	}
+	if _, err := UnmarshalPrivateKeyPEM(pubPEM); err == nil {
+		t.Error("UnmarshalPrivateKeyPEM accepted a public key block")
+	}
+}
+
+func TestJWKEncoding(t *testing.T) {
+	testAllCurves(t, testJWKEncoding)
+}
+
+func testJWKEncoding(t *testing.T, c elliptic.Curve) {
+	priv, _ := GenerateKey(c, rand.Reader)
+	if c == elliptic.P224() {
+		if _, err := MarshalPublicKeyJWK(&priv.PublicKey); err == nil {
+			t.Error("MarshalPublicKeyJWK accepted a curve with no JWK name")
+		}
+		return
+	}
+
+	pubJWK, err := MarshalPublicKeyJWK(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := UnmarshalPublicKeyJWK(pubJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(&priv.PublicKey) {
+		t.Error("JWK public key round-trip mismatch")
+	}
+	if _, err := UnmarshalPrivateKeyJWK(pubJWK); err == nil {
+		t.Error("UnmarshalPrivateKeyJWK accepted a public key")
+	}
+
+	privJWK, err := MarshalPrivateKeyJWK(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalPrivateKeyJWK(privJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(priv) {
+		t.Error("JWK private key round-trip mismatch")
+	}
+
+	var members map[string]string
+	if err := json.Unmarshal(privJWK, &members); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := GenerateKey(c, rand.Reader)
+	otherJWK, _ := MarshalPrivateKeyJWK(other)
+	var otherMembers map[string]string
+	if err := json.Unmarshal(otherJWK, &otherMembers); err != nil {
+		t.Fatal(err)
+	}
+	for name, tampered := range map[string]map[string]string{
+		"mismatched d":   {"d": otherMembers["d"]},
+		"swapped x":      {"x": otherMembers["x"]},
+		"truncated x":    {"x": members["x"][4:]},
+		"wrong key type": {"kty": "OKP"},
+		"wrong curve":    {"crv": "P-224"},
+	} {
+		m := make(map[string]string)
+		for k, v := range members {
+			m[k] = v
+		}
+		for k, v := range tampered {
+			m[k] = v
+		}
+		data, _ := json.Marshal(m)
+		if _, err := UnmarshalPrivateKeyJWK(data); err == nil {
+			t.Errorf("%s: UnmarshalPrivateKeyJWK succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nistec implements the NIST P-256, P-384, and P-521 elliptic curves.
+//
+// This package uses fiat-crypto for its backend field arithmetic (not
+// math/big) and exposes constant-time, heap allocation-free, byte slice-based
+// safe APIs. Group operations use modern and safe complete addition formulas.
+// The point at infinity is handled and encoded according to SEC 1, Version
+// 2.0, and invalid curve points can't be represented.
+//
+// It is a copy of the Go standard library's crypto/internal/nistec package,
+// which cannot be imported from outside the standard library. The P-256
+// implementation is generated from the same template as P-384 and P-521
+// rather than using the specialized assembly of the standard library.
+package nistec
@@ -0,0 +1,99 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nistec_test
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/cloudflare/pat-go/ecdsa/internal/nistec"
+)
+
+type nistPoint[T any] interface {
+	Bytes() []byte
+	BytesCompressed() []byte
+	SetGenerator() T
+	SetBytes([]byte) (T, error)
+	Add(T, T) T
+	Double(T) T
+	ScalarMult(T, []byte) (T, error)
+	ScalarBaseMult([]byte) (T, error)
+}
+
+func TestEquivalents(t *testing.T) {
+	t.Run("P256", func(t *testing.T) {
+		testEquivalents(t, nistec.NewP256Point, elliptic.P256())
+	})
+	t.Run("P384", func(t *testing.T) {
+		testEquivalents(t, nistec.NewP384Point, elliptic.P384())
+	})
+	t.Run("P521", func(t *testing.T) {
+		testEquivalents(t, nistec.NewP521Point, elliptic.P521())
+	})
+}
+
+func testEquivalents[P nistPoint[P]](t *testing.T, newPoint func() P, c elliptic.Curve) {
+	p := newPoint().SetGenerator()
+	byteLen := (c.Params().N.BitLen() + 7) / 8
+
+	for i := 0; i < 16; i++ {
+		k, err := rand.Int(rand.Reader, c.Params().N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scalar := k.FillBytes(make([]byte, byteLen))
+
+		wantX, wantY := c.ScalarBaseMult(scalar)
+		want := elliptic.Marshal(c, wantX, wantY)
+
+		p1, err := newPoint().ScalarBaseMult(scalar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := newPoint().ScalarMult(p, scalar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p1.Bytes(), want) {
+			t.Error("ScalarBaseMult mismatch")
+		}
+		if !bytes.Equal(p2.Bytes(), want) {
+			t.Error("ScalarMult mismatch")
+		}
+
+		// Round-trip through the compressed encoding, which exercises the
+		// square root computation.
+		p3, err := newPoint().SetBytes(p1.BytesCompressed())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p3.Bytes(), want) {
+			t.Error("compressed encoding round-trip mismatch")
+		}
+	}
+
+	two := newPoint().Double(p)
+	sum := newPoint().Add(p, p)
+	if !bytes.Equal(two.Bytes(), sum.Bytes()) {
+		t.Error("P+P != 2*P")
+	}
+
+	n := c.Params().N.FillBytes(make([]byte, byteLen))
+	inf, err := newPoint().ScalarMult(p, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(inf.Bytes(), []byte{0}) {
+		t.Error("[N]G is not the point at infinity")
+	}
+
+	offCurve := elliptic.Marshal(c, c.Params().Gx, c.Params().Gy)
+	offCurve[len(offCurve)-1] ^= 1
+	if _, err := newPoint().SetBytes(offCurve); err == nil {
+		t.Error("SetBytes accepted a point not on the curve")
+	}
+}
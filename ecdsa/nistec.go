@@ -0,0 +1,210 @@
+package ecdsa
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/cloudflare/pat-go/ecdsa/internal/bigmod"
+	"github.com/cloudflare/pat-go/ecdsa/internal/nistec"
+)
+
+// This file implements the key blinding operations for P-256, P-384, and
+// P-521 on top of constant-time field and point arithmetic with fixed-width
+// scalars, rather than on elliptic.Curve.ScalarMult and big.Int modular
+// arithmetic, both of which are variable-time.
+
+// nistPoint is a generic constraint for the nistec Point types.
+type nistPoint[T any] interface {
+	*nistec.P256Point | *nistec.P384Point | *nistec.P521Point
+	Bytes() []byte
+	BytesCompressed() []byte
+	SetBytes([]byte) (T, error)
+	Add(T, T) T
+	ScalarMult(T, []byte) (T, error)
+}
+
+// nistCurve is a constant-time implementation of a NIST P curve and of its
+// scalar field.
+type nistCurve[Point nistPoint[Point]] struct {
+	curve    elliptic.Curve
+	newPoint func() Point
+	N        *bigmod.Modulus
+	// nMinus2 is N - 2 encoded as a big-endian byte string, used to compute
+	// inverses modulo N by Fermat's little theorem.
+	nMinus2 []byte
+}
+
+func newNISTCurve[Point nistPoint[Point]](curve elliptic.Curve, newPoint func() Point) *nistCurve[Point] {
+	N, err := bigmod.NewModulus(curve.Params().N.Bytes())
+	if err != nil {
+		panic("ecdsa: internal error: invalid curve order")
+	}
+	nMinus2 := new(big.Int).Sub(curve.Params().N, big.NewInt(2))
+	return &nistCurve[Point]{
+		curve:    curve,
+		newPoint: newPoint,
+		N:        N,
+		nMinus2:  nMinus2.FillBytes(make([]byte, N.Size())),
+	}
+}
+
+var p256Once sync.Once
+var _p256 *nistCurve[*nistec.P256Point]
+
+func p256() *nistCurve[*nistec.P256Point] {
+	p256Once.Do(func() {
+		_p256 = newNISTCurve(elliptic.P256(), nistec.NewP256Point)
+	})
+	return _p256
+}
+
+var p384Once sync.Once
+var _p384 *nistCurve[*nistec.P384Point]
+
+func p384() *nistCurve[*nistec.P384Point] {
+	p384Once.Do(func() {
+		_p384 = newNISTCurve(elliptic.P384(), nistec.NewP384Point)
+	})
+	return _p384
+}
+
+var p521Once sync.Once
+var _p521 *nistCurve[*nistec.P521Point]
+
+func p521() *nistCurve[*nistec.P521Point] {
+	p521Once.Do(func() {
+		_p521 = newNISTCurve(elliptic.P521(), nistec.NewP521Point)
+	})
+	return _p521
+}
+
+// scalar converts k, which must be in [0, N), to a fixed-width scalar.
+func (c *nistCurve[Point]) scalar(k *big.Int) (*bigmod.Nat, error) {
+	if k.Sign() < 0 || k.BitLen() > c.N.BitLen() {
+		return nil, errors.New("ecdsa: invalid scalar")
+	}
+	return bigmod.NewNat().SetBytes(k.FillBytes(make([]byte, c.N.Size())), c.N)
+}
+
+// point converts pk to a nistec point, checking that it is on the curve.
+func (c *nistCurve[Point]) point(pk *PublicKey) (Point, error) {
+	if pk.X == nil || pk.Y == nil || pk.X.Sign() < 0 || pk.Y.Sign() < 0 {
+		return nil, errors.New("ecdsa: invalid public key")
+	}
+	byteLen := (c.curve.Params().BitSize + 7) / 8
+	if pk.X.BitLen() > byteLen*8 || pk.Y.BitLen() > byteLen*8 {
+		return nil, errors.New("ecdsa: invalid public key")
+	}
+	buf := make([]byte, 1+2*byteLen)
+	buf[0] = 4 // uncompressed point
+	pk.X.FillBytes(buf[1 : 1+byteLen])
+	pk.Y.FillBytes(buf[1+byteLen:])
+	return c.newPoint().SetBytes(buf)
+}
+
+// publicKey converts p to a PublicKey, rejecting the point at infinity.
+func (c *nistCurve[Point]) publicKey(p Point) (*PublicKey, error) {
+	out := p.Bytes()
+	if len(out) == 1 {
+		return nil, errors.New("ecdsa: blinded public key is the point at infinity")
+	}
+	byteLen := (len(out) - 1) / 2
+	return &PublicKey{
+		Curve: c.curve,
+		X:     new(big.Int).SetBytes(out[1 : 1+byteLen]),
+		Y:     new(big.Int).SetBytes(out[1+byteLen:]),
+	}, nil
+}
+
+// scalarMult returns [k]pk.
+func (c *nistCurve[Point]) scalarMult(pk *PublicKey, k *bigmod.Nat) (*PublicKey, error) {
+	p, err := c.point(pk)
+	if err != nil {
+		return nil, err
+	}
+	q, err := c.newPoint().ScalarMult(p, k.Bytes(c.N))
+	if err != nil {
+		return nil, err
+	}
+	return c.publicKey(q)
+}
+
+// blindPublicKey returns [blind]pk.
+func (c *nistCurve[Point]) blindPublicKey(pk *PublicKey, blind *big.Int) (*PublicKey, error) {
+	k, err := c.scalar(blind)
+	if err != nil {
+		return nil, err
+	}
+	return c.scalarMult(pk, k)
+}
+
+// unblindPublicKey returns [blind^-1]pk.
+func (c *nistCurve[Point]) unblindPublicKey(pk *PublicKey, blind *big.Int) (*PublicKey, error) {
+	k, err := c.scalar(blind)
+	if err != nil {
+		return nil, err
+	}
+	kInv := bigmod.NewNat().Exp(k, c.nMinus2, c.N)
+	return c.scalarMult(pk, kInv)
+}
+
+// blindPrivateKey returns d * blind mod N.
+func (c *nistCurve[Point]) blindPrivateKey(d, blind *big.Int) (*big.Int, error) {
+	k, err := c.scalar(blind)
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.scalar(d)
+	if err != nil {
+		return nil, err
+	}
+	db.Mul(k, c.N)
+	return new(big.Int).SetBytes(db.Bytes(c.N)), nil
+}
+
+// blindPublicKey returns [blind]pk, using the constant-time implementation for
+// the curves that have one.
+func blindPublicKey(c elliptic.Curve, pk *PublicKey, blind *big.Int) (*PublicKey, error) {
+	switch c.Params().Name {
+	case "P-256":
+		return p256().blindPublicKey(pk, blind)
+	case "P-384":
+		return p384().blindPublicKey(pk, blind)
+	case "P-521":
+		return p521().blindPublicKey(pk, blind)
+	default:
+		return blindPublicKeyGeneric(c, pk, blind)
+	}
+}
+
+// unblindPublicKey returns [blind^-1]pk, using the constant-time
+// implementation for the curves that have one.
+func unblindPublicKey(c elliptic.Curve, pk *PublicKey, blind *big.Int) (*PublicKey, error) {
+	switch c.Params().Name {
+	case "P-256":
+		return p256().unblindPublicKey(pk, blind)
+	case "P-384":
+		return p384().unblindPublicKey(pk, blind)
+	case "P-521":
+		return p521().unblindPublicKey(pk, blind)
+	default:
+		return unblindPublicKeyGeneric(c, pk, blind)
+	}
+}
+
+// blindPrivateScalar returns d * blind mod N, using the constant-time
+// implementation for the curves that have one.
+func blindPrivateScalar(c elliptic.Curve, d, blind *big.Int) (*big.Int, error) {
+	switch c.Params().Name {
+	case "P-256":
+		return p256().blindPrivateKey(d, blind)
+	case "P-384":
+		return p384().blindPrivateKey(d, blind)
+	case "P-521":
+		return p521().blindPrivateKey(d, blind)
+	default:
+		return blindPrivateScalarGeneric(c, d, blind), nil
+	}
+}
@@ -0,0 +1,189 @@
+package ecdsa
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+var nistCurves = []struct {
+	name  string
+	curve elliptic.Curve
+}{
+	{"P256", elliptic.P256()},
+	{"P384", elliptic.P384()},
+	{"P521", elliptic.P521()},
+}
+
+func randomBlind(t testing.TB, c elliptic.Curve) *big.Int {
+	blind, err := randFieldElement(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blind
+}
+
+func TestBlindingMatchesGeneric(t *testing.T) {
+	for _, test := range nistCurves {
+		c := test.curve
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 16; i++ {
+				sk, err := GenerateKey(c, rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				blind := randomBlind(t, c)
+
+				got, err := blindPublicKey(c, &sk.PublicKey, blind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := blindPublicKeyGeneric(c, &sk.PublicKey, blind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.Equal(want) {
+					t.Fatal("blinded public key mismatch")
+				}
+
+				got, err = unblindPublicKey(c, &sk.PublicKey, blind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err = unblindPublicKeyGeneric(c, &sk.PublicKey, blind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.Equal(want) {
+					t.Fatal("unblinded public key mismatch")
+				}
+
+				gotD, err := blindPrivateScalar(c, sk.D, blind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if gotD.Cmp(blindPrivateScalarGeneric(c, sk.D, blind)) != 0 {
+					t.Fatal("blinded private key mismatch")
+				}
+			}
+		})
+	}
+}
+
+func TestBlindingInvalidInputs(t *testing.T) {
+	for _, test := range nistCurves {
+		c := test.curve
+		t.Run(test.name, func(t *testing.T) {
+			sk, err := GenerateKey(c, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A scalar that is not reduced modulo N.
+			if _, err := blindPublicKey(c, &sk.PublicKey, c.Params().N); err == nil {
+				t.Error("blinding by N succeeded")
+			}
+			if _, err := blindPrivateScalar(c, c.Params().N, big.NewInt(1)); err == nil {
+				t.Error("blinding private scalar N succeeded")
+			}
+
+			// A zero blind maps any key to the point at infinity.
+			if _, err := blindPublicKey(c, &sk.PublicKey, new(big.Int)); err == nil {
+				t.Error("blinding by zero succeeded")
+			}
+			if _, err := unblindPublicKey(c, &sk.PublicKey, new(big.Int)); err == nil {
+				t.Error("unblinding by zero succeeded")
+			}
+
+			// A point that is not on the curve.
+			offCurve := &PublicKey{
+				Curve: c,
+				X:     new(big.Int).Set(sk.X),
+				Y:     new(big.Int).Add(sk.Y, big.NewInt(1)),
+			}
+			if _, err := blindPublicKey(c, offCurve, big.NewInt(1)); err == nil {
+				t.Error("blinding an invalid point succeeded")
+			}
+		})
+	}
+}
+
+func benchmarkNISTCurves(b *testing.B, f func(*testing.B, elliptic.Curve)) {
+	for _, test := range nistCurves {
+		curve := test.curve
+		b.Run(test.name, func(b *testing.B) {
+			f(b, curve)
+		})
+	}
+}
+
+func BenchmarkBlindPublicKey(b *testing.B) {
+	benchmarkNISTCurves(b, func(b *testing.B, c elliptic.Curve) {
+		sk, _ := GenerateKey(c, rand.Reader)
+		blind := randomBlind(b, c)
+
+		b.Run("ConstantTime", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := blindPublicKey(c, &sk.PublicKey, blind); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("Generic", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := blindPublicKeyGeneric(c, &sk.PublicKey, blind); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkUnblindPublicKey(b *testing.B) {
+	benchmarkNISTCurves(b, func(b *testing.B, c elliptic.Curve) {
+		sk, _ := GenerateKey(c, rand.Reader)
+		blind := randomBlind(b, c)
+
+		b.Run("ConstantTime", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := unblindPublicKey(c, &sk.PublicKey, blind); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("Generic", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := unblindPublicKeyGeneric(c, &sk.PublicKey, blind); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkBlindPrivateKey(b *testing.B) {
+	benchmarkNISTCurves(b, func(b *testing.B, c elliptic.Curve) {
+		sk, _ := GenerateKey(c, rand.Reader)
+		blind := randomBlind(b, c)
+
+		b.Run("ConstantTime", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := blindPrivateScalar(c, sk.D, blind); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("Generic", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				blindPrivateScalarGeneric(c, sk.D, blind)
+			}
+		})
+	})
+}
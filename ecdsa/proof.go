@@ -0,0 +1,223 @@
+package ecdsa
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/cloudflare/circl/expander"
+	"github.com/cloudflare/circl/group"
+	"golang.org/x/crypto/cryptobyte"
+
+	"github.com/cloudflare/pat-go/ecdsa/internal/bigmod"
+)
+
+// This file implements a non-interactive Schnorr proof of knowledge of the
+// discrete logarithm b of pkR to the base pkS, i.e. of some b with
+// pkR = [b]pkS. The prover picks a random k, computes T = [k]pkS, derives the
+// challenge
+//
+//	c = hash_to_scalar(pkS || pkR || T || len(context) || context)
+//
+// with the "ECDSA Discrete Log Proof" DST, and outputs (c, z = k - c*b mod N).
+// The verifier recomputes T = [z]pkS + [c]pkR and checks the challenge. The
+// proof reveals nothing about b.
+//
+// The proof does not show that b was derived from a blind key as
+// BlindPublicKeyWithContext does. Anyone who knows the private key skS can
+// prove any pkR = [r]G by using b = r * skS^-1, so the proof must not be used
+// in place of checking a revealed blind key. The type3 attester needs the blind
+// key in any case, to unblind the index key, and does not use this proof.
+
+const discreteLogProofDST = "ECDSA Discrete Log Proof"
+
+// DiscreteLogProof is a proof of knowledge of the discrete logarithm of one
+// public key to the base of another, produced by
+// BlindPublicKeyWithDiscreteLogProof.
+type DiscreteLogProof struct {
+	C, Z *big.Int
+}
+
+// Marshal encodes the proof as the fixed-width big-endian encodings of C and Z
+// for the curve c.
+func (p *DiscreteLogProof) Marshal(c elliptic.Curve) []byte {
+	scalarLen := (c.Params().N.BitLen() + 7) / 8
+	out := make([]byte, 2*scalarLen)
+	p.C.FillBytes(out[:scalarLen])
+	p.Z.FillBytes(out[scalarLen:])
+	return out
+}
+
+// UnmarshalDiscreteLogProof decodes a proof for the curve c, rejecting scalars
+// that are not reduced modulo the curve order.
+func UnmarshalDiscreteLogProof(c elliptic.Curve, data []byte) (*DiscreteLogProof, error) {
+	N := c.Params().N
+	scalarLen := (N.BitLen() + 7) / 8
+	if len(data) != 2*scalarLen {
+		return nil, fmt.Errorf("ecdsa: invalid discrete log proof length %d", len(data))
+	}
+	C := new(big.Int).SetBytes(data[:scalarLen])
+	Z := new(big.Int).SetBytes(data[scalarLen:])
+	if C.Cmp(N) >= 0 || Z.Cmp(N) >= 0 {
+		return nil, errors.New("ecdsa: non-canonical discrete log proof")
+	}
+	return &DiscreteLogProof{C: C, Z: Z}, nil
+}
+
+// discreteLogProofChallenge derives the proof challenge from the compressed
+// encodings of pkS, pkR and T, and the context string.
+func discreteLogProofChallenge(c elliptic.Curve, pkS, pkR, T, context []byte) (*big.Int, error) {
+	h, L, err := hashToScalarParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	b := cryptobyte.NewBuilder(nil)
+	b.AddBytes(pkS)
+	b.AddBytes(pkR)
+	b.AddBytes(T)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(context)
+	})
+	msg, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	xmd := expander.NewExpanderMD(h, []byte(discreteLogProofDST))
+	var u [1]big.Int
+	group.HashToField(u[:], msg, xmd, c.Params().N, L)
+	return new(big.Int).Set(&u[0]), nil
+}
+
+func (c *nistCurve[Point]) proveDiscreteLog(rand io.Reader, pkS *PublicKey, blind *big.Int, context []byte) (*PublicKey, *DiscreteLogProof, error) {
+	b, err := c.scalar(blind)
+	if err != nil {
+		return nil, nil, err
+	}
+	P, err := c.point(pkS)
+	if err != nil {
+		return nil, nil, err
+	}
+	R, err := c.newPoint().ScalarMult(P, b.Bytes(c.N))
+	if err != nil {
+		return nil, nil, err
+	}
+	pkR, err := c.publicKey(R)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kInt, err := randFieldElement(c.curve, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	k, err := c.scalar(kInt)
+	if err != nil {
+		return nil, nil, err
+	}
+	T, err := c.newPoint().ScalarMult(P, k.Bytes(c.N))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challenge, err := discreteLogProofChallenge(c.curve, P.BytesCompressed(), R.BytesCompressed(), T.BytesCompressed(), context)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := c.scalar(challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// z = k - c*b mod N
+	cb := bigmod.NewNat().ExpandFor(c.N).Add(ch, c.N)
+	cb.Mul(b, c.N)
+	z := k.Sub(cb, c.N)
+
+	return pkR, &DiscreteLogProof{
+		C: challenge,
+		Z: new(big.Int).SetBytes(z.Bytes(c.N)),
+	}, nil
+}
+
+func (c *nistCurve[Point]) verifyDiscreteLogProof(pkS, pkR *PublicKey, proof *DiscreteLogProof, context []byte) error {
+	N := c.curve.Params().N
+	if proof == nil || proof.C == nil || proof.Z == nil ||
+		proof.C.Sign() < 0 || proof.C.Cmp(N) >= 0 || proof.Z.Sign() < 0 || proof.Z.Cmp(N) >= 0 {
+		return errors.New("ecdsa: invalid discrete log proof")
+	}
+	P, err := c.point(pkS)
+	if err != nil {
+		return err
+	}
+	R, err := c.point(pkR)
+	if err != nil {
+		return err
+	}
+
+	scalarLen := c.N.Size()
+	zP, err := c.newPoint().ScalarMult(P, proof.Z.FillBytes(make([]byte, scalarLen)))
+	if err != nil {
+		return err
+	}
+	cR, err := c.newPoint().ScalarMult(R, proof.C.FillBytes(make([]byte, scalarLen)))
+	if err != nil {
+		return err
+	}
+	T := c.newPoint().Add(zP, cR)
+
+	challenge, err := discreteLogProofChallenge(c.curve, P.BytesCompressed(), R.BytesCompressed(), T.BytesCompressed(), context)
+	if err != nil {
+		return err
+	}
+	if challenge.Cmp(proof.C) != 0 {
+		return errors.New("ecdsa: discrete log proof verification failed")
+	}
+	return nil
+}
+
+// BlindPublicKeyWithDiscreteLogProof blinds pk using the blind key bk and
+// context string, as BlindPublicKeyWithContext does, and returns the blinded
+// public key together with a proof of knowledge of its discrete logarithm to
+// the base pk. The proof is bound to the context string and does not reveal
+// bk, but it does not show that the blinded key was derived from any blind
+// key.
+func BlindPublicKeyWithDiscreteLogProof(rand io.Reader, pk *PublicKey, bk *PrivateKey, context []byte) (*PublicKey, *DiscreteLogProof, error) {
+	blind, err := hashBlind(pk.Curve, bk, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch pk.Curve.Params().Name {
+	case "P-256":
+		return p256().proveDiscreteLog(rand, pk, blind, context)
+	case "P-384":
+		return p384().proveDiscreteLog(rand, pk, blind, context)
+	case "P-521":
+		return p521().proveDiscreteLog(rand, pk, blind, context)
+	default:
+		return nil, nil, fmt.Errorf("Unsupported curve")
+	}
+}
+
+// VerifyDiscreteLogProof checks a proof produced by
+// BlindPublicKeyWithDiscreteLogProof that the prover knows some b with
+// pkR = [b]pkS, for the same context string. It returns nil if the proof is
+// valid.
+func VerifyDiscreteLogProof(pkS, pkR *PublicKey, proof *DiscreteLogProof, context []byte) error {
+	if pkS.Curve != pkR.Curve {
+		return errors.New("ecdsa: public keys are on different curves")
+	}
+	switch pkS.Curve.Params().Name {
+	case "P-256":
+		return p256().verifyDiscreteLogProof(pkS, pkR, proof, context)
+	case "P-384":
+		return p384().verifyDiscreteLogProof(pkS, pkR, proof, context)
+	case "P-521":
+		return p521().verifyDiscreteLogProof(pkS, pkR, proof, context)
+	default:
+		return fmt.Errorf("Unsupported curve")
+	}
+}
@@ -0,0 +1,128 @@
+package ecdsa
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestDiscreteLogProof(t *testing.T) {
+	for _, test := range nistCurves {
+		c := test.curve
+		t.Run(test.name, func(t *testing.T) {
+			testDiscreteLogProof(t, c)
+		})
+	}
+}
+
+func testDiscreteLogProof(t *testing.T, c elliptic.Curve) {
+	skS, _ := GenerateKey(c, rand.Reader)
+	skB, _ := GenerateKey(c, rand.Reader)
+	other, _ := GenerateKey(c, rand.Reader)
+	context := []byte("ClientBlind")
+
+	pkR, proof, err := BlindPublicKeyWithDiscreteLogProof(rand.Reader, &skS.PublicKey, skB, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := BlindPublicKeyWithContext(c, &skS.PublicKey, skB, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkR.Equal(expected) {
+		t.Fatal("proved blinded public key does not match BlindPublicKeyWithContext")
+	}
+
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, pkR, proof, context); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+
+	decoded, err := UnmarshalDiscreteLogProof(c, proof.Marshal(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, pkR, decoded, context); err != nil {
+		t.Fatalf("decoded proof rejected: %v", err)
+	}
+
+	if err := VerifyDiscreteLogProof(&other.PublicKey, pkR, proof, context); err == nil {
+		t.Error("proof accepted for a different client key")
+	}
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, &other.PublicKey, proof, context); err == nil {
+		t.Error("proof accepted for a different blinded key")
+	}
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, pkR, proof, []byte("other")); err == nil {
+		t.Error("proof accepted for a different context")
+	}
+
+	tampered := &DiscreteLogProof{C: proof.C, Z: new(big.Int).Add(proof.Z, one)}
+	tampered.Z.Mod(tampered.Z, c.Params().N)
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, pkR, tampered, context); err == nil {
+		t.Error("tampered proof accepted")
+	}
+
+	// A proof for a blinding under a different blind key does not transfer.
+	pkR2, _, err := BlindPublicKeyWithDiscreteLogProof(rand.Reader, &skS.PublicKey, other, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, pkR2, proof, context); err == nil {
+		t.Error("proof accepted for a blinding under a different blind key")
+	}
+}
+
+func TestUnmarshalDiscreteLogProof(t *testing.T) {
+	c := elliptic.P384()
+	scalarLen := (c.Params().N.BitLen() + 7) / 8
+
+	if _, err := UnmarshalDiscreteLogProof(c, make([]byte, 2*scalarLen-1)); err == nil {
+		t.Error("short proof accepted")
+	}
+
+	nonCanonical := make([]byte, 2*scalarLen)
+	c.Params().N.FillBytes(nonCanonical[scalarLen:])
+	if _, err := UnmarshalDiscreteLogProof(c, nonCanonical); err == nil {
+		t.Error("non-canonical proof accepted")
+	}
+
+	if _, err := UnmarshalDiscreteLogProof(c, make([]byte, 2*scalarLen)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiscreteLogProofUnsupportedCurve(t *testing.T) {
+	c := elliptic.P224()
+	skS, _ := GenerateKey(c, rand.Reader)
+	skB, _ := GenerateKey(c, rand.Reader)
+	if _, _, err := BlindPublicKeyWithDiscreteLogProof(rand.Reader, &skS.PublicKey, skB, nil); err == nil {
+		t.Error("proof generated for an unsupported curve")
+	}
+}
+
+// TestDiscreteLogProofArbitraryKey checks that the proof only shows knowledge
+// of a discrete logarithm: the holder of skS can prove any key pkR = [r]G,
+// which is not a blinding of pkS by any blind key it commits to.
+func TestDiscreteLogProofArbitraryKey(t *testing.T) {
+	c := elliptic.P384()
+	N := c.Params().N
+	skS, _ := GenerateKey(c, rand.Reader)
+	target, _ := GenerateKey(c, rand.Reader)
+	context := []byte("ClientBlind")
+
+	// b = r * skS^-1 mod N, so [b]pkS = [r]G
+	b := new(big.Int).ModInverse(skS.D, N)
+	b.Mul(b, target.D)
+	b.Mod(b, N)
+
+	pkR, proof, err := p384().proveDiscreteLog(rand.Reader, &skS.PublicKey, b, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkR.Equal(&target.PublicKey) {
+		t.Fatal("proved key does not match the target key")
+	}
+	if err := VerifyDiscreteLogProof(&skS.PublicKey, &target.PublicKey, proof, context); err != nil {
+		t.Fatal(err)
+	}
+}
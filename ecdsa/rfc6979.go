@@ -0,0 +1,195 @@
+package ecdsa
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// This file implements deterministic nonce generation as described in
+// RFC 6979, Section 3.2, and the variant with additional data described in
+// Section 3.6, which is used to hedge signatures with fresh randomness.
+//
+// References:
+//
+//	[RFC6979]
+//	  https://www.rfc-editor.org/rfc/rfc6979
+
+// hedgedEntropySize is the number of bytes read from rand as additional data
+// for hedged signatures.
+const hedgedEntropySize = 32
+
+// nonceGenerator is the HMAC_DRBG of RFC 6979, Section 3.2, steps b to h.
+type nonceGenerator struct {
+	h    crypto.Hash
+	n    *big.Int
+	k, v []byte
+}
+
+// bits2int implements RFC 6979, Section 2.3.2. It matches hashToInt.
+func bits2int(b []byte, c elliptic.Curve) *big.Int {
+	return hashToInt(b, c)
+}
+
+// int2octets implements RFC 6979, Section 2.3.3.
+func int2octets(x *big.Int, c elliptic.Curve) []byte {
+	out := make([]byte, (c.Params().N.BitLen()+7)/8)
+	return x.FillBytes(out)
+}
+
+// bits2octets implements RFC 6979, Section 2.3.4.
+func bits2octets(b []byte, c elliptic.Curve) []byte {
+	z := bits2int(b, c)
+	z.Mod(z, c.Params().N)
+	return int2octets(z, c)
+}
+
+func newNonceGenerator(c elliptic.Curve, h crypto.Hash, priv *big.Int, hash, additionalData []byte) (*nonceGenerator, error) {
+	if !h.Available() {
+		return nil, errors.New("ecdsa: hash function not available")
+	}
+
+	g := &nonceGenerator{
+		h: h,
+		n: c.Params().N,
+		k: make([]byte, h.Size()),
+		v: make([]byte, h.Size()),
+	}
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+
+	x := int2octets(priv, c)
+	h1 := bits2octets(hash, c)
+
+	// K = HMAC_K(V || 0x00 || int2octets(x) || bits2octets(h1) || k')
+	g.k = g.mac(g.v, []byte{0x00}, x, h1, additionalData)
+	// V = HMAC_K(V)
+	g.v = g.mac(g.v)
+	// K = HMAC_K(V || 0x01 || int2octets(x) || bits2octets(h1) || k')
+	g.k = g.mac(g.v, []byte{0x01}, x, h1, additionalData)
+	// V = HMAC_K(V)
+	g.v = g.mac(g.v)
+
+	return g, nil
+}
+
+func (g *nonceGenerator) mac(data ...[]byte) []byte {
+	m := hmac.New(g.h.New, g.k)
+	for _, d := range data {
+		m.Write(d)
+	}
+	return m.Sum(nil)
+}
+
+// next returns the next candidate nonce in [1, N-1].
+func (g *nonceGenerator) next(c elliptic.Curve) *big.Int {
+	qlen := g.n.BitLen()
+	for {
+		var t []byte
+		for len(t)*8 < qlen {
+			g.v = g.mac(g.v)
+			t = append(t, g.v...)
+		}
+
+		k := bits2int(t, c)
+		if k.Sign() > 0 && k.Cmp(g.n) < 0 {
+			return k
+		}
+
+		// K = HMAC_K(V || 0x00), V = HMAC_K(V)
+		g.k = g.mac(g.v, []byte{0x00})
+		g.v = g.mac(g.v)
+	}
+}
+
+// signWithNonceGenerator computes a signature, drawing further nonces from g
+// until both r and s are nonzero, as required by RFC 6979, Section 3.4.
+func signWithNonceGenerator(priv *PrivateKey, g *nonceGenerator, hash []byte) (r, s *big.Int, err error) {
+	c := priv.Curve
+	N := c.Params().N
+	if N.Sign() == 0 {
+		return nil, nil, errZeroParam
+	}
+
+	e := hashToInt(hash, c)
+	for {
+		k := g.next(c)
+
+		var kInv *big.Int
+		if in, ok := c.(invertible); ok {
+			kInv = in.Inverse(k)
+		} else {
+			kInv = fermatInverse(k, N)
+		}
+
+		r, _ = c.ScalarBaseMult(int2octets(k, c))
+		r.Mod(r, N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		s = new(big.Int).Mul(priv.D, r)
+		s.Add(s, e)
+		s.Mul(s, kInv)
+		s.Mod(s, N)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+// SignDeterministic signs a hash (which should be the result of hashing a
+// larger message with h) using the private key, priv, deriving the nonce as
+// specified in RFC 6979. Signing the same hash with the same key always
+// produces the same signature.
+func SignDeterministic(priv *PrivateKey, h crypto.Hash, hash []byte) (r, s *big.Int, err error) {
+	g, err := newNonceGenerator(priv.Curve, h, priv.D, hash, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signWithNonceGenerator(priv, g, hash)
+}
+
+// SignHedged signs a hash (which should be the result of hashing a larger
+// message with h) using the private key, priv. The nonce is derived as
+// specified in RFC 6979 with fresh randomness from rand as additional data
+// (RFC 6979, Section 3.6), so that the signature remains secure if either the
+// randomness or the deterministic derivation is compromised.
+func SignHedged(rand io.Reader, priv *PrivateKey, h crypto.Hash, hash []byte) (r, s *big.Int, err error) {
+	entropy := make([]byte, hedgedEntropySize)
+	if _, err := io.ReadFull(rand, entropy); err != nil {
+		return nil, nil, err
+	}
+
+	g, err := newNonceGenerator(priv.Curve, h, priv.D, hash, entropy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signWithNonceGenerator(priv, g, hash)
+}
+
+// BlindKeySignDeterministicWithContext blinds the signing key by a blind, with
+// a context string, and then produces a deterministic RFC 6979 signature over
+// the input hashed with h.
+func BlindKeySignDeterministicWithContext(skS *PrivateKey, skB *PrivateKey, h crypto.Hash, hash []byte, context []byte) (r, s *big.Int, err error) {
+	skR, err := blindPrivateKey(skS, skB, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SignDeterministic(skR, h, hash)
+}
+
+// BlindKeySignHedgedWithContext blinds the signing key by a blind, with a
+// context string, and then produces a hedged RFC 6979 signature over the
+// input hashed with h, reading additional randomness from rand.
+func BlindKeySignHedgedWithContext(rand io.Reader, skS *PrivateKey, skB *PrivateKey, h crypto.Hash, hash []byte, context []byte) (r, s *big.Int, err error) {
+	skR, err := blindPrivateKey(skS, skB, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SignHedged(rand, skR, h, hash)
+}
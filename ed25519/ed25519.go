@@ -112,10 +112,7 @@ func GenerateKey(rand io.Reader) (PublicKey, PrivateKey, error) {
 
 // BlindPublicKeyWithContext augments the public key pair by the blind key and context string.
 func BlindPublicKeyWithContext(publicKey PublicKey, blind []byte, context []byte) (PublicKey, error) {
-	blindContext := append(blind, 0x00)
-	blindContext = append(blindContext, context...)
-	b := sha512.Sum512(blindContext)
-	r := edwards25519.NewScalar().SetBytes(b[:32])
+	r, _ := blindScalarWithContext(blind, context)
 
 	P, err := (&edwards25519.Point{}).SetBytes(publicKey)
 	if err != nil {
@@ -128,6 +125,18 @@ func BlindPublicKeyWithContext(publicKey PublicKey, blind []byte, context []byte
 	return blindedKey, nil
 }
 
+// blindScalarWithContext derives the scalar by which BlindPublicKeyWithContext
+// and BlindKeySignWithContext blind a key from the blind and context string,
+// along with the nonce prefix that BlindKeySignWithContext mixes in.
+func blindScalarWithContext(blind, context []byte) (*edwards25519.Scalar, []byte) {
+	blindContext := make([]byte, 0, len(blind)+1+len(context))
+	blindContext = append(blindContext, blind...)
+	blindContext = append(blindContext, 0x00)
+	blindContext = append(blindContext, context...)
+	b := sha512.Sum512(blindContext)
+	return edwards25519.NewScalar().SetBytes(b[:32]), b[32:]
+}
+
 // BlindPublicKey augments the public key pair by the blind key.
 func BlindPublicKey(publicKey PublicKey, blind []byte) (PublicKey, error) {
 	return BlindPublicKeyWithContext(publicKey, blind, nil)
@@ -135,11 +144,7 @@ func BlindPublicKey(publicKey PublicKey, blind []byte) (PublicKey, error) {
 
 // UnblindPublicKey unblinds the public key pair by the blind key and context string.
 func UnblindPublicKeyWithContext(publicKey PublicKey, blind []byte, context []byte) (PublicKey, error) {
-	blindContext := append(blind, 0x00)
-	blindContext = append(blindContext, context...)
-	b := sha512.Sum512(blindContext)
-
-	r := edwards25519.NewScalar().SetBytes(b[:32])
+	r, _ := blindScalarWithContext(blind, context)
 	rInv := edwards25519.NewScalar().Set(r).ModInverse()
 
 	P, err := (&edwards25519.Point{}).SetBytes(publicKey)
@@ -247,6 +252,34 @@ func BlindKeySign(privateKey PrivateKey, message, blind []byte) []byte {
 	return BlindKeySignWithContext(privateKey, message, blind, nil)
 }
 
+// VerifyBlindKeySignatureWithContext checks that blindedPublicKey is the blinding
+// of publicKey by the blind and context string, and that sig is a valid signature
+// of message under blindedPublicKey, as produced by BlindKeySignWithContext. It
+// returns nil only if both checks pass.
+func VerifyBlindKeySignatureWithContext(publicKey, blindedPublicKey PublicKey, message, sig, blind, context []byte) error {
+	if l := len(blindedPublicKey); l != PublicKeySize {
+		return errors.New("ed25519: bad blinded public key length: " + strconv.Itoa(l))
+	}
+	expected, err := BlindPublicKeyWithContext(publicKey, blind, context)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expected, blindedPublicKey) {
+		return errors.New("ed25519: blinded public key mismatch")
+	}
+	if !Verify(blindedPublicKey, message, sig) {
+		return errors.New("ed25519: invalid blinded key signature")
+	}
+	return nil
+}
+
+// VerifyBlindKeySignature checks that blindedPublicKey is the blinding of
+// publicKey by the blind and an empty context string, and that sig is a valid
+// signature of message under blindedPublicKey.
+func VerifyBlindKeySignature(publicKey, blindedPublicKey PublicKey, message, sig, blind []byte) error {
+	return VerifyBlindKeySignatureWithContext(publicKey, blindedPublicKey, message, sig, blind, nil)
+}
+
 func blindKeySign(signature, privateKey, blind, message, context []byte) {
 	if l := len(privateKey); l != PrivateKeySize {
 		panic("ed25519: bad private key length: " + strconv.Itoa(l))
@@ -255,12 +288,7 @@ func blindKeySign(signature, privateKey, blind, message, context []byte) {
 		panic("ed25519: bad blind length: " + strconv.Itoa(l))
 	}
 
-	blindContext := append(blind, 0x00)
-	blindContext = append(blindContext, context...)
-	b := sha512.Sum512(blindContext)
-
-	r := edwards25519.NewScalar().SetBytes(b[:32])
-	prefix2 := b[32:]
+	r, prefix2 := blindScalarWithContext(blind, context)
 
 	seed, publicKey := privateKey[:SeedSize], privateKey[SeedSize:]
 	h := sha512.Sum512(seed)
@@ -281,6 +309,13 @@ func blindKeySign(signature, privateKey, blind, message, context []byte) {
 
 // Verify reports whether sig is a valid signature of message by publicKey. It
 // will panic if len(publicKey) is not PublicKeySize.
+//
+// Verify checks the cofactored equation [8][S]B = [8]R + [8][k]A, so unlike
+// crypto/ed25519 it accepts signatures whose R or public key has a
+// small-order component. This keeps it in agreement with VerifyBatch, which
+// cannot check the cofactorless equation reliably. R and S must still be
+// canonically encoded. Signatures produced by Sign or BlindKeySign verify
+// under both equations.
 func Verify(publicKey PublicKey, message, sig []byte) bool {
 	if l := len(publicKey); l != PublicKeySize {
 		panic("ed25519: bad public key length: " + strconv.Itoa(l))
@@ -308,9 +343,118 @@ func Verify(publicKey PublicKey, message, sig []byte) bool {
 		return false
 	}
 
-	// [S]B = R + [k]A --> [k](-A) + [S]B = R
+	R, err := (&edwards25519.Point{}).SetBytes(sig[:32])
+	if err != nil || !bytes.Equal(sig[:32], R.Bytes()) {
+		return false
+	}
+
+	// [8]([k](-A) + [S]B - R) = 0
 	minusA := (&edwards25519.Point{}).Negate(A)
-	R := (&edwards25519.Point{}).VarTimeDoubleScalarBaseMult(k, minusA, S)
+	check := (&edwards25519.Point{}).VarTimeDoubleScalarBaseMult(k, minusA, S)
+	check.Subtract(check, R)
+	check.MultByCofactor(check)
+
+	return check.Equal(edwards25519.NewIdentityPoint()) == 1
+}
+
+// VerifyBatch reports whether every sigs[i] is a valid signature of
+// messages[i] by publicKeys[i], and which of the signatures are valid.
+//
+// The signatures are first checked together using a random linear combination
+// of the verification equations and a single multi-scalar multiplication. If
+// that check fails, each signature is verified individually with Verify to
+// identify the invalid ones.
+//
+// Signatures produced by BlindKeySign and BlindKeySignWithContext are regular
+// Ed25519 signatures under the blinded public key, and can be batch verified
+// against the output of BlindPublicKey and BlindPublicKeyWithContext.
+//
+// Like Verify, the batch equation is multiplied by the cofactor, so a
+// signature is reported valid regardless of which other signatures share its
+// batch. VerifyBatch will panic if the slices have different lengths or if any
+// public key is not PublicKeySize bytes long.
+func VerifyBatch(publicKeys []PublicKey, messages, sigs [][]byte) (bool, []bool) {
+	if len(publicKeys) != len(messages) || len(publicKeys) != len(sigs) {
+		panic("ed25519: mismatched batch verification input lengths")
+	}
+	for _, publicKey := range publicKeys {
+		if l := len(publicKey); l != PublicKeySize {
+			panic("ed25519: bad public key length: " + strconv.Itoa(l))
+		}
+	}
+
+	valid := make([]bool, len(sigs))
+	if len(sigs) == 0 {
+		return true, valid
+	}
 
-	return bytes.Equal(sig[:32], R.Bytes())
+	// Check sum_i [z_i]([8]R_i + [8 k_i]A_i - [8 S_i]B) == 0 for random 128-bit z_i.
+	scalars := make([]*edwards25519.Scalar, 0, 2*len(sigs)+1)
+	points := make([]*edwards25519.Point, 0, 2*len(sigs)+1)
+	bCoefficient := edwards25519.NewScalar()
+	batchValid := true
+	for i := range sigs {
+		sig := sigs[i]
+		if len(sig) != SignatureSize || sig[63]&224 != 0 {
+			batchValid = false
+			continue
+		}
+		A, err := (&edwards25519.Point{}).SetBytes(publicKeys[i])
+		if err != nil {
+			batchValid = false
+			continue
+		}
+		// Verify compares the encoding of R, so reject non-canonical encodings.
+		R, err := (&edwards25519.Point{}).SetBytes(sig[:32])
+		if err != nil || !bytes.Equal(sig[:32], R.Bytes()) {
+			batchValid = false
+			continue
+		}
+		S, err := edwards25519.NewScalar().SetCanonicalBytes(sig[32:])
+		if err != nil {
+			batchValid = false
+			continue
+		}
+
+		kh := sha512.New()
+		kh.Write(sig[:32])
+		kh.Write(publicKeys[i])
+		kh.Write(messages[i])
+		hramDigest := make([]byte, 0, sha512.Size)
+		hramDigest = kh.Sum(hramDigest)
+		k := edwards25519.NewScalar().SetUniformBytes(hramDigest)
+
+		var zBytes [32]byte
+		if _, err := io.ReadFull(cryptorand.Reader, zBytes[:16]); err != nil {
+			panic("ed25519: failed to read random batch coefficient: " + err.Error())
+		}
+		z, err := edwards25519.NewScalar().SetCanonicalBytes(zBytes[:])
+		if err != nil {
+			panic("ed25519: internal error: invalid batch coefficient")
+		}
+
+		scalars = append(scalars, z, edwards25519.NewScalar().Multiply(z, k))
+		points = append(points, R, A)
+		bCoefficient.Subtract(bCoefficient, edwards25519.NewScalar().Multiply(z, S))
+	}
+
+	if batchValid {
+		scalars = append(scalars, bCoefficient)
+		points = append(points, edwards25519.NewGeneratorPoint())
+		check := (&edwards25519.Point{}).VarTimeMultiScalarMult(scalars, points)
+		check.MultByCofactor(check)
+		if check.Equal(edwards25519.NewIdentityPoint()) == 1 {
+			for i := range valid {
+				valid[i] = true
+			}
+			return true, valid
+		}
+	}
+
+	allValid := true
+	for i := range sigs {
+		valid[i] = Verify(publicKeys[i], messages[i], sigs[i])
+		allValid = allValid && valid[i]
+	}
+	return allValid, valid
 }
@@ -9,11 +9,16 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto"
+	stded25519 "crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha512"
 	"encoding/hex"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
 )
 
 type zeroReader struct{}
@@ -64,6 +69,45 @@ func TestMaskSignVerify(t *testing.T) {
 	}
 }
 
+func TestVerifyBlindKeySignature(t *testing.T) {
+	public, private, _ := GenerateKey(rand.Reader)
+	otherPublic, _, _ := GenerateKey(rand.Reader)
+
+	blind := make([]byte, 32)
+	rand.Reader.Read(blind)
+	context := []byte("context")
+
+	blindedKey, err := BlindPublicKeyWithContext(public, blind, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("test message")
+	sig := BlindKeySignWithContext(private, message, blind, context)
+	if err := VerifyBlindKeySignatureWithContext(public, blindedKey, message, sig, blind, context); err != nil {
+		t.Errorf("valid blinded signature rejected: %v", err)
+	}
+
+	if err := VerifyBlindKeySignatureWithContext(otherPublic, blindedKey, message, sig, blind, context); err == nil {
+		t.Errorf("blinded signature accepted for a different client key")
+	}
+	if err := VerifyBlindKeySignatureWithContext(public, blindedKey, message, sig, blind, []byte("other")); err == nil {
+		t.Errorf("blinded signature accepted for a different context")
+	}
+	if err := VerifyBlindKeySignatureWithContext(public, blindedKey, []byte("wrong message"), sig, blind, context); err == nil {
+		t.Errorf("signature of different message accepted")
+	}
+	if err := VerifyBlindKeySignatureWithContext(public, blindedKey[:31], message, sig, blind, context); err == nil {
+		t.Errorf("truncated blinded key accepted")
+	}
+
+	sig = BlindKeySign(private, message, blind)
+	blindedKey, _ = BlindPublicKey(public, blind)
+	if err := VerifyBlindKeySignature(public, blindedKey, message, sig, blind); err != nil {
+		t.Errorf("valid blinded signature rejected: %v", err)
+	}
+}
+
 func TestBlindUnblindKey(t *testing.T) {
 	publicKey, _, _ := GenerateKey(rand.Reader)
 
@@ -323,3 +367,161 @@ func BenchmarkVerification(b *testing.B) {
 		Verify(pub, message, signature)
 	}
 }
+
+func batchTestSignatures(t testing.TB, n int, blind bool) ([]PublicKey, [][]byte, [][]byte) {
+	publicKeys := make([]PublicKey, n)
+	messages := make([][]byte, n)
+	sigs := make([][]byte, n)
+	context := []byte("batch context")
+	for i := 0; i < n; i++ {
+		pub, priv, err := GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages[i] = []byte("message " + strconv.Itoa(i))
+		if blind {
+			blindKey := make([]byte, SeedSize)
+			rand.Read(blindKey)
+			pub, err = BlindPublicKeyWithContext(pub, blindKey, context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigs[i] = BlindKeySignWithContext(priv, messages[i], blindKey, context)
+		} else {
+			sigs[i] = Sign(priv, messages[i])
+		}
+		publicKeys[i] = pub
+	}
+	return publicKeys, messages, sigs
+}
+
+func TestVerifyBatch(t *testing.T) {
+	for _, blind := range []bool{false, true} {
+		publicKeys, messages, sigs := batchTestSignatures(t, 16, blind)
+
+		ok, valid := VerifyBatch(publicKeys, messages, sigs)
+		if !ok {
+			t.Fatal("valid batch failed to verify")
+		}
+		for i := range valid {
+			if !valid[i] {
+				t.Fatalf("valid signature %d reported as invalid", i)
+			}
+		}
+
+		// Corrupt a message and a signature
+		messages[3] = []byte("wrong message")
+		sigs[11] = append([]byte{}, sigs[11]...)
+		sigs[11][40] ^= 0x01
+
+		ok, valid = VerifyBatch(publicKeys, messages, sigs)
+		if ok {
+			t.Fatal("invalid batch verified")
+		}
+		for i := range valid {
+			if valid[i] != (i != 3 && i != 11) {
+				t.Fatalf("signature %d reported as valid=%v", i, valid[i])
+			}
+		}
+
+		// Signature of the wrong length
+		sigs[11] = sigs[11][:SignatureSize-1]
+		if ok, valid = VerifyBatch(publicKeys, messages, sigs); ok || valid[11] {
+			t.Fatal("short signature verified")
+		}
+	}
+
+	if ok, valid := VerifyBatch(nil, nil, nil); !ok || len(valid) != 0 {
+		t.Fatal("empty batch failed to verify")
+	}
+}
+
+// torsionSignature signs message with a nonce point R that has a small-order
+// component, which only the cofactored verification equation accepts.
+func torsionSignature(t *testing.T, privateKey PrivateKey, message []byte) []byte {
+	// The point with y = 0 has order 4
+	T, err := (&edwards25519.Point{}).SetBytes(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rBytes [64]byte
+	if _, err := rand.Read(rBytes[:]); err != nil {
+		t.Fatal(err)
+	}
+	r := edwards25519.NewScalar().SetUniformBytes(rBytes[:])
+	R := (&edwards25519.Point{}).ScalarBaseMult(r)
+	R.Add(R, T)
+
+	h := sha512.Sum512(privateKey.Seed())
+	s := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+
+	signature := make([]byte, 0, SignatureSize)
+	signature = append(signature, R.Bytes()...)
+	kh := sha512.New()
+	kh.Write(signature)
+	kh.Write(privateKey[SeedSize:])
+	kh.Write(message)
+	k := edwards25519.NewScalar().SetUniformBytes(kh.Sum(nil))
+
+	S := edwards25519.NewScalar().MultiplyAdd(k, s, r)
+	return append(signature, S.Bytes()...)
+}
+
+func TestVerifyBatchTorsion(t *testing.T) {
+	publicKeys, messages, sigs := batchTestSignatures(t, 4, false)
+	_, privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeys[1] = privateKey.Public().(PublicKey)
+	sigs[1] = torsionSignature(t, privateKey, messages[1])
+
+	if stded25519.Verify(stded25519.PublicKey(publicKeys[1]), messages[1], sigs[1]) {
+		t.Fatal("cofactorless verification accepted a signature with a small-order R component")
+	}
+	if !Verify(publicKeys[1], messages[1], sigs[1]) {
+		t.Fatal("signature with a small-order R component rejected")
+	}
+	ok, valid := VerifyBatch(publicKeys, messages, sigs)
+	if !ok || !valid[1] {
+		t.Fatalf("VerifyBatch = %v %v, expected all signatures to be valid", ok, valid)
+	}
+
+	// Forcing the per-signature fallback must not change the result
+	messages[2] = []byte("wrong message")
+	ok, valid = VerifyBatch(publicKeys, messages, sigs)
+	if ok || !valid[1] || valid[2] {
+		t.Fatalf("VerifyBatch = %v %v, expected only signature 2 to be invalid", ok, valid)
+	}
+}
+
+func benchmarkVerifyBatch(b *testing.B, n int) {
+	publicKeys, messages, sigs := batchTestSignatures(b, n, true)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ok, _ := VerifyBatch(publicKeys, messages, sigs); !ok {
+			b.Fatal("batch failed to verify")
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	for _, n := range []int{1, 8, 64, 256} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			benchmarkVerifyBatch(b, n)
+		})
+	}
+}
+
+func BenchmarkVerifyIndividually(b *testing.B) {
+	publicKeys, messages, sigs := batchTestSignatures(b, 64, true)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range sigs {
+			if !Verify(publicKeys[j], messages[j], sigs[j]) {
+				b.Fatal("signature failed to verify")
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ed25519
+
+import (
+	"bytes"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/pat-go/util"
+)
+
+// This file implements encoding and decoding of the package's key types.
+// Public keys are encoded as PKIX SubjectPublicKeyInfo structures and private
+// keys as PKCS #8 structures holding the seed (RFC 8410), both also available
+// as PEM blocks, and keys can be encoded as "OKP" JSON Web Keys (RFC 8037).
+
+// MarshalPKIXPublicKey encodes pub as a DER PKIX SubjectPublicKeyInfo
+// structure.
+func MarshalPKIXPublicKey(pub PublicKey) ([]byte, error) {
+	if len(pub) != PublicKeySize {
+		return nil, fmt.Errorf("ed25519: bad public key length: %d", len(pub))
+	}
+	return x509.MarshalPKIXPublicKey(stded25519.PublicKey(pub))
+}
+
+// UnmarshalPKIXPublicKey decodes a public key from a DER PKIX
+// SubjectPublicKeyInfo structure.
+func UnmarshalPKIXPublicKey(der []byte) (PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(stded25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ed25519: unexpected public key type %T", key)
+	}
+	return PublicKey(pub), nil
+}
+
+// MarshalPKCS8PrivateKey encodes priv as a DER PKCS #8 PrivateKeyInfo
+// structure.
+func MarshalPKCS8PrivateKey(priv PrivateKey) ([]byte, error) {
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS8PrivateKey(stded25519.PrivateKey(priv))
+}
+
+// UnmarshalPKCS8PrivateKey decodes a private key from a DER PKCS #8
+// PrivateKeyInfo structure.
+func UnmarshalPKCS8PrivateKey(der []byte) (PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(stded25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("ed25519: unexpected private key type %T", key)
+	}
+	return PrivateKey(priv), nil
+}
+
+// checkPrivateKey returns an error if priv is not a seed followed by the
+// public key derived from it.
+func checkPrivateKey(priv PrivateKey) error {
+	if len(priv) != PrivateKeySize {
+		return fmt.Errorf("ed25519: bad private key length: %d", len(priv))
+	}
+	if !bytes.Equal(NewKeyFromSeed(priv.Seed())[SeedSize:], priv[SeedSize:]) {
+		return errors.New("ed25519: private key does not match public key")
+	}
+	return nil
+}
+
+// MarshalPublicKeyPEM encodes pub as a PEM "PUBLIC KEY" block holding its PKIX
+// encoding.
+func MarshalPublicKeyPEM(pub PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// UnmarshalPublicKeyPEM decodes a public key from a PEM "PUBLIC KEY" block.
+func UnmarshalPublicKeyPEM(data []byte) (PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("ed25519: invalid PEM public key encoding")
+	}
+	return UnmarshalPKIXPublicKey(block.Bytes)
+}
+
+// MarshalPrivateKeyPEM encodes priv as a PEM "PRIVATE KEY" block holding its
+// PKCS #8 encoding.
+func MarshalPrivateKeyPEM(priv PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// UnmarshalPrivateKeyPEM decodes a private key from a PEM "PRIVATE KEY" block.
+func UnmarshalPrivateKeyPEM(data []byte) (PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("ed25519: invalid PEM private key encoding")
+	}
+	return UnmarshalPKCS8PrivateKey(block.Bytes)
+}
+
+func parseJWK(data []byte) (*util.JWK, PublicKey, error) {
+	k, err := util.UnmarshalJWK(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ed25519: %v", err)
+	}
+	if k.KeyType != "OKP" || k.Curve != "Ed25519" {
+		return nil, nil, fmt.Errorf("ed25519: unexpected JWK key type %q with curve %q", k.KeyType, k.Curve)
+	}
+	x, err := util.DecodeJWKMember("x", k.X, PublicKeySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ed25519: %v", err)
+	}
+	return k, PublicKey(x), nil
+}
+
+// MarshalPublicKeyJWK encodes pub as an "OKP" JSON Web Key.
+func MarshalPublicKeyJWK(pub PublicKey) ([]byte, error) {
+	if len(pub) != PublicKeySize {
+		return nil, fmt.Errorf("ed25519: bad public key length: %d", len(pub))
+	}
+	return json.Marshal(util.JWK{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       util.EncodeJWKMember(pub),
+	})
+}
+
+// UnmarshalPublicKeyJWK decodes a public key from an "OKP" JSON Web Key. Any
+// private key is ignored.
+func UnmarshalPublicKeyJWK(data []byte) (PublicKey, error) {
+	_, pub, err := parseJWK(data)
+	return pub, err
+}
+
+// MarshalPrivateKeyJWK encodes priv as an "OKP" JSON Web Key, with its seed as
+// the private key.
+func MarshalPrivateKeyJWK(priv PrivateKey) ([]byte, error) {
+	if err := checkPrivateKey(priv); err != nil {
+		return nil, err
+	}
+	return json.Marshal(util.JWK{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       util.EncodeJWKMember(priv[SeedSize:]),
+		D:       util.EncodeJWKMember(priv.Seed()),
+	})
+}
+
+// UnmarshalPrivateKeyJWK decodes a private key from an "OKP" JSON Web Key,
+// which must hold a seed matching its public key.
+func UnmarshalPrivateKeyJWK(data []byte) (PrivateKey, error) {
+	k, pub, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	if k.D == "" {
+		return nil, errors.New("ed25519: JWK has no private key")
+	}
+	seed, err := util.DecodeJWKMember("d", k.D, SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("ed25519: %v", err)
+	}
+	priv := NewKeyFromSeed(seed)
+	if !bytes.Equal(priv[SeedSize:], pub) {
+		return nil, errors.New("ed25519: private key does not match public key")
+	}
+	return priv, nil
+}
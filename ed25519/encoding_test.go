@@ -0,0 +1,158 @@
+package ed25519
+
+import (
+	"bytes"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"testing"
+)
+
+func TestPKIXAndPKCS8Encoding(t *testing.T) {
+	pub, priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spki, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPub, err := UnmarshalPKIXPublicKey(spki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPub.Equal(pub) {
+		t.Error("PKIX round-trip mismatch")
+	}
+
+	pkcs8, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPriv, err := UnmarshalPKCS8PrivateKey(pkcs8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPriv.Equal(priv) {
+		t.Error("PKCS #8 round-trip mismatch")
+	}
+
+	// The encoding must interoperate with crypto/x509.
+	stdPriv, err := x509.ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stdPriv.(stded25519.PrivateKey), priv) {
+		t.Error("crypto/x509 decoded a different private key")
+	}
+
+	mismatched := append(append(PrivateKey{}, priv.Seed()...), make([]byte, PublicKeySize)...)
+	if _, err := MarshalPKCS8PrivateKey(mismatched); err == nil {
+		t.Error("MarshalPKCS8PrivateKey accepted a mismatched key pair")
+	}
+	if _, err := MarshalPKIXPublicKey(pub[1:]); err == nil {
+		t.Error("MarshalPKIXPublicKey accepted a short key")
+	}
+}
+
+func TestPEMEncoding(t *testing.T) {
+	pub, priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubPEM, err := MarshalPublicKeyPEM(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPub, err := UnmarshalPublicKeyPEM(pubPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPub.Equal(pub) {
+		t.Error("PEM public key round-trip mismatch")
+	}
+
+	privPEM, err := MarshalPrivateKeyPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPriv, err := UnmarshalPrivateKeyPEM(privPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPriv.Equal(priv) {
+		t.Error("PEM private key round-trip mismatch")
+	}
+
+	if _, err := UnmarshalPublicKeyPEM(privPEM); err == nil {
+		t.Error("UnmarshalPublicKeyPEM accepted a private key block")
+	}
+	if _, err := UnmarshalPrivateKeyPEM(pubPEM); err == nil {
+		t.Error("UnmarshalPrivateKeyPEM accepted a public key block")
+	}
+}
+
+func TestJWKEncoding(t *testing.T) {
+	pub, priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubJWK, err := MarshalPublicKeyJWK(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPub, err := UnmarshalPublicKeyJWK(pubJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPub.Equal(pub) {
+		t.Error("JWK public key round-trip mismatch")
+	}
+	if _, err := UnmarshalPrivateKeyJWK(pubJWK); err == nil {
+		t.Error("UnmarshalPrivateKeyJWK accepted a public key")
+	}
+
+	privJWK, err := MarshalPrivateKeyJWK(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPriv, err := UnmarshalPrivateKeyJWK(privJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPriv.Equal(priv) {
+		t.Error("JWK private key round-trip mismatch")
+	}
+
+	var members map[string]string
+	if err := json.Unmarshal(privJWK, &members); err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := GenerateKey(nil)
+	otherJWK, _ := MarshalPublicKeyJWK(otherPub)
+	var otherMembers map[string]string
+	if err := json.Unmarshal(otherJWK, &otherMembers); err != nil {
+		t.Fatal(err)
+	}
+	for name, tampered := range map[string]map[string]string{
+		"mismatched x": {"x": otherMembers["x"]},
+		"truncated d":  {"d": members["d"][4:]},
+		"wrong curve":  {"crv": "X25519"},
+		"wrong type":   {"kty": "EC"},
+	} {
+		m := make(map[string]string)
+		for k, v := range members {
+			m[k] = v
+		}
+		for k, v := range tampered {
+			m[k] = v
+		}
+		data, _ := json.Marshal(m)
+		if _, err := UnmarshalPrivateKeyJWK(data); err == nil {
+			t.Errorf("%s: UnmarshalPrivateKeyJWK succeeded", name)
+		}
+	}
+}
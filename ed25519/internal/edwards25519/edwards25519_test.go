@@ -303,3 +303,19 @@ func decodeHex(s string) []byte {
 	}
 	return b
 }
+
+func TestExtendedCoordinates(t *testing.T) {
+	X, Y, Z, T := B.ExtendedCoordinates()
+	p, err := new(Point).SetExtendedCoordinates(X, Y, Z, T)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Equal(B) != 1 {
+		t.Error("extended coordinates round trip mismatch")
+	}
+
+	T.Add(T, feOne)
+	if _, err := new(Point).SetExtendedCoordinates(X, Y, Z, T); err == nil {
+		t.Error("expected invalid coordinates to be rejected")
+	}
+}
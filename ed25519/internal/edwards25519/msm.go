@@ -0,0 +1,224 @@
+// Copyright (c) 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package edwards25519
+
+// pippengerThreshold is the number of points from which VarTimeMultiScalarMult
+// switches from Straus' method to Pippenger's bucket method.
+const pippengerThreshold = 190
+
+// MultiScalarMult sets v = sum(scalars[i] * points[i]), and returns v.
+//
+// Execution time depends only on the lengths of the two slices, which must match.
+func (v *Point) MultiScalarMult(scalars []*Scalar, points []*Point) *Point {
+	if len(scalars) != len(points) {
+		panic("edwards25519: called MultiScalarMult with different size inputs")
+	}
+	checkInitialized(points...)
+
+	// Proceed as in the single-base case, but share doublings
+	// between each point in the multiscalar equation. Pippenger's
+	// bucket method is not used here, as selecting a bucket by a
+	// secret digit is not constant-time.
+
+	// Build lookup tables for each point
+	tables := make([]projLookupTable, len(points))
+	for i := range tables {
+		tables[i].FromP3(points[i])
+	}
+	// Compute signed radix-16 digits for each scalar
+	digits := make([][64]int8, len(scalars))
+	for i := range digits {
+		digits[i] = scalars[i].signedRadix16()
+	}
+
+	// Unwrap first loop iteration to save computing 16*identity
+	multiple := &projCached{}
+	tmp1 := &projP1xP1{}
+	tmp2 := &projP2{}
+	// Lookup-and-add the appropriate multiple of each input point
+	v.Set(NewIdentityPoint())
+	for j := range tables {
+		tables[j].SelectInto(multiple, digits[j][63])
+		tmp1.Add(v, multiple) // tmp1 = v + x_(j,63)*Q in P1xP1 coords
+		v.fromP1xP1(tmp1)     // update v
+	}
+	tmp2.FromP3(v) // set up tmp2 = v in P2 coords for next iteration
+	for i := 62; i >= 0; i-- {
+		tmp1.Double(tmp2)    // tmp1 =  2*(prev) in P1xP1 coords
+		tmp2.FromP1xP1(tmp1) // tmp2 =  2*(prev) in P2 coords
+		tmp1.Double(tmp2)    // tmp1 =  4*(prev) in P1xP1 coords
+		tmp2.FromP1xP1(tmp1) // tmp2 =  4*(prev) in P2 coords
+		tmp1.Double(tmp2)    // tmp1 =  8*(prev) in P1xP1 coords
+		tmp2.FromP1xP1(tmp1) // tmp2 =  8*(prev) in P2 coords
+		tmp1.Double(tmp2)    // tmp1 = 16*(prev) in P1xP1 coords
+		v.fromP1xP1(tmp1)    //    v = 16*(prev) in P3 coords
+		// Add the next nibble of each scalar to v
+		for j := range tables {
+			tables[j].SelectInto(multiple, digits[j][i])
+			tmp1.Add(v, multiple)
+			v.fromP1xP1(tmp1)
+		}
+		tmp2.FromP3(v) // set up tmp2 = v in P2 coords for next iteration
+	}
+	return v
+}
+
+// VarTimeMultiScalarMult sets v = sum(scalars[i] * points[i]), and returns v.
+//
+// Straus' method is used for small inputs, and Pippenger's bucket method for
+// large ones.
+//
+// Execution time depends on the inputs.
+func (v *Point) VarTimeMultiScalarMult(scalars []*Scalar, points []*Point) *Point {
+	if len(scalars) != len(points) {
+		panic("edwards25519: called VarTimeMultiScalarMult with different size inputs")
+	}
+	checkInitialized(points...)
+
+	if len(points) < pippengerThreshold {
+		return v.varTimeStraus(scalars, points)
+	}
+	return v.varTimePippenger(scalars, points)
+}
+
+func (v *Point) varTimeStraus(scalars []*Scalar, points []*Point) *Point {
+	// Generalize double-base NAF computation to arbitrary sizes.
+	// Here all the points are dynamic, so we only use the smaller
+	// tables.
+
+	// Build lookup tables for each point
+	tables := make([]nafLookupTable5, len(points))
+	for i := range tables {
+		tables[i].FromP3(points[i])
+	}
+	// Compute a NAF for each scalar
+	nafs := make([][256]int8, len(scalars))
+	for i := range nafs {
+		nafs[i] = scalars[i].nonAdjacentForm(5)
+	}
+
+	multiple := &projCached{}
+	tmp1 := &projP1xP1{}
+	tmp2 := &projP2{}
+	tmp2.Zero()
+
+	// Move from high to low bits, doubling the accumulator
+	// at each iteration and checking whether there is a nonzero
+	// coefficient to look up a multiple of.
+	//
+	// Skip trying to find the first nonzero coefficent, because
+	// searching might be more work than a few extra doublings.
+	for i := 255; i >= 0; i-- {
+		tmp1.Double(tmp2)
+
+		for j := range nafs {
+			if nafs[j][i] > 0 {
+				v.fromP1xP1(tmp1)
+				tables[j].SelectInto(multiple, nafs[j][i])
+				tmp1.Add(v, multiple)
+			} else if nafs[j][i] < 0 {
+				v.fromP1xP1(tmp1)
+				tables[j].SelectInto(multiple, -nafs[j][i])
+				tmp1.Sub(v, multiple)
+			}
+		}
+
+		tmp2.FromP1xP1(tmp1)
+	}
+
+	v.fromP2(tmp2)
+	return v
+}
+
+// pippengerWindow returns the bucket window width, in bits, for n points.
+func pippengerWindow(n int) uint {
+	switch {
+	case n < 500:
+		return 6
+	case n < 800:
+		return 7
+	default:
+		return 8
+	}
+}
+
+// signedRadix2w returns the signed radix-2^w digits of s, least significant
+// first, each in [-2^(w-1), 2^(w-1)). w must be between 6 and 8.
+func (s *Scalar) signedRadix2w(w uint) []int {
+	if s.s[31] > 127 {
+		panic("scalar has high bit set illegally")
+	}
+	// The top window has enough spare bits that the final carry is always zero.
+	digits := make([]int, (256+w-1)/w)
+	carry := 0
+	for k := range digits {
+		offset := uint(k) * w
+		i := offset / 8
+		bits := uint(s.s[i])
+		if i+1 < uint(len(s.s)) {
+			bits |= uint(s.s[i+1]) << 8
+		}
+		d := int(bits>>(offset%8))&(1<<w-1) + carry
+		carry = (d + 1<<(w-1)) >> w
+		digits[k] = d - carry<<w
+	}
+	return digits
+}
+
+func (v *Point) varTimePippenger(scalars []*Scalar, points []*Point) *Point {
+	w := pippengerWindow(len(points))
+
+	cached := make([]projCached, len(points))
+	for i := range cached {
+		cached[i].FromP3(points[i])
+	}
+	digits := make([][]int, len(scalars))
+	for i := range digits {
+		digits[i] = scalars[i].signedRadix2w(w)
+	}
+
+	// Signed digits halve the number of buckets, as negative digits
+	// subtract the point from the bucket of the digit's magnitude.
+	buckets := make([]Point, 1<<(w-1))
+	tmp1 := &projP1xP1{}
+	tmp2 := &projP2{}
+	sum, total := &Point{}, &Point{}
+
+	v.Set(NewIdentityPoint())
+	windows := int((256 + w - 1) / w)
+	for k := windows - 1; k >= 0; k-- {
+		// v = 2^w * v
+		tmp2.FromP3(v)
+		for i := uint(0); i < w; i++ {
+			tmp1.Double(tmp2)
+			tmp2.FromP1xP1(tmp1)
+		}
+		v.fromP2(tmp2)
+
+		// Sort the points into buckets by the value of their window digit
+		for b := range buckets {
+			buckets[b].Set(NewIdentityPoint())
+		}
+		for j := range digits {
+			if d := digits[j][k]; d > 0 {
+				tmp1.Add(&buckets[d-1], &cached[j])
+				buckets[d-1].fromP1xP1(tmp1)
+			} else if d < 0 {
+				tmp1.Sub(&buckets[-d-1], &cached[j])
+				buckets[-d-1].fromP1xP1(tmp1)
+			}
+		}
+
+		// Compute sum_d d * buckets[d-1] with a running sum
+		sum.Set(NewIdentityPoint())
+		total.Set(NewIdentityPoint())
+		for b := len(buckets) - 1; b >= 0; b-- {
+			sum.Add(sum, &buckets[b])
+			total.Add(total, sum)
+		}
+		v.Add(v, total)
+	}
+	return v
+}
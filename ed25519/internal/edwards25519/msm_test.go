@@ -0,0 +1,197 @@
+// Copyright (c) 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package edwards25519
+
+import (
+	mathrand "math/rand"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+// randomMultiScalarInputs returns n random scalars and n random points.
+func randomMultiScalarInputs(rand *mathrand.Rand, n int) ([]*Scalar, []*Point) {
+	scalars := make([]*Scalar, n)
+	points := make([]*Point, n)
+	for i := 0; i < n; i++ {
+		var wide [64]byte
+		rand.Read(wide[:])
+		scalars[i] = NewScalar().SetUniformBytes(wide[:])
+		rand.Read(wide[:])
+		points[i] = new(Point).ScalarBaseMult(NewScalar().SetUniformBytes(wide[:]))
+	}
+	return scalars, points
+}
+
+// naiveMultiScalarMult computes sum(scalars[i] * points[i]) one term at a time.
+func naiveMultiScalarMult(scalars []*Scalar, points []*Point) *Point {
+	sum := NewIdentityPoint()
+	for i := range scalars {
+		sum.Add(sum, new(Point).ScalarMult(scalars[i], points[i]))
+	}
+	return sum
+}
+
+func TestMultiScalarMultMatchesBaseMult(t *testing.T) {
+	multiScalarMultMatchesBaseMult := func(x, y, z Scalar) bool {
+		var p, q1, q2, q3, check Point
+
+		p.MultiScalarMult([]*Scalar{&x, &y, &z}, []*Point{B, B, B})
+
+		q1.ScalarBaseMult(&x)
+		q2.ScalarBaseMult(&y)
+		q3.ScalarBaseMult(&z)
+		check.Add(&q1, &q2).Add(&check, &q3)
+
+		checkOnCurve(t, &p, &check, &q1, &q2, &q3)
+		return p.Equal(&check) == 1
+	}
+
+	if err := quick.Check(multiScalarMultMatchesBaseMult, quickCheckConfig32); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVarTimeMultiScalarMultMatchesBaseMult(t *testing.T) {
+	varTimeMultiScalarMultMatchesBaseMult := func(x, y, z Scalar) bool {
+		var p, q1, q2, q3, check Point
+
+		p.VarTimeMultiScalarMult([]*Scalar{&x, &y, &z}, []*Point{B, B, B})
+
+		q1.ScalarBaseMult(&x)
+		q2.ScalarBaseMult(&y)
+		q3.ScalarBaseMult(&z)
+		check.Add(&q1, &q2).Add(&check, &q3)
+
+		checkOnCurve(t, &p, &check, &q1, &q2, &q3)
+		return p.Equal(&check) == 1
+	}
+
+	if err := quick.Check(varTimeMultiScalarMultMatchesBaseMult, quickCheckConfig32); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMultiScalarMultMatchesNaive(t *testing.T) {
+	rand := mathrand.New(mathrand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 7, 64, pippengerThreshold, 600, 900} {
+		scalars, points := randomMultiScalarInputs(rand, n)
+		// Include edge-case scalars and points
+		if n > 2 {
+			scalars[0] = NewScalar()
+			scalars[1] = new(Scalar).Set(&scMinusOne)
+			points[2] = NewIdentityPoint()
+		}
+		expected := naiveMultiScalarMult(scalars, points)
+
+		var p Point
+		if p.MultiScalarMult(scalars, points).Equal(expected) != 1 {
+			t.Errorf("MultiScalarMult mismatch for %d points", n)
+		}
+		if p.VarTimeMultiScalarMult(scalars, points).Equal(expected) != 1 {
+			t.Errorf("VarTimeMultiScalarMult mismatch for %d points", n)
+		}
+		if p.varTimeStraus(scalars, points).Equal(expected) != 1 {
+			t.Errorf("Straus mismatch for %d points", n)
+		}
+		if p.varTimePippenger(scalars, points).Equal(expected) != 1 {
+			t.Errorf("Pippenger mismatch for %d points", n)
+		}
+		checkOnCurve(t, &p)
+	}
+}
+
+func TestMultiScalarMultMismatchedLengths(t *testing.T) {
+	for _, f := range []func(v *Point, s []*Scalar, p []*Point) *Point{
+		(*Point).MultiScalarMult,
+		(*Point).VarTimeMultiScalarMult,
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic for mismatched input lengths")
+				}
+			}()
+			f(new(Point), []*Scalar{NewScalar()}, nil)
+		}()
+	}
+}
+
+var multiScalarMultSizes = []int{1, 8, 64, 256, 1024}
+
+func BenchmarkMultiScalarMult(b *testing.B) {
+	rand := mathrand.New(mathrand.NewSource(1))
+	for _, n := range multiScalarMultSizes {
+		scalars, points := randomMultiScalarInputs(rand, n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var p Point
+			for i := 0; i < b.N; i++ {
+				p.MultiScalarMult(scalars, points)
+			}
+		})
+	}
+}
+
+func BenchmarkVarTimeMultiScalarMult(b *testing.B) {
+	rand := mathrand.New(mathrand.NewSource(1))
+	for _, n := range multiScalarMultSizes {
+		scalars, points := randomMultiScalarInputs(rand, n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var p Point
+			for i := 0; i < b.N; i++ {
+				p.VarTimeMultiScalarMult(scalars, points)
+			}
+		})
+	}
+}
+
+func BenchmarkVarTimePippenger(b *testing.B) {
+	rand := mathrand.New(mathrand.NewSource(1))
+	for _, n := range multiScalarMultSizes {
+		scalars, points := randomMultiScalarInputs(rand, n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var p Point
+			for i := 0; i < b.N; i++ {
+				p.varTimePippenger(scalars, points)
+			}
+		})
+	}
+}
+
+func TestSignedRadix2w(t *testing.T) {
+	signedRadix2wReconstructs := func(x Scalar) bool {
+		for _, w := range []uint{6, 7, 8} {
+			// Recompute x from its digits with Horner's method
+			digits := x.signedRadix2w(w)
+			var base, acc, digit Scalar
+			base.s[0] = 1
+			for i := uint(0); i < w; i++ {
+				base.Add(&base, &base)
+			}
+			for k := len(digits) - 1; k >= 0; k-- {
+				d := digits[k]
+				if d < -(1<<(w-1)) || d >= 1<<(w-1) {
+					return false
+				}
+				digit = Scalar{}
+				if d >= 0 {
+					digit.s[0] = byte(d)
+				} else {
+					digit.s[0] = byte(-d)
+					digit.Negate(&digit)
+				}
+				acc.MultiplyAdd(&acc, &base, &digit)
+			}
+			if acc != x {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(signedRadix2wReconstructs, quickCheckConfig32); err != nil {
+		t.Error(err)
+	}
+}
@@ -207,3 +207,16 @@ func BenchmarkVarTimeDoubleScalarBaseMult(t *testing.B) {
 		p.VarTimeDoubleScalarBaseMult(&dalekScalar, B, &dalekScalar)
 	}
 }
+
+func TestMultByCofactor(t *testing.T) {
+	var eight Scalar
+	eight.s[0] = 8
+
+	var p, check Point
+	p.MultByCofactor(B)
+	check.ScalarBaseMult(&eight)
+	if p.Equal(&check) != 1 {
+		t.Error("8*B != MultByCofactor(B)")
+	}
+	checkOnCurve(t, &p)
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ristretto255 implements the ristretto255 prime-order group as
+// specified in RFC 9496, on top of the edwards25519 implementation used by the
+// ed25519 package.
+package ristretto255
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519/field"
+)
+
+// Constants from RFC 9496, Section 4.1, encoded in little-endian.
+var (
+	sqrtM1 = fieldElementFromBytes([]byte{
+		0xb0, 0xa0, 0x0e, 0x4a, 0x27, 0x1b, 0xee, 0xc4,
+		0x78, 0xe4, 0x2f, 0xad, 0x06, 0x18, 0x43, 0x2f,
+		0xa7, 0xd7, 0xfb, 0x3d, 0x99, 0x00, 0x4d, 0x2b,
+		0x0b, 0xdf, 0xc1, 0x4f, 0x80, 0x24, 0x83, 0x2b})
+	sqrtADMinusOne = fieldElementFromBytes([]byte{
+		0x1b, 0x2e, 0x7b, 0x49, 0xa0, 0xf6, 0x97, 0x7e,
+		0xbd, 0x54, 0x78, 0x1b, 0x0c, 0x8e, 0x9d, 0xaf,
+		0xfd, 0xd1, 0xf5, 0x31, 0xc9, 0xfc, 0x3c, 0x0f,
+		0xac, 0x48, 0x83, 0x2b, 0xbf, 0x31, 0x69, 0x37})
+	invSqrtAMinusD = fieldElementFromBytes([]byte{
+		0xea, 0x40, 0x5d, 0x80, 0xaa, 0xfd, 0xc8, 0x99,
+		0xbe, 0x72, 0x41, 0x5a, 0x17, 0x16, 0x2f, 0x9d,
+		0x40, 0xd8, 0x01, 0xfe, 0x91, 0x7b, 0xc2, 0x16,
+		0xa2, 0xfc, 0xaf, 0xcf, 0x05, 0x89, 0x6c, 0x78})
+	oneMinusDSQ = fieldElementFromBytes([]byte{
+		0x76, 0xc1, 0x5f, 0x94, 0xc1, 0x09, 0x7c, 0xe2,
+		0x0f, 0x35, 0x5e, 0xcd, 0x38, 0xa1, 0x81, 0x2c,
+		0xe4, 0xdf, 0x70, 0xbe, 0xdd, 0xab, 0x94, 0x99,
+		0xd7, 0xe0, 0xb3, 0xb2, 0xa8, 0x72, 0x90, 0x02})
+	dMinusOneSQ = fieldElementFromBytes([]byte{
+		0x20, 0x4d, 0xed, 0x44, 0xaa, 0x5a, 0xad, 0x31,
+		0x99, 0x19, 0x1e, 0xb0, 0x2c, 0x4a, 0x9e, 0xd2,
+		0xeb, 0x4e, 0x9b, 0x52, 0x2f, 0xd3, 0xdc, 0x4c,
+		0x41, 0x22, 0x6c, 0xf6, 0x7a, 0xb3, 0x68, 0x59})
+	d = fieldElementFromBytes([]byte{
+		0xa3, 0x78, 0x59, 0x13, 0xca, 0x4d, 0xeb, 0x75,
+		0xab, 0xd8, 0x41, 0x41, 0x4d, 0x0a, 0x70, 0x00,
+		0x98, 0xe8, 0x79, 0x77, 0x79, 0x40, 0xc7, 0x8c,
+		0x73, 0xfe, 0x6f, 0x2b, 0xee, 0x6c, 0x03, 0x52})
+
+	one      = new(field.Element).One()
+	minusOne = new(field.Element).Negate(one)
+)
+
+func fieldElementFromBytes(x []byte) *field.Element {
+	return new(field.Element).SetBytes(x)
+}
+
+// Element is an element of the ristretto255 prime-order group.
+//
+// Arguments and receivers are allowed to alias. The zero value is NOT valid,
+// and it may be used only as a receiver.
+type Element struct {
+	r edwards25519.Point
+}
+
+// NewIdentityElement returns a new Element set to the identity value.
+func NewIdentityElement() *Element {
+	e := &Element{}
+	e.r.Set(edwards25519.NewIdentityPoint())
+	return e
+}
+
+// NewGeneratorElement returns a new Element set to the canonical generator.
+func NewGeneratorElement() *Element {
+	e := &Element{}
+	e.r.Set(edwards25519.NewGeneratorPoint())
+	return e
+}
+
+// Set sets e = x, and returns e.
+func (e *Element) Set(x *Element) *Element {
+	e.r.Set(&x.r)
+	return e
+}
+
+// Equal returns 1 if e is equivalent to ee, and 0 otherwise.
+//
+// Note that Elements must not be compared in any other way.
+func (e *Element) Equal(ee *Element) int {
+	X1, Y1, _, _ := e.r.ExtendedCoordinates()
+	X2, Y2, _, _ := ee.r.ExtendedCoordinates()
+
+	var f0, f1 field.Element
+
+	f0.Multiply(X1, Y2) // x1 * y2
+	f1.Multiply(Y1, X2) // y1 * x2
+	out := f0.Equal(&f1)
+
+	f0.Multiply(Y1, Y2) // y1 * y2
+	f1.Multiply(X1, X2) // x1 * x2
+	out = out | f0.Equal(&f1)
+
+	return out
+}
+
+// Add sets e = p + q, and returns e.
+func (e *Element) Add(p, q *Element) *Element {
+	e.r.Add(&p.r, &q.r)
+	return e
+}
+
+// Subtract sets e = p - q, and returns e.
+func (e *Element) Subtract(p, q *Element) *Element {
+	e.r.Subtract(&p.r, &q.r)
+	return e
+}
+
+// Negate sets e = -p, and returns e.
+func (e *Element) Negate(p *Element) *Element {
+	e.r.Negate(&p.r)
+	return e
+}
+
+// ScalarMult sets e = s * p, and returns e.
+func (e *Element) ScalarMult(s *edwards25519.Scalar, p *Element) *Element {
+	e.r.ScalarMult(s, &p.r)
+	return e
+}
+
+// ScalarBaseMult sets e = s * B, where B is the canonical generator, and
+// returns e.
+func (e *Element) ScalarBaseMult(s *edwards25519.Scalar) *Element {
+	e.r.ScalarBaseMult(s)
+	return e
+}
+
+// VarTimeDoubleScalarBaseMult sets e = a * A + b * B, where B is the canonical
+// generator, and returns e.
+//
+// Execution time depends on the inputs.
+func (e *Element) VarTimeDoubleScalarBaseMult(a *edwards25519.Scalar, A *Element, b *edwards25519.Scalar) *Element {
+	e.r.VarTimeDoubleScalarBaseMult(a, &A.r, b)
+	return e
+}
+
+// SetUniformBytes deterministically sets e to a uniformly distributed value
+// given 64 uniformly distributed random bytes, using the element derivation
+// function of RFC 9496, Section 4.3.4.
+//
+// This can be used for hash-to-group operations or to obtain a random element.
+func (e *Element) SetUniformBytes(b []byte) *Element {
+	if len(b) != 64 {
+		panic("ristretto255: SetUniformBytes input is not 64 bytes long")
+	}
+
+	f := &field.Element{}
+
+	f.SetBytes(b[:32])
+	point1 := &Element{}
+	mapToPoint(&point1.r, f)
+
+	f.SetBytes(b[32:])
+	point2 := &Element{}
+	mapToPoint(&point2.r, f)
+
+	return e.Add(point1, point2)
+}
+
+// mapToPoint implements MAP from RFC 9496, Section 4.3.4. The top bit of t
+// has already been masked by field.Element.SetBytes.
+func mapToPoint(out *edwards25519.Point, t *field.Element) {
+	// r = SQRT_M1 * t^2
+	r := &field.Element{}
+	r.Multiply(sqrtM1, r.Square(t))
+
+	// u = (r + 1) * ONE_MINUS_D_SQ
+	u := &field.Element{}
+	u.Multiply(u.Add(r, one), oneMinusDSQ)
+
+	// c = -1
+	c := &field.Element{}
+	c.Set(minusOne)
+
+	// v = (c - r*D) * (r + D)
+	rPlusD := &field.Element{}
+	rPlusD.Add(r, d)
+	v := &field.Element{}
+	v.Multiply(v.Subtract(c, v.Multiply(r, d)), rPlusD)
+
+	// (was_square, s) = SQRT_RATIO_M1(u, v)
+	s := &field.Element{}
+	_, wasSquare := s.SqrtRatio(u, v)
+
+	// s_prime = -CT_ABS(s*t)
+	sPrime := &field.Element{}
+	sPrime.Negate(sPrime.Absolute(sPrime.Multiply(s, t)))
+
+	// s = CT_SELECT(s IF was_square ELSE s_prime)
+	s.Select(s, sPrime, wasSquare)
+	// c = CT_SELECT(c IF was_square ELSE r)
+	c.Select(c, r, wasSquare)
+
+	// N = c * (r - 1) * D_MINUS_ONE_SQ - v
+	N := &field.Element{}
+	N.Multiply(c, N.Subtract(r, one))
+	N.Subtract(N.Multiply(N, dMinusOneSQ), v)
+
+	s2 := &field.Element{}
+	s2.Square(s)
+
+	// w0 = 2 * s * v
+	w0 := &field.Element{}
+	w0.Add(w0, w0.Multiply(s, v))
+	// w1 = N * SQRT_AD_MINUS_ONE
+	w1 := &field.Element{}
+	w1.Multiply(N, sqrtADMinusOne)
+	// w2 = 1 - s^2
+	w2 := &field.Element{}
+	w2.Subtract(one, s2)
+	// w3 = 1 + s^2
+	w3 := &field.Element{}
+	w3.Add(one, s2)
+
+	// return (w0*w3, w2*w1, w1*w3, w0*w2)
+	var X, Y, Z, T field.Element
+	X.Multiply(w0, w3)
+	Y.Multiply(w2, w1)
+	Z.Multiply(w1, w3)
+	T.Multiply(w0, w2)
+	if _, err := out.SetExtendedCoordinates(&X, &Y, &Z, &T); err != nil {
+		panic("ristretto255: internal error: MAP generated invalid coordinates")
+	}
+}
+
+// Bytes returns the 32 bytes canonical encoding of e, as specified in
+// RFC 9496, Section 4.3.2.
+func (e *Element) Bytes() []byte {
+	// Bytes is outlined to let the allocation happen on the stack of the caller.
+	b := make([]byte, 0, 32)
+	return e.bytes(b)
+}
+
+func (e *Element) bytes(b []byte) []byte {
+	X, Y, Z, T := e.r.ExtendedCoordinates()
+	tmp := &field.Element{}
+
+	// u1 = (z0 + y0) * (z0 - y0)
+	u1 := &field.Element{}
+	u1.Add(Z, Y).Multiply(u1, tmp.Subtract(Z, Y))
+
+	// u2 = x0 * y0
+	u2 := &field.Element{}
+	u2.Multiply(X, Y)
+
+	// Ignore was_square since this is always square
+	// (_, invsqrt) = SQRT_RATIO_M1(1, u1 * u2^2)
+	invSqrt := &field.Element{}
+	invSqrt.SqrtRatio(one, tmp.Multiply(u1, tmp.Square(u2)))
+
+	// den1 = invsqrt * u1
+	// den2 = invsqrt * u2
+	den1, den2 := &field.Element{}, &field.Element{}
+	den1.Multiply(invSqrt, u1)
+	den2.Multiply(invSqrt, u2)
+	// z_inv = den1 * den2 * t0
+	zInv := &field.Element{}
+	zInv.Multiply(den1, den2).Multiply(zInv, T)
+
+	// ix0 = x0 * SQRT_M1
+	// iy0 = y0 * SQRT_M1
+	ix0, iy0 := &field.Element{}, &field.Element{}
+	ix0.Multiply(X, sqrtM1)
+	iy0.Multiply(Y, sqrtM1)
+	// enchanted_denominator = den1 * INVSQRT_A_MINUS_D
+	enchantedDenominator := &field.Element{}
+	enchantedDenominator.Multiply(den1, invSqrtAMinusD)
+
+	// rotate = IS_NEGATIVE(t0 * z_inv)
+	rotate := tmp.Multiply(T, zInv).IsNegative()
+
+	// x = CT_SELECT(iy0 IF rotate ELSE x0)
+	// y = CT_SELECT(ix0 IF rotate ELSE y0)
+	x, y := &field.Element{}, &field.Element{}
+	x.Select(iy0, X, rotate)
+	y.Select(ix0, Y, rotate)
+	// z = z0
+	z := Z
+	// den_inv = CT_SELECT(enchanted_denominator IF rotate ELSE den2)
+	denInv := &field.Element{}
+	denInv.Select(enchantedDenominator, den2, rotate)
+
+	// y = CT_NEG(y, IS_NEGATIVE(x * z_inv))
+	isNegative := tmp.Multiply(x, zInv).IsNegative()
+	y.Select(tmp.Negate(y), y, isNegative)
+
+	// s = CT_ABS(den_inv * (z - y))
+	s := tmp.Subtract(z, y)
+	s.Multiply(s, denInv).Absolute(s)
+
+	// Return the canonical little-endian encoding of s.
+	return append(b, s.Bytes()...)
+}
+
+var errInvalidEncoding = errors.New("ristretto255: invalid element encoding")
+
+// SetCanonicalBytes sets e to the decoded value of in. If in is not a canonical
+// encoding of a valid ristretto255 element, as specified in RFC 9496,
+// Section 4.3.1, SetCanonicalBytes returns nil and an error and the receiver is
+// unchanged.
+func (e *Element) SetCanonicalBytes(in []byte) (*Element, error) {
+	if len(in) != 32 {
+		return nil, errInvalidEncoding
+	}
+
+	// First, interpret the string as an integer s in little-endian representation.
+	s := &field.Element{}
+	s.SetBytes(in)
+
+	// If the resulting value is >= p, decoding fails.
+	if subtle.ConstantTimeCompare(s.Bytes(), in) != 1 {
+		return nil, errInvalidEncoding
+	}
+
+	// If IS_NEGATIVE(s) returns TRUE, decoding fails.
+	if s.IsNegative() == 1 {
+		return nil, errInvalidEncoding
+	}
+
+	// ss = s^2
+	sSqr := &field.Element{}
+	sSqr.Square(s)
+
+	// u1 = 1 - ss
+	u1 := &field.Element{}
+	u1.Subtract(one, sSqr)
+
+	// u2 = 1 + ss
+	u2 := &field.Element{}
+	u2.Add(one, sSqr)
+
+	// u2_sqr = u2^2
+	u2Sqr := &field.Element{}
+	u2Sqr.Square(u2)
+
+	// v = -(D * u1^2) - u2_sqr
+	v := &field.Element{}
+	v.Square(u1).Multiply(v, d).Negate(v).Subtract(v, u2Sqr)
+
+	// (was_square, invsqrt) = SQRT_RATIO_M1(1, v * u2_sqr)
+	invSqrt, tmp := &field.Element{}, &field.Element{}
+	_, wasSquare := invSqrt.SqrtRatio(one, tmp.Multiply(v, u2Sqr))
+
+	// den_x = invsqrt * u2
+	// den_y = invsqrt * den_x * v
+	denX, denY := &field.Element{}, &field.Element{}
+	denX.Multiply(invSqrt, u2)
+	denY.Multiply(invSqrt, denX).Multiply(denY, v)
+
+	// x = CT_ABS(2 * s * den_x)
+	// y = u1 * den_y
+	// t = x * y
+	var X, Y, Z, T field.Element
+	X.Multiply(s, denX).Add(&X, &X).Absolute(&X)
+	Y.Multiply(u1, denY)
+	Z.One()
+	T.Multiply(&X, &Y)
+
+	// If was_square is FALSE, or IS_NEGATIVE(t) returns TRUE, or y = 0, decoding fails.
+	if wasSquare == 0 || T.IsNegative() == 1 || Y.Equal(new(field.Element)) == 1 {
+		return nil, errInvalidEncoding
+	}
+
+	// Otherwise, return the internal representation in extended coordinates (x, y, 1, t).
+	if _, err := e.r.SetExtendedCoordinates(&X, &Y, &Z, &T); err != nil {
+		return nil, errInvalidEncoding
+	}
+	return e, nil
+}
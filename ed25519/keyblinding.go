@@ -0,0 +1,199 @@
+package ed25519
+
+// This file implements key blinding for Ed25519 modelled on the Ed25519
+// instantiation in draft-irtf-cfrg-signature-key-blinding. It is not pinned to
+// a draft revision and has not been checked against the draft's published
+// test vectors, so it should not be assumed to interoperate with other
+// implementations. The derivation is fixed by this package instead: the blind
+// scalar is hash_to_scalar(SHA-512(skB)[:32] || I2OSP(len(ctx), 2) || ctx)
+// with expand_message_xmd(SHA-512) and the DST "Ed25519 Key Blind", and
+// ed25519-key-blinding-test-vectors.json records its outputs. The functions
+// are named after expand_message_xmd rather than the draft for that reason.
+//
+// It is exposed separately from BlindPublicKeyWithContext and
+// BlindKeySignWithContext, which derive the blind differently, so that
+// existing keys and test vectors keep working.
+
+import (
+	"bytes"
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/cloudflare/circl/expander"
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
+)
+
+const (
+	// BlindKeySize is the size, in bytes, of blind keys used by BlindPublicKeyXMD,
+	// UnblindPublicKeyXMD and BlindKeySignXMD.
+	BlindKeySize = 32
+
+	// maxBlindContextLength is the maximum length of a key blinding context.
+	maxBlindContextLength = 0xFFFF
+)
+
+var (
+	blindScalarDST = []byte("Ed25519 Key Blind")
+	blindPrefixDST = []byte("Ed25519 Key Blind Prefix")
+)
+
+// GenerateBlindKey generates a blind key using entropy from rand. If rand is
+// nil, crypto/rand.Reader will be used.
+func GenerateBlindKey(rand io.Reader) ([]byte, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	blindKey := make([]byte, BlindKeySize)
+	if _, err := io.ReadFull(rand, blindKey); err != nil {
+		return nil, err
+	}
+	return blindKey, nil
+}
+
+// blindInput encodes the blind key and context as hashed_skB || I2OSP(len(ctx), 2) || ctx,
+// where hashed_skB is the given half of the expanded blind key.
+func blindInput(hashedBlindKey, context []byte) []byte {
+	input := make([]byte, 0, len(hashedBlindKey)+2+len(context))
+	input = append(input, hashedBlindKey...)
+	input = append(input, byte(len(context)>>8), byte(len(context)))
+	return append(input, context...)
+}
+
+// hashToScalar maps msg to a scalar using expand_message_xmd with SHA-512
+// and the given DST, reducing 64 uniform bytes modulo the group order.
+func hashToScalar(msg, dst []byte) *edwards25519.Scalar {
+	xmd := expander.NewExpanderMD(crypto.SHA512, dst)
+	return edwards25519.NewScalar().SetUniformBytes(xmd.Expand(msg, 64))
+}
+
+// deriveBlind expands the blind key into the blind scalar and the signing
+// nonce prefix contributed by the blind key.
+func deriveBlind(blindKey, context []byte) (*edwards25519.Scalar, []byte, error) {
+	return deriveBlindWithDST(blindKey, context, blindScalarDST)
+}
+
+// deriveBlindWithDST is like deriveBlind, but hashes to the blind scalar with
+// the given DST.
+func deriveBlindWithDST(blindKey, context, dst []byte) (*edwards25519.Scalar, []byte, error) {
+	if l := len(blindKey); l != BlindKeySize {
+		return nil, nil, errors.New("ed25519: bad blind key length: " + strconv.Itoa(l))
+	}
+	if len(context) > maxBlindContextLength {
+		return nil, nil, errors.New("ed25519: blind context too long")
+	}
+
+	h := sha512.Sum512(blindKey)
+	blind := hashToScalar(blindInput(h[:32], context), dst)
+	if blind.Equal(edwards25519.NewScalar()) == 1 {
+		return nil, nil, errors.New("ed25519: invalid blind key")
+	}
+	return blind, h[32:], nil
+}
+
+// BlindPublicKeyXMD blinds publicKey with the blind key and context string.
+// The blind scalar is derived from the expanded blind key and the context with
+// a DST-based hash_to_scalar over expand_message_xmd(SHA-512). It is not
+// claimed to match any revision of draft-irtf-cfrg-signature-key-blinding.
+func BlindPublicKeyXMD(publicKey PublicKey, blindKey, context []byte) (PublicKey, error) {
+	blind, _, err := deriveBlind(blindKey, context)
+	if err != nil {
+		return nil, err
+	}
+
+	P, err := (&edwards25519.Point{}).SetBytes(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return P.ScalarMult(blind, P).Bytes(), nil
+}
+
+// UnblindPublicKeyXMD reverses BlindPublicKeyXMD for the same blind key and
+// context string.
+func UnblindPublicKeyXMD(publicKey PublicKey, blindKey, context []byte) (PublicKey, error) {
+	blind, _, err := deriveBlind(blindKey, context)
+	if err != nil {
+		return nil, err
+	}
+	blindInv := edwards25519.NewScalar().Set(blind).ModInverse()
+
+	P, err := (&edwards25519.Point{}).SetBytes(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return P.ScalarMult(blindInv, P).Bytes(), nil
+}
+
+// PublicBlindKeyXMD returns the public key pkB = [blind]B corresponding to
+// the blind scalar derived from the blind key and context string.
+func PublicBlindKeyXMD(blindKey, context []byte) (PublicKey, error) {
+	blind, _, err := deriveBlind(blindKey, context)
+	if err != nil {
+		return nil, err
+	}
+	return (&edwards25519.Point{}).ScalarBaseMult(blind).Bytes(), nil
+}
+
+// BlindKeySignXMD signs the message with privateKey blinded by the blind key
+// and context string, and returns a signature that verifies with Verify under
+// the output of BlindPublicKeyXMD. The signing nonce prefix is derived from
+// the prefixes of both expanded keys with a DST-based hash. It will panic if
+// len(privateKey) is not PrivateKeySize.
+func BlindKeySignXMD(privateKey PrivateKey, message, blindKey, context []byte) ([]byte, error) {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+
+	blind, blindPrefix, err := deriveBlind(blindKey, context)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, publicKey := privateKey[:SeedSize], privateKey[SeedSize:]
+	h := sha512.Sum512(seed)
+	s := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	s.Multiply(s, blind)
+
+	prefixInput := make([]byte, 0, 64)
+	prefixInput = append(prefixInput, h[32:]...)
+	prefixInput = append(prefixInput, blindPrefix...)
+	xmd := expander.NewExpanderMD(crypto.SHA512, blindPrefixDST)
+	prefix := xmd.Expand(blindInput(prefixInput, context), 32)
+
+	A, err := (&edwards25519.Point{}).SetBytes(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	A.ScalarMult(blind, A)
+
+	signature := make([]byte, SignatureSize)
+	signInternal(signature, A.Bytes(), message, prefix, s)
+	return signature, nil
+}
+
+// VerifyBlindKeySignatureXMD checks that blindedPublicKey is the output of
+// BlindPublicKeyXMD for publicKey, the blind key and context string, and that
+// sig is a valid signature of message under blindedPublicKey, as produced by
+// BlindKeySignXMD. It returns nil only if both checks pass.
+func VerifyBlindKeySignatureXMD(publicKey, blindedPublicKey PublicKey, message, sig, blindKey, context []byte) error {
+	if l := len(blindedPublicKey); l != PublicKeySize {
+		return errors.New("ed25519: bad blinded public key length: " + strconv.Itoa(l))
+	}
+	expected, err := BlindPublicKeyXMD(publicKey, blindKey, context)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expected, blindedPublicKey) {
+		return errors.New("ed25519: blinded public key mismatch")
+	}
+	if !Verify(blindedPublicKey, message, sig) {
+		return errors.New("ed25519: invalid blinded key signature")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package ed25519
+
+// This file implements a prime-order group variant of the key blinding in
+// keyblinding.go. Public keys are ristretto255 elements (RFC 9496) rather than
+// Ed25519 points, so a blinded key carries no small-order component and every
+// encoding names exactly one group element. The blind scalar is derived as for
+// BlindPublicKeyXMD, with the DST "ristretto255 Key Blind".
+
+import (
+	"crypto/sha512"
+	"errors"
+	"strconv"
+
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
+	"github.com/cloudflare/pat-go/ed25519/internal/ristretto255"
+)
+
+// Ristretto255PublicKeySize is the size, in bytes, of the ristretto255 public
+// keys used by BlindPublicKeyRistretto255 and UnblindPublicKeyRistretto255.
+const Ristretto255PublicKeySize = 32
+
+var blindRistretto255DST = []byte("ristretto255 Key Blind")
+
+// Ristretto255PublicKey returns the ristretto255 encoding of the public key
+// of privateKey, i.e. of [s]B for the secret scalar s that Sign uses. It will
+// panic if len(privateKey) is not PrivateKeySize.
+func Ristretto255PublicKey(privateKey PrivateKey) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+	h := sha512.Sum512(privateKey[:SeedSize])
+	s := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	return ristretto255.NewIdentityElement().ScalarBaseMult(s).Bytes()
+}
+
+func decodeRistretto255PublicKey(publicKey []byte) (*ristretto255.Element, error) {
+	if l := len(publicKey); l != Ristretto255PublicKeySize {
+		return nil, errors.New("ed25519: bad ristretto255 public key length: " + strconv.Itoa(l))
+	}
+	return ristretto255.NewIdentityElement().SetCanonicalBytes(publicKey)
+}
+
+// BlindPublicKeyRistretto255 blinds the ristretto255 public key publicKey with
+// the blind key and context string. Non-canonical encodings are rejected.
+func BlindPublicKeyRistretto255(publicKey, blindKey, context []byte) ([]byte, error) {
+	blind, _, err := deriveBlindWithDST(blindKey, context, blindRistretto255DST)
+	if err != nil {
+		return nil, err
+	}
+
+	P, err := decodeRistretto255PublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return P.ScalarMult(blind, P).Bytes(), nil
+}
+
+// UnblindPublicKeyRistretto255 reverses BlindPublicKeyRistretto255 for the
+// same blind key and context string.
+func UnblindPublicKeyRistretto255(publicKey, blindKey, context []byte) ([]byte, error) {
+	blind, _, err := deriveBlindWithDST(blindKey, context, blindRistretto255DST)
+	if err != nil {
+		return nil, err
+	}
+	blindInv := edwards25519.NewScalar().Set(blind).ModInverse()
+
+	P, err := decodeRistretto255PublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return P.ScalarMult(blindInv, P).Bytes(), nil
+}
@@ -0,0 +1,398 @@
+package ed25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
+	"github.com/cloudflare/pat-go/ed25519/internal/ristretto255"
+)
+
+const (
+	outputKeyBlindingTestVectorEnvironmentKey = "ED25519_KEY_BLINDING_TEST_VECTORS_OUT"
+	inputKeyBlindingTestVectorEnvironmentKey  = "ED25519_KEY_BLINDING_TEST_VECTORS_IN"
+)
+
+// TestBlindKeyXMDKnownAnswer checks the second entry of
+// ed25519-key-blinding-test-vectors.json, which uses a non-empty context.
+func TestBlindKeyXMDKnownAnswer(t *testing.T) {
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	priv := NewKeyFromSeed(decode("fed13b4c8400ec94fc76ecab55b2b6cc6a7fd6bec403e9af2f195cadc66f812f"))
+	pub := priv.Public().(PublicKey)
+	blindKey := decode("9bbaf634782b5b8f88941055045a63f7610a5bc8bdab15818659b959cf2cfd62")
+	context := decode("3598b5f629e25bee5eaf72610deb402a9efc66c08967a35a7b04c2f2867880ff")
+	message := decode("68656c6c6f20776f726c64")
+
+	if !bytes.Equal(pub, decode("d1814877ed7a78245b6c5879f80c79a84198c1c74c4f6a390ffddc326c194be3")) {
+		t.Fatalf("pkS mismatch: %x", pub)
+	}
+	publicBlind, err := PublicBlindKeyXMD(blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicBlind, decode("f306af9fb23236753e20ae53806cdf71a42e3f2ea74ebc0ca1d3737dfff7556a")) {
+		t.Fatalf("pkB mismatch: %x", publicBlind)
+	}
+	blindedPublicKey, err := BlindPublicKeyXMD(pub, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blindedPublicKey, decode("c45ebd002b7d18c35cb08eb6a908db168a065176205c74ea0f839728e98b1332")) {
+		t.Fatalf("pkR mismatch: %x", blindedPublicKey)
+	}
+	signature, err := BlindKeySignXMD(priv, message, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signature, decode("e5f3f44a3cea4d6b40eca33348e4d7065abecbd23fa79b8b1dbe0fdca40c4b2e921c260dd766219e9a46413e47d9c0b3bf0ce7931a8796e3860d4bc3ab5f200b")) {
+		t.Fatalf("signature mismatch: %x", signature)
+	}
+}
+
+func TestBlindKeyRistretto255(t *testing.T) {
+	_, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := GenerateBlindKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := []byte("context")
+
+	pub := Ristretto255PublicKey(priv)
+	blindedPublicKey, err := BlindPublicKeyRistretto255(pub, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pkR = [s * blind]B for the secret scalar s of priv
+	h := sha512.Sum512(priv.Seed())
+	s := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	blind, _, err := deriveBlindWithDST(blindKey, context, blindRistretto255DST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ristretto255.NewIdentityElement().ScalarBaseMult(s.Multiply(s, blind)).Bytes()
+	if !bytes.Equal(blindedPublicKey, expected) {
+		t.Fatalf("blinded public key mismatch: %x, expected %x", blindedPublicKey, expected)
+	}
+
+	unblindedPublicKey, err := UnblindPublicKeyRistretto255(blindedPublicKey, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unblindedPublicKey, pub) {
+		t.Fatal("unblinded public key mismatch")
+	}
+
+	otherBlindedPublicKey, err := BlindPublicKeyRistretto255(pub, blindKey, []byte("other context"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(otherBlindedPublicKey, blindedPublicKey) {
+		t.Fatal("blinded public key does not depend on the context")
+	}
+
+	// The ristretto255 blind is domain separated from the Ed25519 one
+	xmdBlind, _, err := deriveBlind(blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xmdBlind.Equal(blind) == 1 {
+		t.Fatal("ristretto255 and Ed25519 blinds are equal")
+	}
+
+	if _, err := BlindPublicKeyRistretto255(bytes.Repeat([]byte{0xFF}, Ristretto255PublicKeySize), blindKey, context); err == nil {
+		t.Fatal("non-canonical public key was blinded")
+	}
+	if _, err := BlindPublicKeyRistretto255(pub[:31], blindKey, context); err == nil {
+		t.Fatal("short public key was blinded")
+	}
+	if _, err := BlindPublicKeyRistretto255(pub, blindKey[:31], context); err == nil {
+		t.Fatal("short blind key was accepted")
+	}
+}
+
+func TestBlindKeySignXMD(t *testing.T) {
+	pub, priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := GenerateBlindKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := []byte("context")
+	message := []byte("test message")
+
+	blindedPublicKey, err := BlindPublicKeyXMD(pub, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := BlindKeySignXMD(priv, message, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(blindedPublicKey, message, signature) {
+		t.Fatal("valid blinded signature rejected")
+	}
+	if Verify(pub, message, signature) {
+		t.Fatal("blinded signature verified under the unblinded key")
+	}
+	if err := VerifyBlindKeySignatureXMD(pub, blindedPublicKey, message, signature, blindKey, context); err != nil {
+		t.Fatalf("valid blinded signature rejected: %v", err)
+	}
+	if err := VerifyBlindKeySignatureXMD(pub, blindedPublicKey, message, signature, blindKey, []byte("other context")); err == nil {
+		t.Fatal("blinded signature accepted for a different context")
+	}
+
+	unblindedPublicKey, err := UnblindPublicKeyXMD(blindedPublicKey, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, unblindedPublicKey) {
+		t.Fatal("unblinded public key mismatch")
+	}
+
+	// The blind is bound to the context and differs from the legacy derivation
+	otherContext, err := BlindPublicKeyXMD(pub, blindKey, []byte("other context"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(blindedPublicKey, otherContext) {
+		t.Fatal("blinded public keys for different contexts match")
+	}
+	legacy, err := BlindPublicKeyWithContext(pub, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(blindedPublicKey, legacy) {
+		t.Fatal("blinded public key matches the legacy derivation")
+	}
+
+	if _, err := BlindPublicKeyXMD(pub, blindKey[:31], context); err == nil {
+		t.Fatal("short blind key accepted")
+	}
+	if _, err := BlindKeySignXMD(priv, message, blindKey, make([]byte, maxBlindContextLength+1)); err == nil {
+		t.Fatal("oversized context accepted")
+	}
+}
+
+// /////
+// Key blinding test vector
+type rawKeyBlindingTestVector struct {
+	PrivateKey     string `json:"skS"`
+	PublicKey      string `json:"pkS"`
+	PrivateBlind   string `json:"bk"`
+	PublicBlind    string `json:"pkB"`
+	BlindPublicKey string `json:"pkR"`
+	Message        string `json:"message"`
+	Context        string `json:"context"`
+	Signature      string `json:"signature"`
+}
+
+type keyBlindingTestVector struct {
+	skS       []byte
+	pkS       PublicKey
+	skB       []byte
+	pkB       PublicKey
+	pkR       PublicKey
+	message   []byte
+	context   []byte
+	signature []byte
+}
+
+func (etv keyBlindingTestVector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rawKeyBlindingTestVector{
+		PrivateKey:     hex.EncodeToString(etv.skS),
+		PublicKey:      hex.EncodeToString(etv.pkS),
+		PrivateBlind:   hex.EncodeToString(etv.skB),
+		PublicBlind:    hex.EncodeToString(etv.pkB),
+		BlindPublicKey: hex.EncodeToString(etv.pkR),
+		Message:        hex.EncodeToString(etv.message),
+		Context:        hex.EncodeToString(etv.context),
+		Signature:      hex.EncodeToString(etv.signature),
+	})
+}
+
+func (etv *keyBlindingTestVector) UnmarshalJSON(data []byte) error {
+	raw := rawKeyBlindingTestVector{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		dst *[]byte
+		src string
+	}{
+		{&etv.skS, raw.PrivateKey},
+		{(*[]byte)(&etv.pkS), raw.PublicKey},
+		{&etv.skB, raw.PrivateBlind},
+		{(*[]byte)(&etv.pkB), raw.PublicBlind},
+		{(*[]byte)(&etv.pkR), raw.BlindPublicKey},
+		{&etv.message, raw.Message},
+		{&etv.context, raw.Context},
+		{&etv.signature, raw.Signature},
+	}
+	for _, field := range fields {
+		if *field.dst, err = hex.DecodeString(field.src); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func generateKeyBlindingTestVector(t *testing.T, contextLen int) keyBlindingTestVector {
+	skS := make([]byte, SeedSize)
+	rand.Reader.Read(skS)
+
+	skB, err := GenerateBlindKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := make([]byte, contextLen)
+	rand.Reader.Read(context)
+
+	privateKey := NewKeyFromSeed(skS)
+	publicKey := privateKey.Public().(PublicKey)
+	publicBlind, err := PublicBlindKeyXMD(skB, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindedPublicKey, err := BlindPublicKeyXMD(publicKey, skB, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("hello world")
+	signature, err := BlindKeySignXMD(privateKey, message, skB, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyBlindingTestVector{
+		skS:       skS,
+		pkS:       publicKey,
+		skB:       skB,
+		pkB:       publicBlind,
+		pkR:       blindedPublicKey,
+		message:   message,
+		context:   context,
+		signature: signature,
+	}
+}
+
+func verifyKeyBlindingTestVector(t *testing.T, vector keyBlindingTestVector) {
+	privateKey := NewKeyFromSeed(vector.skS)
+	publicKey := privateKey.Public().(PublicKey)
+	if !bytes.Equal(publicKey, vector.pkS) {
+		t.Fatal("Public key mismatch")
+	}
+
+	publicBlind, err := PublicBlindKeyXMD(vector.skB, vector.context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicBlind, vector.pkB) {
+		t.Fatal("Public blind key mismatch")
+	}
+
+	blindedPublicKey, err := BlindPublicKeyXMD(publicKey, vector.skB, vector.context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blindedPublicKey, vector.pkR) {
+		t.Fatal("Blinded public key mismatch")
+	}
+
+	signature, err := BlindKeySignXMD(privateKey, vector.message, vector.skB, vector.context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signature, vector.signature) {
+		t.Fatal("Signature mismatch")
+	}
+	if !Verify(blindedPublicKey, vector.message, vector.signature) {
+		t.Fatal("Signature with blinded key verification failed")
+	}
+}
+
+func verifyKeyBlindingTestVectors(t *testing.T, encoded []byte) {
+	var vectors []keyBlindingTestVector
+	err := json.Unmarshal(encoded, &vectors)
+	if err != nil {
+		t.Fatalf("Error decoding test vector string: %v", err)
+	}
+
+	for _, vector := range vectors {
+		verifyKeyBlindingTestVector(t, vector)
+	}
+}
+
+func TestVectorGenerateKeyBlinding(t *testing.T) {
+	vectors := make([]keyBlindingTestVector, 0)
+	vectors = append(vectors, generateKeyBlindingTestVector(t, 0))
+	vectors = append(vectors, generateKeyBlindingTestVector(t, 32))
+
+	// Encode the test vectors
+	encoded, err := json.Marshal(vectors)
+	if err != nil {
+		t.Fatalf("Error producing test vectors: %v", err)
+	}
+
+	// Verify that we process them correctly
+	verifyKeyBlindingTestVectors(t, encoded)
+
+	var outputFile string
+	if outputFile = os.Getenv(outputKeyBlindingTestVectorEnvironmentKey); len(outputFile) > 0 {
+		err := ioutil.WriteFile(outputFile, encoded, 0644)
+		if err != nil {
+			t.Fatalf("Error writing test vectors: %v", err)
+		}
+	}
+}
+
+func TestVectorVerifyKeyBlinding(t *testing.T) {
+	var inputFile string
+	if inputFile = os.Getenv(inputKeyBlindingTestVectorEnvironmentKey); len(inputFile) == 0 {
+		t.Skip("Test vectors were not provided")
+	}
+
+	encoded, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		t.Fatalf("Failed reading test vectors: %v", err)
+	}
+
+	verifyKeyBlindingTestVectors(t, encoded)
+}
+
+func BenchmarkBlindKeySignXMD(b *testing.B) {
+	_, priv, err := GenerateKey(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	blindKey := make([]byte, BlindKeySize)
+	message := []byte("Hello, world!")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := BlindKeySignXMD(priv, message, blindKey, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
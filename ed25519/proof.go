@@ -0,0 +1,141 @@
+package ed25519
+
+import (
+	cryptorand "crypto/rand"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
+)
+
+// This file implements a non-interactive Schnorr proof of knowledge of the
+// discrete logarithm r of R to the base A, i.e. of some r with R = [r]A. The
+// prover picks a random k, computes T = [k]A, derives the challenge
+//
+//	c = hash_to_scalar(A || R || T || I2OSP(len(context), 2) || context)
+//
+// with the "Ed25519 Discrete Log Proof" DST, and outputs c || s with
+// s = k - c*r mod L. The verifier recomputes T = [s]A + [c]R and checks the
+// challenge. The proof reveals nothing about r.
+//
+// The proof does not show that r was derived from a blind as the key blinding
+// functions do. Anyone who knows the private scalar a of A can prove any
+// R = [x]B by using r = x / a, so the proof must not be used in place of
+// checking a revealed blind.
+
+// DiscreteLogProofSize is the size, in bytes, of proofs produced by
+// BlindPublicKeyWithDiscreteLogProof and
+// BlindPublicKeyXMDWithDiscreteLogProof.
+const DiscreteLogProofSize = 64
+
+var discreteLogProofDST = []byte("Ed25519 Discrete Log Proof")
+
+func discreteLogProofChallenge(A, R, T, context []byte) *edwards25519.Scalar {
+	msg := make([]byte, 0, len(A)+len(R)+len(T)+2+len(context))
+	msg = append(msg, A...)
+	msg = append(msg, R...)
+	msg = append(msg, T...)
+	msg = append(msg, byte(len(context)>>8), byte(len(context)))
+	msg = append(msg, context...)
+	return hashToScalar(msg, discreteLogProofDST)
+}
+
+func proveDiscreteLog(rand io.Reader, publicKey PublicKey, r *edwards25519.Scalar, context []byte) (PublicKey, []byte, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	if len(context) > maxBlindContextLength {
+		return nil, nil, errors.New("ed25519: blind context too long")
+	}
+	if l := len(publicKey); l != PublicKeySize {
+		return nil, nil, errors.New("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+
+	A, err := (&edwards25519.Point{}).SetBytes(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	R := (&edwards25519.Point{}).ScalarMult(r, A).Bytes()
+
+	var seed [64]byte
+	if _, err := io.ReadFull(rand, seed[:]); err != nil {
+		return nil, nil, err
+	}
+	k := edwards25519.NewScalar().SetUniformBytes(seed[:])
+	T := (&edwards25519.Point{}).ScalarMult(k, A).Bytes()
+
+	c := discreteLogProofChallenge(publicKey, R, T, context)
+	s := edwards25519.NewScalar().Multiply(c, r)
+	s.Subtract(k, s)
+
+	proof := make([]byte, 0, DiscreteLogProofSize)
+	proof = append(proof, c.Bytes()...)
+	proof = append(proof, s.Bytes()...)
+	return R, proof, nil
+}
+
+// BlindPublicKeyWithDiscreteLogProof blinds publicKey by the blind and context
+// string, as BlindPublicKeyWithContext does, and returns the blinded public key
+// together with a proof of knowledge of its discrete logarithm to the base
+// publicKey. The proof is bound to the context string and does not reveal the
+// blind, but it does not show that the blinded key was derived from any blind.
+// If rand is nil, crypto/rand.Reader will be used.
+func BlindPublicKeyWithDiscreteLogProof(rand io.Reader, publicKey PublicKey, blind, context []byte) (PublicKey, []byte, error) {
+	r, _ := blindScalarWithContext(blind, context)
+	return proveDiscreteLog(rand, publicKey, r, context)
+}
+
+// BlindPublicKeyXMDWithDiscreteLogProof blinds publicKey by the blind key and
+// context string, as BlindPublicKeyXMD does, and returns the blinded public
+// key together with a proof of knowledge of its discrete logarithm to the base
+// publicKey. If rand is nil, crypto/rand.Reader will be used.
+func BlindPublicKeyXMDWithDiscreteLogProof(rand io.Reader, publicKey PublicKey, blindKey, context []byte) (PublicKey, []byte, error) {
+	blind, _, err := deriveBlind(blindKey, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proveDiscreteLog(rand, publicKey, blind, context)
+}
+
+// VerifyDiscreteLogProof checks a proof produced by
+// BlindPublicKeyWithDiscreteLogProof or BlindPublicKeyXMDWithDiscreteLogProof
+// that the prover knows some r with blindedPublicKey = [r]publicKey, for the
+// same context string. It returns nil if the proof is valid.
+func VerifyDiscreteLogProof(publicKey, blindedPublicKey PublicKey, proof, context []byte) error {
+	if len(context) > maxBlindContextLength {
+		return errors.New("ed25519: blind context too long")
+	}
+	if l := len(proof); l != DiscreteLogProofSize {
+		return errors.New("ed25519: bad discrete log proof length: " + strconv.Itoa(l))
+	}
+	if len(publicKey) != PublicKeySize || len(blindedPublicKey) != PublicKeySize {
+		return errors.New("ed25519: bad public key length")
+	}
+
+	A, err := (&edwards25519.Point{}).SetBytes(publicKey)
+	if err != nil {
+		return err
+	}
+	R, err := (&edwards25519.Point{}).SetBytes(blindedPublicKey)
+	if err != nil {
+		return err
+	}
+	c, err := edwards25519.NewScalar().SetCanonicalBytes(proof[:32])
+	if err != nil {
+		return err
+	}
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(proof[32:])
+	if err != nil {
+		return err
+	}
+
+	T := (&edwards25519.Point{}).VarTimeMultiScalarMult(
+		[]*edwards25519.Scalar{s, c}, []*edwards25519.Point{A, R})
+
+	expected := discreteLogProofChallenge(publicKey, blindedPublicKey, T.Bytes(), context)
+	if expected.Equal(c) != 1 {
+		return errors.New("ed25519: discrete log proof verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package ed25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"math/big"
+	"testing"
+
+	"github.com/cloudflare/pat-go/ed25519/internal/edwards25519"
+)
+
+func TestDiscreteLogProof(t *testing.T) {
+	public, _, _ := GenerateKey(rand.Reader)
+	otherPublic, _, _ := GenerateKey(rand.Reader)
+
+	blind := make([]byte, 32)
+	rand.Reader.Read(blind)
+	context := []byte("ClientBlind")
+
+	blindedKey, proof, err := BlindPublicKeyWithDiscreteLogProof(rand.Reader, public, blind, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proof) != DiscreteLogProofSize {
+		t.Fatalf("unexpected proof length %d", len(proof))
+	}
+	expected, err := BlindPublicKeyWithContext(public, blind, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blindedKey, expected) {
+		t.Fatal("proved blinded public key does not match BlindPublicKeyWithContext")
+	}
+
+	if err := VerifyDiscreteLogProof(public, blindedKey, proof, context); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if err := VerifyDiscreteLogProof(otherPublic, blindedKey, proof, context); err == nil {
+		t.Error("proof accepted for a different client key")
+	}
+	if err := VerifyDiscreteLogProof(public, otherPublic, proof, context); err == nil {
+		t.Error("proof accepted for a different blinded key")
+	}
+	if err := VerifyDiscreteLogProof(public, blindedKey, proof, []byte("other")); err == nil {
+		t.Error("proof accepted for a different context")
+	}
+
+	for i := range proof {
+		tampered := append([]byte{}, proof...)
+		tampered[i] ^= 0x01
+		if err := VerifyDiscreteLogProof(public, blindedKey, tampered, context); err == nil {
+			t.Fatalf("proof with byte %d modified accepted", i)
+		}
+	}
+	if err := VerifyDiscreteLogProof(public, blindedKey, proof[:DiscreteLogProofSize-1], context); err == nil {
+		t.Error("truncated proof accepted")
+	}
+}
+
+func TestDiscreteLogProofXMD(t *testing.T) {
+	public, _, _ := GenerateKey(rand.Reader)
+	blindKey, err := GenerateBlindKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := []byte("context")
+
+	blindedKey, proof, err := BlindPublicKeyXMDWithDiscreteLogProof(nil, public, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := BlindPublicKeyXMD(public, blindKey, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blindedKey, expected) {
+		t.Fatal("proved blinded public key does not match BlindPublicKeyXMD")
+	}
+	if err := VerifyDiscreteLogProof(public, blindedKey, proof, context); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+}
+
+// TestDiscreteLogProofArbitraryKey checks that the proof only shows knowledge
+// of a discrete logarithm: the holder of the private key for A can prove any
+// key R = [x]B, which is not a blinding of A by any blind it commits to.
+func TestDiscreteLogProofArbitraryKey(t *testing.T) {
+	_, private, _ := GenerateKey(rand.Reader)
+	target, targetPrivate, _ := GenerateKey(rand.Reader)
+	context := []byte("ClientBlind")
+
+	scalar := func(privateKey PrivateKey) *big.Int {
+		h := sha512.Sum512(privateKey.Seed())
+		s := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+		return new(big.Int).SetBytes(reverse(s.Bytes()))
+	}
+
+	// r = x / a mod L, so [r]A = [x]B
+	L, _ := new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+	r := new(big.Int).ModInverse(scalar(private), L)
+	r.Mul(r, scalar(targetPrivate))
+	r.Mod(r, L)
+	rScalar, err := edwards25519.NewScalar().SetCanonicalBytes(reverse(r.FillBytes(make([]byte, 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := private.Public().(PublicKey)
+	blindedKey, proof, err := proveDiscreteLog(rand.Reader, public, rScalar, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blindedKey, target) {
+		t.Fatal("proved key does not match the target key")
+	}
+	if err := VerifyDiscreteLogProof(public, target, proof, context); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
@@ -0,0 +1,332 @@
+// Package ed448 implements the Ed448 signature algorithm of RFC 8032, with
+// support for key blinding mirroring the ed25519 package.
+//
+// Signatures are produced and verified for pure Ed448 with an empty Ed448
+// context string. The context arguments of the blinding functions select the
+// key blind and are unrelated to the Ed448 context string.
+//
+// Like the ed25519 package, the private key representation includes a public
+// key suffix, and RFC 8032 private keys are referred to as the “seed”.
+package ed448
+
+import (
+	"bytes"
+	"crypto"
+	cryptorand "crypto/rand"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/cloudflare/circl/ecc/goldilocks"
+	circled448 "github.com/cloudflare/circl/sign/ed448"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// PublicKeySize is the size, in bytes, of public keys as used in this package.
+	PublicKeySize = 57
+	// PrivateKeySize is the size, in bytes, of private keys as used in this package.
+	PrivateKeySize = 114
+	// SignatureSize is the size, in bytes, of signatures generated and verified by this package.
+	SignatureSize = 114
+	// SeedSize is the size, in bytes, of private key seeds. These are the private key representations used by RFC 8032.
+	SeedSize = 57
+	// BlindSize is the size, in bytes, of blind keys.
+	BlindSize = 57
+
+	hashSize = 2 * PublicKeySize
+)
+
+// PublicKey is the type of Ed448 public keys.
+type PublicKey []byte
+
+// Any methods implemented on PublicKey might need to also be implemented on
+// PrivateKey, as the latter embeds the former and will expose its methods.
+
+// Equal reports whether pub and x have the same value.
+func (pub PublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(PublicKey)
+	if !ok {
+		return false
+	}
+	return bytes.Equal(pub, xx)
+}
+
+// PrivateKey is the type of Ed448 private keys. It implements crypto.Signer.
+type PrivateKey []byte
+
+// Public returns the PublicKey corresponding to priv.
+func (priv PrivateKey) Public() crypto.PublicKey {
+	publicKey := make([]byte, PublicKeySize)
+	copy(publicKey, priv[SeedSize:])
+	return PublicKey(publicKey)
+}
+
+// Equal reports whether priv and x have the same value.
+func (priv PrivateKey) Equal(x crypto.PrivateKey) bool {
+	xx, ok := x.(PrivateKey)
+	if !ok {
+		return false
+	}
+	return bytes.Equal(priv, xx)
+}
+
+// Seed returns the private key seed corresponding to priv. It is provided for
+// interoperability with RFC 8032. RFC 8032's private keys correspond to seeds
+// in this package.
+func (priv PrivateKey) Seed() []byte {
+	seed := make([]byte, SeedSize)
+	copy(seed, priv[:SeedSize])
+	return seed
+}
+
+// Sign signs the given message with priv. Ed448 performs two passes over
+// messages to be signed and therefore cannot handle pre-hashed messages. Thus
+// opts.HashFunc() must return zero to indicate the message hasn't been hashed.
+func (priv PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	if opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("ed448: cannot sign hashed message")
+	}
+
+	return Sign(priv, message), nil
+}
+
+// GenerateKey generates a public/private key pair using entropy from rand.
+// If rand is nil, crypto/rand.Reader will be used.
+func GenerateKey(rand io.Reader) (PublicKey, PrivateKey, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, nil, err
+	}
+
+	privateKey := NewKeyFromSeed(seed)
+	publicKey := make([]byte, PublicKeySize)
+	copy(publicKey, privateKey[SeedSize:])
+
+	return publicKey, privateKey, nil
+}
+
+// NewKeyFromSeed calculates a private key from a seed. It will panic if
+// len(seed) is not SeedSize. This function is provided for interoperability
+// with RFC 8032. RFC 8032's private keys correspond to seeds in this
+// package.
+func NewKeyFromSeed(seed []byte) PrivateKey {
+	if l := len(seed); l != SeedSize {
+		panic("ed448: bad seed length: " + strconv.Itoa(l))
+	}
+
+	s, _ := expandSeed(seed)
+	privateKey := make([]byte, PrivateKeySize)
+	copy(privateKey, seed)
+	if err := (goldilocks.Curve{}).ScalarBaseMult(s).ToBytes(privateKey[SeedSize:]); err != nil {
+		panic("ed448: " + err.Error())
+	}
+	return privateKey
+}
+
+// expandSeed hashes the seed with SHAKE256(seed, 114), and returns the clamped
+// secret scalar and the nonce prefix.
+func expandSeed(seed []byte) (*goldilocks.Scalar, []byte) {
+	var h [hashSize]byte
+	sha3.ShakeSum256(h[:], seed)
+
+	// Clear the two least significant bits of the first octet and all bits
+	// of the last octet, and set the highest bit of the second to last octet.
+	h[0] &= 0xFC
+	h[PublicKeySize-1] = 0x00
+	h[PublicKeySize-2] |= 0x80
+	s := &goldilocks.Scalar{}
+	s.FromBytes(h[:PublicKeySize])
+
+	return s, h[PublicKeySize:]
+}
+
+// expandBlind hashes the blind key and context string with
+// SHAKE256(blind || 0x00 || context, 114), and returns the blind scalar and
+// the nonce prefix contributed by the blind.
+func expandBlind(blind, context []byte) (*goldilocks.Scalar, []byte, error) {
+	var h [hashSize]byte
+	shake := sha3.NewShake256()
+	shake.Write(blind)
+	shake.Write([]byte{0x00})
+	shake.Write(context)
+	shake.Read(h[:])
+
+	r := &goldilocks.Scalar{}
+	r.FromBytes(h[:PublicKeySize])
+	if r.IsZero() {
+		return nil, nil, errors.New("ed448: invalid blind")
+	}
+	return r, h[PublicKeySize:], nil
+}
+
+// invertScalar returns r^-1 mod the group order. It computes r^(n-2) by
+// Fermat's little theorem with a fixed square-and-multiply sequence over the
+// public exponent, so its timing does not depend on r.
+func invertScalar(r *goldilocks.Scalar) *goldilocks.Scalar {
+	e := goldilocks.Curve{}.Order()
+	// The least significant byte of the order is 0xf3, so this cannot borrow.
+	e[0] -= 2
+
+	inv := &goldilocks.Scalar{1}
+	for i := 8*len(e) - 1; i >= 0; i-- {
+		inv.Mul(inv, inv)
+		if (e[i/8]>>(i%8))&1 == 1 {
+			inv.Mul(inv, r)
+		}
+	}
+	return inv
+}
+
+func scalarMultPublicKey(publicKey PublicKey, r *goldilocks.Scalar) (PublicKey, error) {
+	P, err := goldilocks.FromBytes(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	blindedKey := make([]byte, PublicKeySize)
+	if err := (goldilocks.Curve{}).ScalarMult(r, P).ToBytes(blindedKey); err != nil {
+		return nil, err
+	}
+	return blindedKey, nil
+}
+
+// BlindPublicKeyWithContext augments the public key pair by the blind key and context string.
+func BlindPublicKeyWithContext(publicKey PublicKey, blind []byte, context []byte) (PublicKey, error) {
+	if l := len(blind); l != BlindSize {
+		return nil, errors.New("ed448: bad blind length: " + strconv.Itoa(l))
+	}
+	r, _, err := expandBlind(blind, context)
+	if err != nil {
+		return nil, err
+	}
+	return scalarMultPublicKey(publicKey, r)
+}
+
+// BlindPublicKey augments the public key pair by the blind key.
+func BlindPublicKey(publicKey PublicKey, blind []byte) (PublicKey, error) {
+	return BlindPublicKeyWithContext(publicKey, blind, nil)
+}
+
+// UnblindPublicKeyWithContext unblinds the public key pair by the blind key and context string.
+func UnblindPublicKeyWithContext(publicKey PublicKey, blind []byte, context []byte) (PublicKey, error) {
+	if l := len(blind); l != BlindSize {
+		return nil, errors.New("ed448: bad blind length: " + strconv.Itoa(l))
+	}
+	r, _, err := expandBlind(blind, context)
+	if err != nil {
+		return nil, err
+	}
+	return scalarMultPublicKey(publicKey, invertScalar(r))
+}
+
+// UnblindPublicKey unblinds the public key pair by the blind key.
+func UnblindPublicKey(publicKey PublicKey, blind []byte) (PublicKey, error) {
+	return UnblindPublicKeyWithContext(publicKey, blind, nil)
+}
+
+// Sign signs the message with privateKey and returns a signature. It will
+// panic if len(privateKey) is not PrivateKeySize.
+func Sign(privateKey PrivateKey, message []byte) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed448: bad private key length: " + strconv.Itoa(l))
+	}
+
+	s, prefix := expandSeed(privateKey[:SeedSize])
+	signature := make([]byte, SignatureSize)
+	signInternal(signature, privateKey[SeedSize:], message, prefix, s)
+	return signature
+}
+
+// BlindKeySignWithContext signs the message with privateKey blinded by a blind key and context string,
+// and returns a signature. It will panic if len(privateKey) is not PrivateKeySize
+// or len(blind) is not BlindSize.
+func BlindKeySignWithContext(privateKey PrivateKey, message, blind, context []byte) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed448: bad private key length: " + strconv.Itoa(l))
+	}
+	if l := len(blind); l != BlindSize {
+		panic("ed448: bad blind length: " + strconv.Itoa(l))
+	}
+
+	r, prefix2, err := expandBlind(blind, context)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	k, prefix1 := expandSeed(privateKey[:SeedSize])
+	prefix := append(prefix1, prefix2...)
+
+	s := &goldilocks.Scalar{}
+	s.Mul(k, r)
+
+	publicKey, err := scalarMultPublicKey(PublicKey(privateKey[SeedSize:]), r)
+	if err != nil {
+		panic("ed448: " + err.Error())
+	}
+
+	signature := make([]byte, SignatureSize)
+	signInternal(signature, publicKey, message, prefix, s)
+	return signature
+}
+
+// BlindKeySign signs the message with privateKey blinded by a blind key,
+// and returns a signature. It will panic if len(privateKey) is not PrivateKeySize.
+func BlindKeySign(privateKey PrivateKey, message, blind []byte) []byte {
+	return BlindKeySignWithContext(privateKey, message, blind, nil)
+}
+
+// dom4 is the Ed448 domain separation prefix for pure Ed448 with an empty
+// context string.
+var dom4 = []byte("SigEd448\x00\x00")
+
+func signInternal(signature, publicKey, message, prefix []byte, s *goldilocks.Scalar) {
+	// r = SHAKE256(dom4(0, "") || prefix || M, 114)
+	var rDigest [hashSize]byte
+	H := sha3.NewShake256()
+	H.Write(dom4)
+	H.Write(prefix)
+	H.Write(message)
+	H.Read(rDigest[:])
+	r := &goldilocks.Scalar{}
+	r.FromBytes(rDigest[:])
+
+	R := make([]byte, PublicKeySize)
+	if err := (goldilocks.Curve{}).ScalarBaseMult(r).ToBytes(R); err != nil {
+		panic("ed448: " + err.Error())
+	}
+
+	// k = SHAKE256(dom4(0, "") || R || A || M, 114)
+	var hramDigest [hashSize]byte
+	H.Reset()
+	H.Write(dom4)
+	H.Write(R)
+	H.Write(publicKey)
+	H.Write(message)
+	H.Read(hramDigest[:])
+	k := &goldilocks.Scalar{}
+	k.FromBytes(hramDigest[:])
+
+	// S = r + k * s mod L
+	S := &goldilocks.Scalar{}
+	S.Mul(k, s)
+	S.Add(S, r)
+
+	copy(signature[:PublicKeySize], R)
+	copy(signature[PublicKeySize:], S[:])
+	signature[SignatureSize-1] = 0x00
+}
+
+// Verify reports whether sig is a valid signature of message by publicKey. It
+// will panic if len(publicKey) is not PublicKeySize.
+func Verify(publicKey PublicKey, message, sig []byte) bool {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed448: bad public key length: " + strconv.Itoa(l))
+	}
+
+	return circled448.Verify(circled448.PublicKey(publicKey), message, sig, "")
+}
@@ -0,0 +1,246 @@
+package ed448
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/cloudflare/circl/ecc/goldilocks"
+	circled448 "github.com/cloudflare/circl/sign/ed448"
+)
+
+// RFC 8032, Section 7.4, "Blank" and "1 octet" test vectors.
+var rfc8032Vectors = []struct {
+	seed, publicKey, message, signature string
+}{
+	{
+		seed:      "6c82a562cb808d10d632be89c8513ebf6c929f34ddfa8c9f63c9960ef6e348a3528c8a3fcc2f044e39a3fc5b94492f8f032e7549a20098f95b",
+		publicKey: "5fd7449b59b461fd2ce787ec616ad46a1da1342485a70e1f8a0ea75d80e96778edf124769b46c7061bd6783df1e50f6cd1fa1abeafe8256180",
+		message:   "",
+		signature: "533a37f6bbe457251f023c0d88f976ae2dfb504a843e34d2074fd823d41a591f2b233f034f628281f2fd7a22ddd47d7828c59bd0a21bfd3980ff0d2028d4b18a9df63e006c5d1c2d345b925d8dc00b4104852db99ac5c7cdda8530a113a0f4dbb61149f05a7363268c71d95808ff2e652600",
+	},
+	{
+		seed:      "c4eab05d357007c632f3dbb48489924d552b08fe0c353a0d4a1f00acda2c463afbea67c5e8d2877c5e3bc397a659949ef8021e954e0a12274e",
+		publicKey: "43ba28f430cdff456ae531545f7ecd0ac834a55d9358c0372bfa0c6c6798c0866aea01eb00742802b8438ea4cb82169c235160627b4c3a9480",
+		message:   "03",
+		signature: "26b8f91727bd62897af15e41eb43c377efb9c610d48f2335cb0bd0087810f4352541b143c4b981b7e18f62de8ccdf633fc1bf037ab7cd779805e0dbcc0aae1cbcee1afb2e027df36bc04dcecbf154336c19f0af7e0a6472905e799f1953d2a0ff3348ab21aa4adafd1d234441cf807c03a00",
+	},
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRFC8032Vectors(t *testing.T) {
+	for _, v := range rfc8032Vectors {
+		privateKey := NewKeyFromSeed(mustDecodeHex(t, v.seed))
+		publicKey := privateKey.Public().(PublicKey)
+		if !bytes.Equal(publicKey, mustDecodeHex(t, v.publicKey)) {
+			t.Fatal("public key mismatch")
+		}
+
+		message := mustDecodeHex(t, v.message)
+		signature := Sign(privateKey, message)
+		if !bytes.Equal(signature, mustDecodeHex(t, v.signature)) {
+			t.Fatal("signature mismatch")
+		}
+		if !Verify(publicKey, message, signature) {
+			t.Fatal("valid signature rejected")
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	public, private, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("test message")
+	sig := Sign(private, message)
+	if !bytes.Equal(sig, circled448.Sign(circled448.NewKeyFromSeed(private.Seed()), message, "")) {
+		t.Errorf("signature does not match the reference implementation")
+	}
+	if !Verify(public, message, sig) {
+		t.Errorf("valid signature rejected")
+	}
+
+	wrongMessage := []byte("wrong message")
+	if Verify(public, wrongMessage, sig) {
+		t.Errorf("signature of different message accepted")
+	}
+}
+
+func TestCryptoSigner(t *testing.T) {
+	public, private, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var signer crypto.Signer = private
+	if !public.Equal(signer.Public()) {
+		t.Fatal("Public() mismatch")
+	}
+
+	message := []byte("message")
+	signature, err := signer.Sign(nil, message, crypto.Hash(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(public, message, signature) {
+		t.Errorf("Verify failed on signature from Sign()")
+	}
+	if _, err := signer.Sign(nil, message, crypto.SHA256); err == nil {
+		t.Errorf("expected error signing a hashed message")
+	}
+}
+
+func TestBlindUnblindKeyWithContext(t *testing.T) {
+	public, private, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blind := make([]byte, BlindSize)
+	rand.Read(blind)
+	context := []byte("context")
+
+	blindedPublic, err := BlindPublicKeyWithContext(public, blind, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(public, blindedPublic) {
+		t.Fatal("blinded public key matches the public key")
+	}
+
+	otherContext, err := BlindPublicKeyWithContext(public, blind, []byte("other context"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(blindedPublic, otherContext) {
+		t.Fatal("blinded public keys for different contexts match")
+	}
+
+	unblindedPublic, err := UnblindPublicKeyWithContext(blindedPublic, blind, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(public, unblindedPublic) {
+		t.Fatal("unblinded public key mismatch")
+	}
+
+	message := []byte("test message")
+	sig := BlindKeySignWithContext(private, message, blind, context)
+	if !Verify(blindedPublic, message, sig) {
+		t.Errorf("valid blinded signature rejected")
+	}
+	if Verify(public, message, sig) {
+		t.Errorf("blinded signature accepted under the unblinded key")
+	}
+
+	if _, err := BlindPublicKeyWithContext(public, blind[:32], context); err == nil {
+		t.Errorf("short blind accepted")
+	}
+}
+
+func TestBlindUnblindKey(t *testing.T) {
+	public, private, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blind := make([]byte, BlindSize)
+	rand.Read(blind)
+
+	blindedPublic, err := BlindPublicKey(public, blind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unblindedPublic, err := UnblindPublicKey(blindedPublic, blind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(public, unblindedPublic) {
+		t.Fatal("unblinded public key mismatch")
+	}
+
+	message := []byte("test message")
+	if !Verify(blindedPublic, message, BlindKeySign(private, message, blind)) {
+		t.Errorf("valid blinded signature rejected")
+	}
+}
+
+func BenchmarkSigning(b *testing.B) {
+	_, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := []byte("Hello, world!")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sign(priv, message)
+	}
+}
+
+func BenchmarkBlindKeySigning(b *testing.B) {
+	_, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	blind := make([]byte, BlindSize)
+	rand.Read(blind)
+	message := []byte("Hello, world!")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BlindKeySignWithContext(priv, message, blind, nil)
+	}
+}
+
+func BenchmarkVerification(b *testing.B) {
+	pub, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := []byte("Hello, world!")
+	signature := Sign(priv, message)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Verify(pub, message, signature)
+	}
+}
+
+func TestInvertScalar(t *testing.T) {
+	order := goldilocks.Curve{}.Order()
+	n := new(big.Int).SetBytes(reverseBytes(order[:]))
+	for i := 0; i < 32; i++ {
+		var buf [goldilocks.ScalarSize]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatal(err)
+		}
+		r := &goldilocks.Scalar{}
+		r.FromBytes(buf[:])
+
+		inv := invertScalar(r)
+		x := new(big.Int).SetBytes(reverseBytes(r[:]))
+		expected := new(big.Int).ModInverse(x, n)
+		if actual := new(big.Int).SetBytes(reverseBytes(inv[:])); actual.Cmp(expected) != 0 {
+			t.Fatalf("invertScalar(%x) = %x, expected %x", r[:], actual, expected)
+		}
+	}
+}
+
+func reverseBytes(x []byte) []byte {
+	y := make([]byte, len(x))
+	for i := range x {
+		y[len(x)-(i+1)] = x[i]
+	}
+	return y
+}
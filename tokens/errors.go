@@ -0,0 +1,98 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors returned when decoding, issuing and verifying tokens. Errors
+// returned by this module that fall into one of these kinds match it with
+// errors.Is, so that callers can tell client errors apart from server faults
+// without inspecting error strings.
+var (
+	// ErrMalformedEncoding is returned when a token, token request, token
+	// response, challenge or key cannot be decoded.
+	ErrMalformedEncoding = errors.New("malformed encoding")
+
+	// ErrUnknownKey is returned when a request or token names a key that the
+	// issuer or verifier does not know.
+	ErrUnknownKey = errors.New("unknown key")
+
+	// ErrUnexpectedTokenType is returned when a token is of a different token
+	// type than the verifier or the challenge it is checked against.
+	ErrUnexpectedTokenType = errors.New("unexpected token type")
+
+	// ErrUnknownClient is returned when the attester has no state for the
+	// client a request comes from.
+	ErrUnknownClient = errors.New("unknown client")
+
+	// ErrUnknownOrigin is returned when a request or token is for an origin
+	// that is not registered or not allowed by the challenge.
+	ErrUnknownOrigin = errors.New("unknown origin")
+
+	// ErrSignatureInvalid is returned when a token authenticator or request
+	// signature does not verify.
+	ErrSignatureInvalid = errors.New("signature invalid")
+
+	// ErrRateLimited is returned when a client request would violate the
+	// attester's rate limiting invariants.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrReplay is returned when a token is redeemed against a challenge it
+	// was not issued for, e.g. one whose redemption context has expired. This
+	// module does not track redemptions itself, so double-spend checks built
+	// on top of it should report tokens that were already used with the same
+	// kind.
+	ErrReplay = errors.New("replay")
+)
+
+// Error is an error of one of the kinds above. Its message is that of the
+// underlying error, which it unwraps to.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Is reports whether target is the kind of e.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errorf formats an error of the given kind as fmt.Errorf does, so that a
+// cause can be wrapped with the %w verb.
+func Errorf(kind error, format string, args ...interface{}) error {
+	return &Error{
+		Kind: kind,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+var errorKinds = []error{
+	ErrMalformedEncoding,
+	ErrUnknownKey,
+	ErrUnexpectedTokenType,
+	ErrUnknownClient,
+	ErrUnknownOrigin,
+	ErrSignatureInvalid,
+	ErrRateLimited,
+	ErrReplay,
+}
+
+// ErrorKind returns the kind of err, or nil if err is nil or not of any of the
+// kinds above.
+func ErrorKind(err error) error {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package tokens
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	err := Errorf(ErrMalformedEncoding, "invalid encoding: %w", io.ErrUnexpectedEOF)
+	if err.Error() != "invalid encoding: unexpected EOF" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+	if !errors.Is(err, ErrMalformedEncoding) {
+		t.Fatal("error does not match its kind")
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("error does not match its cause")
+	}
+	if errors.Is(err, ErrSignatureInvalid) {
+		t.Fatal("error matches another kind")
+	}
+
+	var tokenErr *Error
+	if !errors.As(err, &tokenErr) || tokenErr.Kind != ErrMalformedEncoding {
+		t.Fatal("failed to recover the error kind")
+	}
+}
+
+func TestUnmarshalTokenChallengeErrorKind(t *testing.T) {
+	for _, data := range [][]byte{
+		{},
+		{0x00, 0x02, 0x00, 0x00},
+		{0x00, 0x02, 0x00, 0x01, 'i', 0x01, 0xAA, 0x00, 0x00},
+	} {
+		if _, err := UnmarshalTokenChallenge(data); !errors.Is(err, ErrMalformedEncoding) {
+			t.Errorf("UnmarshalTokenChallenge(%x) = %v, expected a malformed encoding error", data, err)
+		}
+	}
+}
+
+func TestVerifyTokenChallengeErrorKind(t *testing.T) {
+	challenge := createTokenChallenge(0x0002, nil, "issuer.example", []string{"origin.example"})
+	token := Token{TokenType: challenge.TokenType}
+	if err := VerifyTokenChallenge(token, challenge, "other.example"); !errors.Is(err, ErrUnknownOrigin) {
+		t.Fatalf("VerifyTokenChallenge = %v, expected an unknown origin error", err)
+	}
+	if err := VerifyTokenChallenge(token, challenge, "origin.example"); !errors.Is(err, ErrReplay) {
+		t.Fatalf("VerifyTokenChallenge = %v, expected a replay error", err)
+	}
+	token.TokenType = 0x0001
+	if err := VerifyTokenChallenge(token, challenge, "origin.example"); !errors.Is(err, ErrUnexpectedTokenType) {
+		t.Fatalf("VerifyTokenChallenge = %v, expected an unexpected token type error", err)
+	}
+}
@@ -0,0 +1,158 @@
+package tokens
+
+import (
+	"sync"
+	"time"
+)
+
+// Operation names a step of the issuance protocol reported to an Observer.
+type Operation string
+
+const (
+	// OperationEvaluate is an issuer evaluating a token request.
+	OperationEvaluate Operation = "Evaluate"
+	// OperationVerifyRequest is the rate-limited attester verifying a token
+	// request from a client.
+	OperationVerifyRequest Operation = "VerifyRequest"
+	// OperationFinalizeIndex is the rate-limited attester computing the
+	// anonymous issuer origin ID of a request.
+	OperationFinalizeIndex Operation = "FinalizeIndex"
+)
+
+// RequestInfo describes a request reported to an Observer.
+type RequestInfo struct {
+	Operation Operation
+	TokenType uint16
+	// KeyID identifies the issuer key the request is for, if known. For
+	// OperationEvaluate it is the token key ID. For OperationVerifyRequest it
+	// is the ID of the issuer name key the request is encrypted to, since the
+	// attester does not learn the token key. OperationFinalizeIndex leaves it
+	// empty.
+	KeyID []byte
+}
+
+// Observer receives events from issuers and attesters, e.g. to export metrics
+// or traces. Methods may be called concurrently and must not block.
+type Observer interface {
+	// RequestReceived is called when an operation starts.
+	RequestReceived(info RequestInfo)
+	// RequestCompleted is called when an operation returns, with how long it
+	// took and the error it returned, if any. ErrorKind classifies the error.
+	RequestCompleted(info RequestInfo, duration time.Duration, err error)
+}
+
+// NopObserver is an Observer that ignores all events. Issuers and attesters
+// without an observer behave as if they had this one.
+type NopObserver struct{}
+
+func (NopObserver) RequestReceived(RequestInfo) {}
+
+func (NopObserver) RequestCompleted(RequestInfo, time.Duration, error) {}
+
+// ObserveRequest reports the start of an operation to o and returns a function
+// that reports its completion with the given error. A nil o is a NopObserver.
+func ObserveRequest(o Observer, info RequestInfo) func(err error) {
+	if o == nil {
+		return func(error) {}
+	}
+	o.RequestReceived(info)
+	start := time.Now()
+	return func(err error) {
+		o.RequestCompleted(info, time.Since(start), err)
+	}
+}
+
+type observerKey struct {
+	operation Operation
+	tokenType uint16
+}
+
+type observerCounts struct {
+	received  int
+	completed int
+	failures  map[error]int
+	duration  time.Duration
+}
+
+// MemoryObserver is an Observer that counts events in memory, per operation
+// and token type. It is intended for tests.
+type MemoryObserver struct {
+	mu     sync.Mutex
+	counts map[observerKey]*observerCounts
+	keyIDs map[observerKey][]byte
+}
+
+func NewMemoryObserver() *MemoryObserver {
+	return &MemoryObserver{
+		counts: make(map[observerKey]*observerCounts),
+		keyIDs: make(map[observerKey][]byte),
+	}
+}
+
+func (o *MemoryObserver) countsFor(key observerKey) *observerCounts {
+	c, ok := o.counts[key]
+	if !ok {
+		c = &observerCounts{failures: make(map[error]int)}
+		o.counts[key] = c
+	}
+	return c
+}
+
+func (o *MemoryObserver) RequestReceived(info RequestInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := observerKey{info.Operation, info.TokenType}
+	o.countsFor(key).received++
+	o.keyIDs[key] = append([]byte{}, info.KeyID...)
+}
+
+func (o *MemoryObserver) RequestCompleted(info RequestInfo, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c := o.countsFor(observerKey{info.Operation, info.TokenType})
+	c.completed++
+	c.duration += duration
+	if err != nil {
+		c.failures[ErrorKind(err)]++
+	}
+}
+
+// Received returns the number of operations of token type tokenType that were
+// started.
+func (o *MemoryObserver) Received(operation Operation, tokenType uint16) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.countsFor(observerKey{operation, tokenType}).received
+}
+
+// Completed returns the number of operations that returned, successfully or
+// not.
+func (o *MemoryObserver) Completed(operation Operation, tokenType uint16) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.countsFor(observerKey{operation, tokenType}).completed
+}
+
+// Failed returns the number of operations that returned an error of the given
+// kind. A nil kind counts errors that are not of any kind.
+func (o *MemoryObserver) Failed(operation Operation, tokenType uint16, kind error) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.countsFor(observerKey{operation, tokenType}).failures[kind]
+}
+
+// Duration returns the total time spent in completed operations.
+func (o *MemoryObserver) Duration(operation Operation, tokenType uint16) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.countsFor(observerKey{operation, tokenType}).duration
+}
+
+// LastKeyID returns the key ID of the last operation that was started.
+func (o *MemoryObserver) LastKeyID(operation Operation, tokenType uint16) []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.keyIDs[observerKey{operation, tokenType}]
+}
@@ -0,0 +1,63 @@
+package tokens
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestObserveRequest(t *testing.T) {
+	// A nil observer is a no-op.
+	ObserveRequest(nil, RequestInfo{Operation: OperationEvaluate})(nil)
+
+	observer := NewMemoryObserver()
+	info := RequestInfo{
+		Operation: OperationEvaluate,
+		TokenType: 0x0002,
+		KeyID:     []byte{0x01, 0x02},
+	}
+
+	ObserveRequest(observer, info)(nil)
+	ObserveRequest(observer, info)(Errorf(ErrUnknownKey, "unknown key"))
+	ObserveRequest(observer, info)(fmt.Errorf("internal error"))
+	done := ObserveRequest(observer, info)
+
+	if n := observer.Received(OperationEvaluate, 0x0002); n != 4 {
+		t.Fatalf("received %d requests, expected 4", n)
+	}
+	if n := observer.Completed(OperationEvaluate, 0x0002); n != 3 {
+		t.Fatalf("completed %d requests, expected 3", n)
+	}
+	done(nil)
+	if n := observer.Completed(OperationEvaluate, 0x0002); n != 4 {
+		t.Fatalf("completed %d requests, expected 4", n)
+	}
+	if n := observer.Failed(OperationEvaluate, 0x0002, ErrUnknownKey); n != 1 {
+		t.Fatalf("%d unknown key failures, expected 1", n)
+	}
+	if n := observer.Failed(OperationEvaluate, 0x0002, nil); n != 1 {
+		t.Fatalf("%d unclassified failures, expected 1", n)
+	}
+	if n := observer.Failed(OperationEvaluate, 0x0002, ErrMalformedEncoding); n != 0 {
+		t.Fatalf("%d malformed encoding failures, expected 0", n)
+	}
+	if !bytes.Equal(observer.LastKeyID(OperationEvaluate, 0x0002), info.KeyID) {
+		t.Fatal("key ID mismatch")
+	}
+	if n := observer.Received(OperationEvaluate, 0x0003); n != 0 {
+		t.Fatalf("received %d requests for another token type", n)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	if kind := ErrorKind(nil); kind != nil {
+		t.Fatalf("ErrorKind(nil) = %v", kind)
+	}
+	if kind := ErrorKind(fmt.Errorf("internal error")); kind != nil {
+		t.Fatalf("ErrorKind of an unclassified error = %v", kind)
+	}
+	wrapped := fmt.Errorf("request failed: %w", Errorf(ErrRateLimited, "too many requests"))
+	if kind := ErrorKind(wrapped); kind != ErrRateLimited {
+		t.Fatalf("ErrorKind = %v, expected %v", kind, ErrRateLimited)
+	}
+}
@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const defaultOriginPort = "443"
+
+// normalizeOrigin splits an origin name into a lower-case host and a port,
+// defaulting to port 443 when none is given.
+func normalizeOrigin(origin string) (string, string, error) {
+	host, port := origin, defaultOriginPort
+	if strings.HasPrefix(origin, "[") && strings.HasSuffix(origin, "]") {
+		host = origin[1 : len(origin)-1]
+	} else if strings.HasPrefix(origin, "[") || strings.Count(origin, ":") == 1 {
+		var err error
+		host, port, err = net.SplitHostPort(origin)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid origin name %q: %v", origin, err)
+		}
+		if len(port) == 0 {
+			port = defaultOriginPort
+		}
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(host) == 0 {
+		return "", "", fmt.Errorf("invalid origin name %q: empty host", origin)
+	}
+	return host, port, nil
+}
+
+// OriginNamesMatch reports whether two origin names refer to the same origin.
+// Hosts are compared case-insensitively, ignoring a trailing dot, and a
+// missing port is equivalent to port 443.
+func OriginNamesMatch(a, b string) bool {
+	hostA, portA, err := normalizeOrigin(a)
+	if err != nil {
+		return false
+	}
+	hostB, portB, err := normalizeOrigin(b)
+	if err != nil {
+		return false
+	}
+	return hostA == hostB && portA == portB
+}
+
+// MatchesOrigin reports whether a token for this challenge may be redeemed at
+// origin. A challenge with an empty origin list may be redeemed at any origin;
+// otherwise origin must match one of the listed origin names.
+func (c TokenChallenge) MatchesOrigin(origin string) bool {
+	if len(c.OriginInfo) == 0 {
+		return true
+	}
+	for _, originName := range c.OriginInfo {
+		if OriginNamesMatch(originName, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyTokenChallenge checks that token was issued for challenge and that the
+// challenge allows redemption at origin. It does not check the token
+// authenticator.
+func VerifyTokenChallenge(token Token, challenge TokenChallenge, origin string) error {
+	if token.TokenType != challenge.TokenType {
+		return Errorf(ErrUnexpectedTokenType, "token type mismatch: token %04x, challenge %04x", token.TokenType, challenge.TokenType)
+	}
+	if !challenge.MatchesOrigin(origin) {
+		return Errorf(ErrUnknownOrigin, "challenge not valid for origin: %s", origin)
+	}
+
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		return err
+	}
+	context := sha256.Sum256(challengeEnc)
+	if subtle.ConstantTimeCompare(context[:], token.Context) != 1 {
+		return Errorf(ErrReplay, "token context does not match challenge")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestOriginNamesMatch(t *testing.T) {
+	var cases = []struct {
+		a, b  string
+		match bool
+	}{
+		{"origin.example", "origin.example", true},
+		{"Origin.Example", "origin.example", true},
+		{"origin.example.", "origin.example", true},
+		{"origin.example", "origin.example:443", true},
+		{"origin.example:443", "origin.example", true},
+		{"origin.example:8443", "origin.example:8443", true},
+		{"origin.example:8443", "origin.example", false},
+		{"origin.example", "origin.example:8443", false},
+		{"origin.example", "other.example", false},
+		{"sub.origin.example", "origin.example", false},
+		{"[2001:db8::1]:8443", "[2001:DB8::1]:8443", true},
+		{"[2001:db8::1]", "2001:db8::1", true},
+		{"origin.example:", "origin.example", true},
+		{"", "origin.example", false},
+	}
+
+	for _, c := range cases {
+		if OriginNamesMatch(c.a, c.b) != c.match {
+			t.Fatalf("OriginNamesMatch(%q, %q) = %v, expected %v", c.a, c.b, !c.match, c.match)
+		}
+	}
+}
+
+func TestTokenChallengeMatchesOrigin(t *testing.T) {
+	anyOrigin := TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example"}
+	if !anyOrigin.MatchesOrigin("origin.example") {
+		t.Fatal("Challenge without origins should match any origin")
+	}
+
+	multipleOrigins := TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{"foo.example", "bar.example:8443"}}
+	if !multipleOrigins.MatchesOrigin("FOO.example") {
+		t.Fatal("Expected first origin to match")
+	}
+	if !multipleOrigins.MatchesOrigin("bar.example:8443") {
+		t.Fatal("Expected second origin to match")
+	}
+	if multipleOrigins.MatchesOrigin("bar.example") {
+		t.Fatal("Expected origin with a different port not to match")
+	}
+	if multipleOrigins.MatchesOrigin("baz.example") {
+		t.Fatal("Expected unlisted origin not to match")
+	}
+}
+
+func TestVerifyTokenChallenge(t *testing.T) {
+	challenge := TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{"foo.example", "bar.example"}}
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := sha256.Sum256(challengeEnc)
+	token := Token{
+		TokenType: 0x0002,
+		Nonce:     make([]byte, 32),
+		Context:   context[:],
+		KeyID:     make([]byte, 32),
+	}
+
+	if err := VerifyTokenChallenge(token, challenge, "bar.example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTokenChallenge(token, challenge, "baz.example"); err == nil {
+		t.Fatal("Expected verification at an unlisted origin to fail")
+	}
+
+	otherChallenge := challenge
+	otherChallenge.OriginInfo = []string{"bar.example"}
+	if err := VerifyTokenChallenge(token, otherChallenge, "bar.example"); err == nil {
+		t.Fatal("Expected verification against a different challenge to fail")
+	}
+
+	token.TokenType = 0x0001
+	if err := VerifyTokenChallenge(token, challenge, "bar.example"); err == nil {
+		t.Fatal("Expected verification with a mismatched token type to fail")
+	}
+}
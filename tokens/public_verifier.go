@@ -0,0 +1,56 @@
+package tokens
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/cloudflare/pat-go/util"
+)
+
+// VerifyPublicToken checks that token has the given type, was issued under
+// the RSA-PSS token key and carries a valid signature. It is shared by the
+// publicly verifiable token types, which differ only in their token type.
+func VerifyPublicToken(token Token, tokenType uint16, tokenKey *rsa.PublicKey) error {
+	if token.TokenType != tokenType {
+		return Errorf(ErrUnexpectedTokenType, "invalid token type: %04x", token.TokenType)
+	}
+
+	publicKeyEnc, err := util.MarshalTokenKeyPSSOID(tokenKey)
+	if err != nil {
+		return err
+	}
+	keyID := sha256.Sum256(publicKeyEnc)
+	if !bytes.Equal(token.KeyID, keyID[:]) {
+		return Errorf(ErrUnknownKey, "unknown token key ID %x", token.KeyID)
+	}
+
+	hash := sha512.New384()
+	_, err = hash.Write(token.AuthenticatorInput())
+	if err != nil {
+		return err
+	}
+	digest := hash.Sum(nil)
+
+	err = rsa.VerifyPSS(tokenKey, crypto.SHA384, digest, token.Authenticator, &rsa.PSSOptions{
+		Hash:       crypto.SHA384,
+		SaltLength: crypto.SHA384.Size(),
+	})
+	if err != nil {
+		return Errorf(ErrSignatureInvalid, "%w", err)
+	}
+
+	return nil
+}
+
+// VerifyPublicTokenForOrigin checks that token was issued for challenge, that
+// the challenge allows redemption at origin, and then verifies the token as
+// VerifyPublicToken does.
+func VerifyPublicTokenForOrigin(token Token, challenge TokenChallenge, origin string, tokenType uint16, tokenKey *rsa.PublicKey) error {
+	if err := VerifyTokenChallenge(token, challenge, origin); err != nil {
+		return err
+	}
+	return VerifyPublicToken(token, tokenType, tokenKey)
+}
@@ -0,0 +1,123 @@
+package redemption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+var labelChallenge = []byte("RedemptionContext Challenge")
+
+// ChallengeParameters are the TokenChallenge fields, other than the redemption
+// nonce, that an origin chooses when issuing a challenge.
+type ChallengeParameters struct {
+	TokenType  uint16
+	IssuerName string
+	OriginInfo []string
+}
+
+func (p ChallengeParameters) challenge() tokens.TokenChallenge {
+	return tokens.TokenChallenge{
+		TokenType:  p.TokenType,
+		IssuerName: p.IssuerName,
+		OriginInfo: p.OriginInfo,
+	}
+}
+
+// ChallengeIssuer issues TokenChallenges whose redemption nonce is a MAC over
+// the challenge parameters and the time window in which the challenge was
+// issued. When a token is redeemed, the origin recomputes the challenge for
+// each candidate set of parameters and each window still considered valid,
+// and compares it against the token context. No per-challenge state is kept.
+type ChallengeIssuer struct {
+	key          []byte
+	window       time.Duration
+	validWindows int
+}
+
+// NewChallengeIssuer creates a ChallengeIssuer with the given MAC key. Issued
+// challenges remain valid for validWindows windows of the given duration,
+// including the window in which they were issued.
+func NewChallengeIssuer(key []byte, window time.Duration, validWindows int) (*ChallengeIssuer, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("empty challenge key")
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid challenge window: %s", window)
+	}
+	if validWindows < 1 {
+		return nil, fmt.Errorf("invalid number of valid challenge windows: %d", validWindows)
+	}
+	return &ChallengeIssuer{
+		key:          key,
+		window:       window,
+		validWindows: validWindows,
+	}, nil
+}
+
+func (i *ChallengeIssuer) redemptionNonce(params ChallengeParameters, bucket uint64) ([]byte, error) {
+	// The challenge encoding without a nonce unambiguously encodes the parameters
+	paramsEnc, err := params.challenge().Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	var bucketEnc [8]byte
+	binary.BigEndian.PutUint64(bucketEnc[:], bucket)
+
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write(labelChallenge)
+	mac.Write(bucketEnc[:])
+	mac.Write(paramsEnc)
+	return mac.Sum(nil), nil
+}
+
+func (i *ChallengeIssuer) challengeForBucket(params ChallengeParameters, bucket uint64) (tokens.TokenChallenge, error) {
+	nonce, err := i.redemptionNonce(params, bucket)
+	if err != nil {
+		return tokens.TokenChallenge{}, err
+	}
+	return tokens.NewTokenChallenge(params.TokenType, params.IssuerName, nonce, params.OriginInfo)
+}
+
+// IssueChallenge returns a TokenChallenge for the given parameters, bound to
+// the window containing now.
+func (i *ChallengeIssuer) IssueChallenge(params ChallengeParameters, now time.Time) (tokens.TokenChallenge, error) {
+	return i.challengeForBucket(params, timeBucket(now, i.window))
+}
+
+// RecoverChallenge reconstructs the TokenChallenge the token was issued for,
+// trying every candidate set of parameters in every window that is still valid
+// at now. It returns the matching challenge, or an error if the token does not
+// correspond to any challenge this issuer could have produced.
+//
+// RecoverChallenge only matches the token context. It does not check the
+// token authenticator, so the token must still be verified with the issuer's
+// verifier before it is accepted.
+func (i *ChallengeIssuer) RecoverChallenge(token tokens.Token, candidates []ChallengeParameters, now time.Time) (tokens.TokenChallenge, error) {
+	current := timeBucket(now, i.window)
+	for _, params := range candidates {
+		if params.TokenType != token.TokenType {
+			continue
+		}
+		for w := 0; w < i.validWindows && uint64(w) <= current; w++ {
+			challenge, err := i.challengeForBucket(params, current-uint64(w))
+			if err != nil {
+				return tokens.TokenChallenge{}, err
+			}
+			context, err := ComputeTokenContext(challenge)
+			if err != nil {
+				return tokens.TokenChallenge{}, err
+			}
+			if subtle.ConstantTimeCompare(context, token.Context) == 1 {
+				return challenge, nil
+			}
+		}
+	}
+	return tokens.TokenChallenge{}, tokens.Errorf(tokens.ErrReplay, "token does not match any issued challenge")
+}
@@ -0,0 +1,111 @@
+package redemption
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/tokens/type2"
+)
+
+func issueToken(t *testing.T, challenge tokens.TokenChallenge) tokens.Token {
+	tokenKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := type2.NewBasicPublicIssuer(tokenKey)
+	client := type2.NewBasicPublicClient()
+
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challengeEnc, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := requestState.FinalizeToken(blindSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestChallengeIssuerRoundTrip(t *testing.T) {
+	window := time.Minute
+	issuer, err := NewChallengeIssuer([]byte("test key"), window, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := ChallengeParameters{
+		TokenType:  type2.BasicPublicTokenType,
+		IssuerName: "issuer.example",
+		OriginInfo: []string{"origin.example"},
+	}
+	otherParams := ChallengeParameters{
+		TokenType:  type2.BasicPublicTokenType,
+		IssuerName: "issuer.example",
+		OriginInfo: []string{"other.example"},
+	}
+
+	issuedAt := time.Unix(1700000000, 0).Truncate(window)
+	challenge, err := issuer.IssueChallenge(params, issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := issueToken(t, challenge)
+
+	recovered, err := issuer.RecoverChallenge(token, []ChallengeParameters{otherParams, params}, issuedAt.Add(window))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recovered.Equals(challenge) {
+		t.Fatal("Recovered challenge mismatch")
+	}
+
+	if _, err := issuer.RecoverChallenge(token, []ChallengeParameters{otherParams}, issuedAt); !errors.Is(err, tokens.ErrReplay) {
+		t.Fatal("Expected verification with unrelated parameters to fail")
+	}
+	if _, err := issuer.RecoverChallenge(token, []ChallengeParameters{params}, issuedAt.Add(2*window)); !errors.Is(err, tokens.ErrReplay) {
+		t.Fatal("Expected verification of an expired challenge to fail")
+	}
+
+	otherIssuer, err := NewChallengeIssuer([]byte("other key"), window, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otherIssuer.RecoverChallenge(token, []ChallengeParameters{params}, issuedAt); err == nil {
+		t.Fatal("Expected verification with a different key to fail")
+	}
+}
+
+func TestChallengeIssuerConfiguration(t *testing.T) {
+	if _, err := NewChallengeIssuer(nil, time.Minute, 1); err == nil {
+		t.Fatal("Expected empty key to be rejected")
+	}
+	if _, err := NewChallengeIssuer([]byte("key"), 0, 1); err == nil {
+		t.Fatal("Expected empty window to be rejected")
+	}
+	if _, err := NewChallengeIssuer([]byte("key"), time.Minute, 0); err == nil {
+		t.Fatal("Expected zero valid windows to be rejected")
+	}
+
+	issuer, err := NewChallengeIssuer([]byte("key"), time.Minute, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.IssueChallenge(ChallengeParameters{TokenType: 0x0002}, time.Now()); err == nil {
+		t.Fatal("Expected challenge without issuer name to be rejected")
+	}
+}
@@ -0,0 +1,177 @@
+// Package redemption provides strategies for generating the redemption nonce
+// an origin places in a TokenChallenge, along with helpers to check that the
+// context of a redeemed token corresponds to a challenge the origin could have
+// issued.
+//
+// Three strategies are supported:
+//
+//   - RandomGenerator produces a fresh random nonce per challenge. Tokens bound
+//     to these nonces can only be verified against nonces the origin stored.
+//   - TimeWindowGenerator derives the nonce as HMAC(key, time bucket), so any
+//     token issued for the current or previous window can be verified without
+//     storing challenges.
+//   - SessionGenerator derives the nonce as HMAC(key, session identifier),
+//     binding tokens to a session known to the origin.
+package redemption
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+// NonceLength is the length of the redemption nonces produced by this package.
+const NonceLength = 32
+
+var (
+	labelTimeWindow = []byte("RedemptionContext TimeWindow")
+	labelSession    = []byte("RedemptionContext Session")
+)
+
+// RandomGenerator produces uniformly random redemption nonces.
+type RandomGenerator struct {
+	rand io.Reader
+}
+
+// NewRandomGenerator creates a RandomGenerator reading from rand. If rand is
+// nil, crypto/rand.Reader is used.
+func NewRandomGenerator(rand io.Reader) RandomGenerator {
+	return RandomGenerator{rand: rand}
+}
+
+// Generate returns a fresh random redemption nonce.
+func (g RandomGenerator) Generate() ([]byte, error) {
+	r := g.rand
+	if r == nil {
+		r = rand.Reader
+	}
+	nonce := make([]byte, NonceLength)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// TimeWindowGenerator derives redemption nonces from the current time bucket,
+// so that all challenges issued within the same window share a nonce.
+type TimeWindowGenerator struct {
+	key    []byte
+	window time.Duration
+}
+
+// NewTimeWindowGenerator creates a TimeWindowGenerator with the given HMAC key
+// and window duration.
+func NewTimeWindowGenerator(key []byte, window time.Duration) (TimeWindowGenerator, error) {
+	if len(key) == 0 {
+		return TimeWindowGenerator{}, fmt.Errorf("empty redemption context key")
+	}
+	if window <= 0 {
+		return TimeWindowGenerator{}, fmt.Errorf("invalid redemption context window: %s", window)
+	}
+	return TimeWindowGenerator{
+		key:    key,
+		window: window,
+	}, nil
+}
+
+// timeBucket returns the index of the window of the given duration that
+// contains t, counting from the Unix epoch.
+func timeBucket(t time.Time, window time.Duration) uint64 {
+	return uint64(t.UnixNano() / int64(window))
+}
+
+func (g TimeWindowGenerator) nonceForBucket(bucket uint64) []byte {
+	var bucketEnc [8]byte
+	binary.BigEndian.PutUint64(bucketEnc[:], bucket)
+
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write(labelTimeWindow)
+	mac.Write(bucketEnc[:])
+	return mac.Sum(nil)
+}
+
+// Generate returns the redemption nonce for the window containing t.
+func (g TimeWindowGenerator) Generate(t time.Time) []byte {
+	return g.nonceForBucket(timeBucket(t, g.window))
+}
+
+// Candidates returns the redemption nonces that are acceptable at time t: the
+// nonce of the current window followed by the nonce of the previous window.
+func (g TimeWindowGenerator) Candidates(t time.Time) [][]byte {
+	bucket := timeBucket(t, g.window)
+	candidates := [][]byte{g.nonceForBucket(bucket)}
+	if bucket > 0 {
+		candidates = append(candidates, g.nonceForBucket(bucket-1))
+	}
+	return candidates
+}
+
+// Verify checks that the token context corresponds to the challenge template
+// with a redemption nonce from the current or previous window at time t.
+func (g TimeWindowGenerator) Verify(token tokens.Token, template tokens.TokenChallenge, t time.Time) error {
+	return VerifyTokenContext(token.Context, template, g.Candidates(t))
+}
+
+// SessionGenerator derives redemption nonces from a session identifier, so
+// that all challenges issued within a session share a nonce.
+type SessionGenerator struct {
+	key []byte
+}
+
+// NewSessionGenerator creates a SessionGenerator with the given HMAC key.
+func NewSessionGenerator(key []byte) (SessionGenerator, error) {
+	if len(key) == 0 {
+		return SessionGenerator{}, fmt.Errorf("empty redemption context key")
+	}
+	return SessionGenerator{key: key}, nil
+}
+
+// Generate returns the redemption nonce for the given session.
+func (g SessionGenerator) Generate(sessionID []byte) []byte {
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write(labelSession)
+	mac.Write(sessionID)
+	return mac.Sum(nil)
+}
+
+// Verify checks that the token context corresponds to the challenge template
+// with the redemption nonce of the given session.
+func (g SessionGenerator) Verify(token tokens.Token, template tokens.TokenChallenge, sessionID []byte) error {
+	return VerifyTokenContext(token.Context, template, [][]byte{g.Generate(sessionID)})
+}
+
+// ComputeTokenContext returns the token context for a challenge, i.e., the
+// SHA-256 digest of its encoding.
+func ComputeTokenContext(challenge tokens.TokenChallenge) ([]byte, error) {
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	context := sha256.Sum256(challengeEnc)
+	return context[:], nil
+}
+
+// VerifyTokenContext checks that tokenContext matches the context of template
+// with one of the candidate redemption nonces. The RedemptionNonce of template
+// is ignored.
+func VerifyTokenContext(tokenContext []byte, template tokens.TokenChallenge, candidates [][]byte) error {
+	for _, nonce := range candidates {
+		challenge := template
+		challenge.RedemptionNonce = nonce
+		context, err := ComputeTokenContext(challenge)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare(context, tokenContext) == 1 {
+			return nil
+		}
+	}
+	return tokens.Errorf(tokens.ErrReplay, "token context does not match any acceptable challenge")
+}
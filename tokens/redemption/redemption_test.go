@@ -0,0 +1,138 @@
+package redemption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+func createToken(t *testing.T, challenge tokens.TokenChallenge) tokens.Token {
+	context, err := ComputeTokenContext(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.Token{
+		TokenType: challenge.TokenType,
+		Nonce:     make([]byte, 32),
+		Context:   context,
+		KeyID:     make([]byte, 32),
+	}
+}
+
+func testTemplate() tokens.TokenChallenge {
+	return tokens.TokenChallenge{
+		TokenType:  0x0002,
+		IssuerName: "issuer.example",
+		OriginInfo: []string{"origin.example"},
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+	g := NewRandomGenerator(nil)
+	nonce1, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce2, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nonce1) != NonceLength || len(nonce2) != NonceLength {
+		t.Fatal("Invalid nonce length")
+	}
+	if bytes.Equal(nonce1, nonce2) {
+		t.Fatal("Random nonces should differ")
+	}
+
+	challenge := testTemplate()
+	challenge.RedemptionNonce = nonce1
+	token := createToken(t, challenge)
+	if err := VerifyTokenContext(token.Context, testTemplate(), [][]byte{nonce2, nonce1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTokenContext(token.Context, testTemplate(), [][]byte{nonce2}); !errors.Is(err, tokens.ErrReplay) {
+		t.Fatal("Expected verification with unknown nonce to fail")
+	}
+}
+
+func TestTimeWindowGenerator(t *testing.T) {
+	window := 5 * time.Minute
+	g, err := NewTimeWindowGenerator([]byte("test key"), window)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuedAt := time.Unix(1700000000, 0).Truncate(window)
+	challenge := testTemplate()
+	challenge.RedemptionNonce = g.Generate(issuedAt)
+	if len(challenge.RedemptionNonce) != NonceLength {
+		t.Fatal("Invalid nonce length")
+	}
+	if !bytes.Equal(challenge.RedemptionNonce, g.Generate(issuedAt.Add(window-time.Second))) {
+		t.Fatal("Nonce should be stable within a window")
+	}
+	token := createToken(t, challenge)
+
+	// Same window
+	if err := g.Verify(token, testTemplate(), issuedAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// Next window
+	if err := g.Verify(token, testTemplate(), issuedAt.Add(window+time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// Expired
+	if err := g.Verify(token, testTemplate(), issuedAt.Add(2*window+time.Minute)); err == nil {
+		t.Fatal("Expected verification in an expired window to fail")
+	}
+
+	// Different challenge parameters
+	otherTemplate := testTemplate()
+	otherTemplate.OriginInfo = []string{"other.example"}
+	if err := g.Verify(token, otherTemplate, issuedAt); err == nil {
+		t.Fatal("Expected verification with a different origin to fail")
+	}
+
+	// Different key
+	other, err := NewTimeWindowGenerator([]byte("other key"), window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Verify(token, testTemplate(), issuedAt); err == nil {
+		t.Fatal("Expected verification with a different key to fail")
+	}
+}
+
+func TestSessionGenerator(t *testing.T) {
+	g, err := NewSessionGenerator([]byte("test key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID := []byte("session 1")
+	challenge := testTemplate()
+	challenge.RedemptionNonce = g.Generate(sessionID)
+	token := createToken(t, challenge)
+
+	if err := g.Verify(token, testTemplate(), sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Verify(token, testTemplate(), []byte("session 2")); err == nil {
+		t.Fatal("Expected verification with a different session to fail")
+	}
+}
+
+func TestGeneratorConfiguration(t *testing.T) {
+	if _, err := NewTimeWindowGenerator(nil, time.Minute); err == nil {
+		t.Fatal("Expected empty key to be rejected")
+	}
+	if _, err := NewTimeWindowGenerator([]byte("key"), 0); err == nil {
+		t.Fatal("Expected empty window to be rejected")
+	}
+	if _, err := NewSessionGenerator(nil); err == nil {
+		t.Fatal("Expected empty key to be rejected")
+	}
+}
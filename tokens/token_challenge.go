@@ -3,12 +3,18 @@ package tokens
 import (
 	"bytes"
 	"fmt"
-	"reflect"
 	"strings"
 
 	"golang.org/x/crypto/cryptobyte"
 )
 
+const (
+	maxIssuerNameLength   = 0xFFFF
+	maxOriginInfoLength   = 0xFFFF
+	redemptionNonceLength = 32
+	originInfoSeparator   = ","
+)
+
 //	struct {
 //	    uint16_t token_type;
 //	    opaque issuer_name<1..2^16-1>;
@@ -22,17 +28,100 @@ type TokenChallenge struct {
 	OriginInfo      []string
 }
 
+// NewTokenChallenge creates a TokenChallenge from the given parameters, copying
+// the redemption nonce and origin list, and checks that the result can be
+// encoded and decoded without loss.
+func NewTokenChallenge(tokenType uint16, issuerName string, redemptionNonce []byte, originInfo []string) (TokenChallenge, error) {
+	challenge := TokenChallenge{
+		TokenType:  tokenType,
+		IssuerName: issuerName,
+	}
+	if len(redemptionNonce) > 0 {
+		challenge.RedemptionNonce = make([]byte, len(redemptionNonce))
+		copy(challenge.RedemptionNonce, redemptionNonce)
+	}
+	if len(originInfo) > 0 {
+		challenge.OriginInfo = make([]string, len(originInfo))
+		copy(challenge.OriginInfo, originInfo)
+	}
+
+	if err := challenge.Validate(); err != nil {
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "%w", err)
+	}
+
+	return challenge, nil
+}
+
+// Validate checks that the challenge respects the limits of the TokenChallenge
+// encoding: a non-empty issuer name of at most 2^16-1 bytes, a redemption nonce
+// that is either empty or 32 bytes long, and origin names that are non-empty,
+// printable ASCII strings without whitespace or commas.
+func (c TokenChallenge) Validate() error {
+	if len(c.IssuerName) == 0 {
+		return fmt.Errorf("invalid TokenChallenge: empty issuer name")
+	}
+	if len(c.IssuerName) > maxIssuerNameLength {
+		return fmt.Errorf("invalid TokenChallenge: issuer name too long (%d bytes)", len(c.IssuerName))
+	}
+
+	if len(c.RedemptionNonce) != 0 && len(c.RedemptionNonce) != redemptionNonceLength {
+		return fmt.Errorf("invalid TokenChallenge: redemption nonce must be empty or %d bytes, got %d", redemptionNonceLength, len(c.RedemptionNonce))
+	}
+
+	for _, originName := range c.OriginInfo {
+		if err := validateOriginName(originName); err != nil {
+			return err
+		}
+	}
+	if originInfoLen := len(strings.Join(c.OriginInfo, originInfoSeparator)); originInfoLen > maxOriginInfoLength {
+		return fmt.Errorf("invalid TokenChallenge: origin info too long (%d bytes)", originInfoLen)
+	}
+
+	return nil
+}
+
+func validateOriginName(originName string) error {
+	if len(originName) == 0 {
+		return fmt.Errorf("invalid TokenChallenge: empty origin name")
+	}
+	for i := 0; i < len(originName); i++ {
+		c := originName[i]
+		if c <= 0x20 || c >= 0x7F || c == originInfoSeparator[0] {
+			return fmt.Errorf("invalid TokenChallenge: invalid character %q in origin name %q", c, originName)
+		}
+	}
+	return nil
+}
+
+// splitOriginInfo parses the comma-separated origin_info field, mapping the
+// empty string to an empty origin list.
+func splitOriginInfo(originInfo string) []string {
+	if len(originInfo) == 0 {
+		return nil
+	}
+	return strings.Split(originInfo, originInfoSeparator)
+}
+
 func (c TokenChallenge) Equals(o TokenChallenge) bool {
-	if c.TokenType == o.TokenType &&
-		c.IssuerName == o.IssuerName &&
-		bytes.Equal(c.RedemptionNonce, o.RedemptionNonce) &&
-		reflect.DeepEqual(c.OriginInfo, o.OriginInfo) {
-		return true
+	if c.TokenType != o.TokenType ||
+		c.IssuerName != o.IssuerName ||
+		!bytes.Equal(c.RedemptionNonce, o.RedemptionNonce) ||
+		len(c.OriginInfo) != len(o.OriginInfo) {
+		return false
+	}
+	for i := range c.OriginInfo {
+		if c.OriginInfo[i] != o.OriginInfo[i] {
+			return false
+		}
 	}
-	return false
+	return true
 }
 
-func (c TokenChallenge) Marshal() []byte {
+func (c TokenChallenge) Marshal() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, Errorf(ErrMalformedEncoding, "%w", err)
+	}
+
 	b := cryptobyte.NewBuilder(nil)
 	b.AddUint16(c.TokenType)
 	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
@@ -42,9 +131,9 @@ func (c TokenChallenge) Marshal() []byte {
 		b.AddBytes(c.RedemptionNonce)
 	})
 	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
-		b.AddBytes([]byte(strings.Join(c.OriginInfo, ",")))
+		b.AddBytes([]byte(strings.Join(c.OriginInfo, originInfoSeparator)))
 	})
-	return b.BytesOrPanic()
+	return b.Bytes()
 }
 
 func UnmarshalTokenChallenge(data []byte) (TokenChallenge, error) {
@@ -53,27 +142,35 @@ func UnmarshalTokenChallenge(data []byte) (TokenChallenge, error) {
 	challenge := TokenChallenge{}
 
 	if !s.ReadUint16(&challenge.TokenType) {
-		return TokenChallenge{}, fmt.Errorf("invalid TokenChallenge encoding")
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "invalid TokenChallenge encoding")
 	}
 
 	var issuerName cryptobyte.String
 	if !s.ReadUint16LengthPrefixed(&issuerName) || issuerName.Empty() {
-		return TokenChallenge{}, fmt.Errorf("invalid TokenChallenge encoding")
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "invalid TokenChallenge encoding")
 	}
 	challenge.IssuerName = string(issuerName)
 
 	var redemptionNonce cryptobyte.String
 	if !s.ReadUint8LengthPrefixed(&redemptionNonce) {
-		return TokenChallenge{}, fmt.Errorf("invalid TokenChallenge encoding")
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "invalid TokenChallenge encoding")
 	}
 	challenge.RedemptionNonce = make([]byte, len(redemptionNonce))
 	copy(challenge.RedemptionNonce, redemptionNonce)
 
 	var originInfo cryptobyte.String
 	if !s.ReadUint16LengthPrefixed(&originInfo) {
-		return TokenChallenge{}, fmt.Errorf("invalid TokenRequest encoding")
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "invalid TokenChallenge encoding")
+	}
+	challenge.OriginInfo = splitOriginInfo(string(originInfo))
+
+	if !s.Empty() {
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "invalid TokenChallenge encoding: trailing data")
+	}
+
+	if err := challenge.Validate(); err != nil {
+		return TokenChallenge{}, Errorf(ErrMalformedEncoding, "%w", err)
 	}
-	challenge.OriginInfo = strings.Split(string(originInfo), ",")
 
 	return challenge, nil
 }
@@ -9,6 +9,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -84,7 +85,10 @@ func TestTokenChallengeMarshal(t *testing.T) {
 	rand.Reader.Read(context)
 
 	challenge := createTokenChallenge(0x0003, context, "issuer.example", []string{"origin.example"})
-	challengeEnc := challenge.Marshal()
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
 	recoveredChallenge, err := UnmarshalTokenChallenge(challengeEnc)
 	if err != nil {
 		t.Fatal(err)
@@ -101,12 +105,104 @@ func TestTokenChallengeUnmarshal(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err = UnmarshalTokenChallenge(challengeBlob)
+	// The encoded challenge carries one trailing byte after origin_info
+	if _, err := UnmarshalTokenChallenge(challengeBlob); !errors.Is(err, ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalTokenChallenge = %v, expected trailing data to be rejected", err)
+	}
+	_, err = UnmarshalTokenChallenge(challengeBlob[:len(challengeBlob)-1])
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestTokenChallengeEmptyOriginInfo(t *testing.T) {
+	challenge, err := NewTokenChallenge(0x0002, "issuer.example", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveredChallenge, err := UnmarshalTokenChallenge(challengeEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recoveredChallenge.OriginInfo) != 0 {
+		t.Fatalf("Expected empty origin info, got %q", recoveredChallenge.OriginInfo)
+	}
+	if !challenge.Equals(recoveredChallenge) {
+		t.Fatal("Failed to deserialize challenge")
+	}
+}
+
+func TestTokenChallengeMultipleOrigins(t *testing.T) {
+	challenge, err := NewTokenChallenge(0x0002, "issuer.example", nil, []string{"foo.example", "bar.example:8443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveredChallenge, err := UnmarshalTokenChallenge(challengeEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !challenge.Equals(recoveredChallenge) {
+		t.Fatal("Failed to deserialize challenge")
+	}
+}
+
+func TestTokenChallengeValidate(t *testing.T) {
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	var invalidChallenges = []struct {
+		name      string
+		challenge TokenChallenge
+	}{
+		{"empty issuer name", TokenChallenge{TokenType: 0x0002}},
+		{"long issuer name", TokenChallenge{TokenType: 0x0002, IssuerName: strings.Repeat("a", 0x10000)}},
+		{"short nonce", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", RedemptionNonce: nonce[:16]}},
+		{"long nonce", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", RedemptionNonce: append(nonce, 0x00)}},
+		{"comma in origin name", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{"foo.example,bar.example"}}},
+		{"empty origin name", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{""}}},
+		{"space in origin name", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{"origin example"}}},
+		{"non-ASCII origin name", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{"\xc3\xa9.example"}}},
+		{"long origin info", TokenChallenge{TokenType: 0x0002, IssuerName: "issuer.example", OriginInfo: []string{strings.Repeat("a", 0x8000), strings.Repeat("b", 0x8000)}}},
+	}
+
+	for _, c := range invalidChallenges {
+		if err := c.challenge.Validate(); err == nil {
+			t.Fatalf("Expected validation failure for %s", c.name)
+		}
+		if _, err := c.challenge.Marshal(); !errors.Is(err, ErrMalformedEncoding) {
+			t.Fatalf("Expected encoding failure for %s", c.name)
+		}
+		if _, err := NewTokenChallenge(c.challenge.TokenType, c.challenge.IssuerName, c.challenge.RedemptionNonce, c.challenge.OriginInfo); err == nil {
+			t.Fatalf("Expected construction failure for %s", c.name)
+		}
+	}
+
+	challenge, err := NewTokenChallenge(0x0002, "issuer.example", nonce, []string{"origin.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := challenge.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenChallengeUnmarshalInvalidNonce(t *testing.T) {
+	b := []byte{0x00, 0x02, 0x00, 0x01, 'a', 0x10}
+	b = append(b, make([]byte, 16)...)
+	b = append(b, 0x00, 0x00)
+	if _, err := UnmarshalTokenChallenge(b); err == nil {
+		t.Fatal("Expected decoding failure for a 16-byte redemption nonce")
+	}
+}
+
 func TestBase64(t *testing.T) {
 	v := "MIICUjA9BgkqhkiG9w0BAQowMKANMAsGCWCGSAFlAwQCAqEaMBgGCSqGSIb3DQEBCDALBglghkgBZQMEAgKiAwIBMAOCAg8AMIICCgKCAgEAqf6VmRe_ws8ZWvoxAZ847LQpleN6I0daqdxBY61GVim4bRv9g6xAxaZWcKpu58TbWpDVU6sQw7l58W-C1jmJvobGqtZF4WHqtvqdQZdSbkxbpcgwVJsGuwyJjMNs7koEUfB50Tb2XgdmnuFS0gAxRxVIZghxPkfjgBnT0cQpNDxLf-uO9C-NnoonU4rhoPhiA1IdlApOk2mJuks335nfT4fyAcPbMOsd__XL0dSs_T5s4lxkuKo12p0mURg_Zs1OEucgGxDpVrRA-kZ6iFQKIJNZ_fZ396Yok8jAvRyhEBJbqyhApFG9d3v2-3CmGUuJgyzcb2lI0y86EuCf9A_DR2FK2aV0_fxfRiXji1WER-LTUsM-SqwYYhouFFXIHrXUsI4H5RiDE_4EEAqh4duhaenTne7SDl8Talr2IK-gXFffdkI6g6X2xDg159xT-LeSWE0tk_lFAJkS3GhqZVfB7ikZtpxsJs2pIf26XpRPBydhQgTY2rKx9KuMJoQStolRNAv7b_Z8CfrJj6ZMWaodntmZ0TZ6p6mIq5kKpgsx8kDf125Bwxv0XL-sDO2vhWzCvK6dLWefxrm8aj_F5tz0aL8asgLCr9aFtNbQl96TzcEJcYGCq5BbsqeoIBt2W6nfr3LDHb22zmiiyaH6Pb5eTfDjWTSPEfJ8mjQOZsiD1GsCAwEAAQ"
 	_, err := base64.RawURLEncoding.DecodeString(v)
@@ -190,7 +286,7 @@ func (etv *tokenTestVector) UnmarshalJSON(data []byte) error {
 	etv.tokenType = uint16(tokenType)
 	etv.issuerName = string(util.MustUnhex(nil, raw.IssuerName))
 	etv.redemptionContext = util.MustUnhex(nil, raw.RedemptionContext)
-	etv.originInfo = strings.Split(string(util.MustUnhex(nil, raw.OriginInfo)), ",")
+	etv.originInfo = splitOriginInfo(string(util.MustUnhex(nil, raw.OriginInfo)))
 	etv.nonce = util.MustUnhex(nil, raw.Nonce)
 	etv.tokenKeyId = util.MustUnhex(nil, raw.TokenKeyId)
 	etv.tokenAuthenticatorInput = util.MustUnhex(nil, raw.TokenAuthenticatorInput)
@@ -224,7 +320,11 @@ func generateTokenTestVector(t *testing.T, tokenType uint16, redemptionContext [
 
 	tokenKeyID := sha256.Sum256(util.MustMarshalPublicKey(&tokenSigningKey.PublicKey))
 	challenge := createTokenChallenge(tokenType, redemptionContext, issuerName, originInfo)
-	context := sha256.Sum256(challenge.Marshal())
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		return tokenTestVector{}, err
+	}
+	context := sha256.Sum256(challengeEnc)
 	token := Token{
 		TokenType:     tokenType,
 		Nonce:         nonce,
@@ -263,7 +363,11 @@ func verifyTokenTestVector(t *testing.T, vector tokenTestVector) {
 	}
 
 	challenge := createTokenChallenge(vector.tokenType, vector.redemptionContext, vector.issuerName, vector.originInfo)
-	context := sha256.Sum256(challenge.Marshal())
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := sha256.Sum256(challengeEnc)
 	token := Token{
 		TokenType:     vector.tokenType,
 		Nonce:         vector.nonce,
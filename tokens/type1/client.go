@@ -28,17 +28,29 @@ func (s BasicPrivateTokenRequestState) Request() *BasicPrivateTokenRequest {
 	return s.request
 }
 
+// Blind returns the encoded OPRF blind used to create the request, which can
+// be passed to CreateTokenRequestWithBlind to reproduce it.
+func (s BasicPrivateTokenRequestState) Blind() ([]byte, error) {
+	blinds := s.verifier.CopyBlinds()
+	return blinds[0].MarshalBinary()
+}
+
 func (s BasicPrivateTokenRequestState) FinalizeToken(tokenResponseEnc []byte) (tokens.Token, error) {
+	elementLength := int(group.P384.Params().CompressedElementLength)
+	if len(tokenResponseEnc) < elementLength {
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid token response length %d", len(tokenResponseEnc))
+	}
+
 	evaluatedElement := group.P384.NewElement()
-	err := evaluatedElement.UnmarshalBinary(tokenResponseEnc[:group.P384.Params().CompressedElementLength])
+	err := evaluatedElement.UnmarshalBinary(tokenResponseEnc[:elementLength])
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid token response element: %w", err)
 	}
 
 	proof := new(dleq.Proof)
-	err = proof.UnmarshalBinary(group.P384, tokenResponseEnc[group.P384.Params().CompressedElementLength:])
+	err = proof.UnmarshalBinary(group.P384, tokenResponseEnc[elementLength:])
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid token response proof: %w", err)
 	}
 
 	evaluation := &oprf.Evaluation{
@@ -47,7 +59,7 @@ func (s BasicPrivateTokenRequestState) FinalizeToken(tokenResponseEnc []byte) (t
 	}
 	outputs, err := s.client.Finalize(s.verifier, evaluation)
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	tokenData := append(s.tokenInput, outputs[0]...)
@@ -2,8 +2,8 @@ package type1
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
-	"fmt"
 
 	"github.com/cloudflare/circl/group"
 	"github.com/cloudflare/circl/oprf"
@@ -12,6 +12,7 @@ import (
 
 type BasicPrivateIssuer struct {
 	tokenKey *oprf.PrivateKey
+	observer tokens.Observer
 }
 
 func NewBasicPrivateIssuer(key *oprf.PrivateKey) *BasicPrivateIssuer {
@@ -33,19 +34,49 @@ func (i *BasicPrivateIssuer) TokenKeyID() []byte {
 	return keyID[:]
 }
 
+// SetObserver sets the observer notified of each call to Evaluate.
+func (i *BasicPrivateIssuer) SetObserver(observer tokens.Observer) {
+	i.observer = observer
+}
+
 func (i BasicPrivateIssuer) Evaluate(req *BasicPrivateTokenRequest) ([]byte, error) {
+	return i.EvaluateContext(context.Background(), req)
+}
+
+// EvaluateContext is like Evaluate, but returns ctx.Err() if ctx is done
+// before the OPRF evaluation starts.
+func (i BasicPrivateIssuer) EvaluateContext(ctx context.Context, req *BasicPrivateTokenRequest) ([]byte, error) {
+	tokenKeyID := i.TokenKeyID()
+	done := tokens.ObserveRequest(i.observer, tokens.RequestInfo{
+		Operation: tokens.OperationEvaluate,
+		TokenType: BasicPrivateTokenType,
+		KeyID:     tokenKeyID,
+	})
+	tokenResponse, err := i.evaluate(ctx, tokenKeyID, req)
+	done(err)
+	return tokenResponse, err
+}
+
+func (i BasicPrivateIssuer) evaluate(ctx context.Context, tokenKeyID []byte, req *BasicPrivateTokenRequest) ([]byte, error) {
+	if req.TokenKeyID != tokenKeyID[len(tokenKeyID)-1] {
+		return nil, tokens.Errorf(tokens.ErrUnknownKey, "unknown token key ID %02x", req.TokenKeyID)
+	}
+
 	server := oprf.NewVerifiableServer(oprf.SuiteP384, i.tokenKey)
 
 	e := group.P384.NewElement()
 	err := e.UnmarshalBinary(req.BlindedReq)
 	if err != nil {
-		return nil, err
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blinded element: %w", err)
 	}
 	evalRequest := &oprf.EvaluationRequest{
 		Elements: []oprf.Blinded{e},
 	}
 
 	// Evaluate the input
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	evaluation, err := server.Evaluate(evalRequest)
 	if err != nil {
 		return nil, err
@@ -67,6 +98,10 @@ func (i BasicPrivateIssuer) Evaluate(req *BasicPrivateTokenRequest) ([]byte, err
 }
 
 func (i BasicPrivateIssuer) Verify(token tokens.Token) error {
+	if !bytes.Equal(token.KeyID, i.TokenKeyID()) {
+		return tokens.Errorf(tokens.ErrUnknownKey, "unknown token key ID %x", token.KeyID)
+	}
+
 	server := oprf.NewVerifiableServer(oprf.SuiteP384, i.tokenKey)
 
 	tokenInput := token.AuthenticatorInput()
@@ -75,8 +110,20 @@ func (i BasicPrivateIssuer) Verify(token tokens.Token) error {
 		return err
 	}
 	if !bytes.Equal(output, token.Authenticator) {
-		return fmt.Errorf("token authentication mismatch")
+		return tokens.Errorf(tokens.ErrSignatureInvalid, "token authentication mismatch")
 	}
 
 	return nil
 }
+
+// VerifyForOrigin checks that token was issued for challenge, that the
+// challenge allows redemption at origin, and that the token is authentic.
+func (i BasicPrivateIssuer) VerifyForOrigin(token tokens.Token, challenge tokens.TokenChallenge, origin string) error {
+	if token.TokenType != BasicPrivateTokenType {
+		return tokens.Errorf(tokens.ErrUnexpectedTokenType, "invalid token type: %04x", token.TokenType)
+	}
+	if err := tokens.VerifyTokenChallenge(token, challenge, origin); err != nil {
+		return err
+	}
+	return i.Verify(token)
+}
@@ -1,8 +1,6 @@
 package type1
 
 import (
-	"fmt"
-
 	"github.com/cloudflare/pat-go/tokens"
 	"golang.org/x/crypto/cryptobyte"
 )
@@ -16,7 +14,7 @@ func UnmarshalPrivateToken(data []byte) (tokens.Token, error) {
 		!s.ReadBytes(&token.Context, 32) ||
 		!s.ReadBytes(&token.KeyID, 32) ||
 		!s.ReadBytes(&token.Authenticator, 48) {
-		return tokens.Token{}, fmt.Errorf("invalid Token encoding")
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid Token encoding")
 	}
 
 	return token, nil
@@ -3,6 +3,7 @@ package type1
 import (
 	"bytes"
 
+	"github.com/cloudflare/pat-go/tokens"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -13,7 +14,7 @@ var (
 type BasicPrivateTokenRequest struct {
 	raw        []byte
 	TokenKeyID uint8
-	BlindedReq []byte // 48 bytes
+	BlindedReq []byte // 49 bytes, a compressed P-384 element
 }
 
 func (r BasicPrivateTokenRequest) Type() uint16 {
@@ -49,9 +50,20 @@ func (r *BasicPrivateTokenRequest) Unmarshal(data []byte) bool {
 	if !s.ReadUint16(&tokenType) ||
 		tokenType != BasicPrivateTokenType ||
 		!s.ReadUint8(&r.TokenKeyID) ||
-		!s.ReadBytes(&r.BlindedReq, 48) {
+		!s.ReadBytes(&r.BlindedReq, 49) ||
+		!s.Empty() {
 		return false
 	}
 
 	return true
 }
+
+// UnmarshalBasicPrivateTokenRequest decodes a token request, returning an
+// error matching tokens.ErrMalformedEncoding if it is invalid.
+func UnmarshalBasicPrivateTokenRequest(data []byte) (BasicPrivateTokenRequest, error) {
+	var r BasicPrivateTokenRequest
+	if !r.Unmarshal(data) {
+		return BasicPrivateTokenRequest{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid BasicPrivateTokenRequest encoding")
+	}
+	return r, nil
+}
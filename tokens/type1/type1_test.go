@@ -2,9 +2,11 @@ package type1
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -71,6 +73,185 @@ func TestBasicPrivateIssuanceRoundTrip(t *testing.T) {
 	}
 }
 
+func TestBasicPrivateTokenRequestEncoding(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteP384, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBasicPrivateIssuer(tokenKey)
+	client := BasicPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestEnc := requestState.Request().Marshal()
+
+	var request BasicPrivateTokenRequest
+	if !request.Unmarshal(requestEnc) {
+		t.Fatal("failed to decode token request")
+	}
+	if !request.Equal(*requestState.Request()) || !bytes.Equal(request.Marshal(), requestEnc) {
+		t.Fatal("token request encoding mismatch")
+	}
+	if _, err := issuer.Evaluate(&request); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Unmarshal(requestEnc[:len(requestEnc)-1]) {
+		t.Fatal("decoded a truncated token request")
+	}
+	if request.Unmarshal(append(requestEnc, 0x00)) {
+		t.Fatal("decoded a token request with trailing data")
+	}
+}
+
+func TestBasicPrivateErrorKinds(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteP384, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBasicPrivateIssuer(tokenKey)
+	client := BasicPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestEnc := requestState.Request().Marshal()
+
+	if _, err := UnmarshalBasicPrivateTokenRequest(requestEnc[:len(requestEnc)-1]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalBasicPrivateTokenRequest = %v, expected a malformed encoding error", err)
+	}
+	request, err := UnmarshalBasicPrivateTokenRequest(requestEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.TokenKeyID ^= 0xFF
+	if _, err := issuer.Evaluate(&request); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Evaluate = %v, expected an unknown key error", err)
+	}
+
+	tokenResponse, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeToken(tokenResponse[:10]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("FinalizeToken = %v, expected a malformed encoding error", err)
+	}
+	token, err := requestState.FinalizeToken(tokenResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UnmarshalPrivateToken(token.Marshal()[:10]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalPrivateToken = %v, expected a malformed encoding error", err)
+	}
+
+	token.Authenticator[0] ^= 0xFF
+	if err := issuer.Verify(token); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Fatalf("Verify = %v, expected an invalid signature error", err)
+	}
+	token.KeyID = make([]byte, 32)
+	if err := issuer.Verify(token); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Verify = %v, expected an unknown key error", err)
+	}
+}
+
+func TestBasicPrivateEvaluateContext(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteP384, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBasicPrivateIssuer(tokenKey)
+	client := BasicPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tokenResponse, err := issuer.EvaluateContext(ctx, requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeToken(tokenResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if _, err := issuer.EvaluateContext(ctx, requestState.Request()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EvaluateContext = %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestBasicPrivateVerifyForOrigin(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteP384, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := NewBasicPrivateIssuer(tokenKey)
+	client := BasicPrivateClient{}
+
+	tokenChallenge := createTokenChallenge(BasicPrivateTokenType, nil, "issuer.example", []string{"foo.example", "bar.example"})
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindedSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = issuer.VerifyForOrigin(token, tokenChallenge, "Bar.Example:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = issuer.VerifyForOrigin(token, tokenChallenge, "baz.example")
+	if err == nil {
+		t.Fatal("Expected verification at an unlisted origin to fail")
+	}
+
+	token.TokenType = 0x0002
+	err = issuer.VerifyForOrigin(token, tokenChallenge, "bar.example")
+	if !errors.Is(err, tokens.ErrUnexpectedTokenType) {
+		t.Fatalf("VerifyForOrigin = %v, expected an unexpected token type error", err)
+	}
+}
+
 // /////
 // Basic issuance test vector
 type rawBasicPrivateIssuanceTestVector struct {
@@ -148,7 +329,10 @@ func (etv *BasicPrivateIssuanceTestVector) UnmarshalJSON(data []byte) error {
 }
 
 func generateBasicPrivateIssuanceBlindingTestVector(t *testing.T, client *BasicPrivateClient, issuer *BasicPrivateIssuer, tokenChallenge tokens.TokenChallenge) BasicPrivateIssuanceTestVector {
-	challenge := tokenChallenge.Marshal()
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	nonce := make([]byte, 32)
 	rand.Reader.Read(nonce)
@@ -239,7 +423,7 @@ func TestVectorGenerateBasicPrivateIssuance(t *testing.T) {
 	challenges := []tokens.TokenChallenge{
 		createTokenChallenge(BasicPrivateTokenType, redemptionContext, "issuer.example", []string{"origin.example"}),
 		createTokenChallenge(BasicPrivateTokenType, nil, "issuer.example", []string{"origin.example"}),
-		createTokenChallenge(BasicPrivateTokenType, nil, "issuer.example", []string{"foo.example,bar.example"}),
+		createTokenChallenge(BasicPrivateTokenType, nil, "issuer.example", []string{"foo.example", "bar.example"}),
 		createTokenChallenge(BasicPrivateTokenType, nil, "issuer.example", []string{}),
 		createTokenChallenge(BasicPrivateTokenType, redemptionContext, "issuer.example", []string{}),
 	}
@@ -247,7 +431,10 @@ func TestVectorGenerateBasicPrivateIssuance(t *testing.T) {
 	vectors := make([]BasicPrivateIssuanceTestVector, len(challenges))
 	for i := 0; i < len(challenges); i++ {
 		challenge := challenges[i]
-		challengeEnc := challenge.Marshal()
+		challengeEnc, err := challenge.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		tokenKey, err := oprf.DeriveKey(oprf.SuiteP384, oprf.VerifiableMode, []byte("fixed seed"), challengeEnc)
 		if err != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/cloudflare/circl/blindsign"
 	"github.com/cloudflare/circl/blindsign/blindrsa"
 	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/util"
 )
 
 type BasicPublicClient struct {
@@ -30,14 +31,28 @@ func (s BasicPublicTokenRequestState) Request() *BasicPublicTokenRequest {
 	return s.request
 }
 
+// Blind returns the RSA blind used to create the request, which can be passed
+// to CreateTokenRequestWithBlind along with Salt to reproduce it.
+func (s BasicPublicTokenRequestState) Blind() []byte {
+	return s.verifier.CopyBlind()
+}
+
+// Salt returns the PSS salt used to create the request.
+func (s BasicPublicTokenRequestState) Salt() []byte {
+	return s.verifier.CopySalt()
+}
+
 func (s BasicPublicTokenRequestState) FinalizeToken(blindSignature []byte) (tokens.Token, error) {
+	if len(blindSignature) != s.verificationKey.Size() {
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blind signature length %d, expected %d", len(blindSignature), s.verificationKey.Size())
+	}
 	signature, err := s.verifier.Finalize(blindSignature)
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	tokenData := append(s.tokenInput, signature...)
-	token, err := UnmarshalToken(tokenData)
+	token, err := UnmarshalTokenWithKey(tokenData, s.verificationKey)
 	if err != nil {
 		return tokens.Token{}, err
 	}
@@ -55,7 +70,7 @@ func (s BasicPublicTokenRequestState) FinalizeToken(blindSignature []byte) (toke
 		SaltLength: crypto.SHA384.Size(),
 	})
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	return token, nil
@@ -63,6 +78,9 @@ func (s BasicPublicTokenRequestState) FinalizeToken(blindSignature []byte) (toke
 
 // https://ietf-wg-privacypass.github.io/base-drafts/caw/pp-issuance/draft-ietf-privacypass-protocol.html#name-issuance-protocol-for-publi
 func (c BasicPublicClient) CreateTokenRequest(challenge, nonce []byte, tokenKeyID []byte, tokenKey *rsa.PublicKey) (BasicPublicTokenRequestState, error) {
+	if _, err := util.TokenKeySize(tokenKey); err != nil {
+		return BasicPublicTokenRequestState{}, err
+	}
 	verifier := blindrsa.NewRSAVerifier(tokenKey, crypto.SHA384)
 
 	context := sha256.Sum256(challenge)
@@ -96,6 +114,9 @@ func (c BasicPublicClient) CreateTokenRequest(challenge, nonce []byte, tokenKeyI
 }
 
 func (c BasicPublicClient) CreateTokenRequestWithBlind(challenge, nonce []byte, tokenKeyID []byte, tokenKey *rsa.PublicKey, blind, salt []byte) (BasicPublicTokenRequestState, error) {
+	if _, err := util.TokenKeySize(tokenKey); err != nil {
+		return BasicPublicTokenRequestState{}, err
+	}
 	verifier := blindrsa.NewRSAVerifier(tokenKey, crypto.SHA384)
 
 	context := sha256.Sum256(challenge)
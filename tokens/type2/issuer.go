@@ -1,15 +1,18 @@
 package type2
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/sha256"
 
 	"github.com/cloudflare/circl/blindsign/blindrsa"
+	"github.com/cloudflare/pat-go/tokens"
 	"github.com/cloudflare/pat-go/util"
 )
 
 type BasicPublicIssuer struct {
 	tokenKey *rsa.PrivateKey
+	observer tokens.Observer
 }
 
 func NewBasicPublicIssuer(key *rsa.PrivateKey) *BasicPublicIssuer {
@@ -31,7 +34,46 @@ func (i *BasicPublicIssuer) TokenKeyID() []byte {
 	return keyID[:]
 }
 
+// SetObserver sets the observer notified of each call to Evaluate.
+func (i *BasicPublicIssuer) SetObserver(observer tokens.Observer) {
+	i.observer = observer
+}
+
 func (i BasicPublicIssuer) Evaluate(req *BasicPublicTokenRequest) ([]byte, error) {
+	return i.EvaluateContext(context.Background(), req)
+}
+
+// EvaluateContext is like Evaluate, but returns ctx.Err() if ctx is done
+// before the blind signature computation starts. The computation itself
+// cannot be interrupted.
+func (i BasicPublicIssuer) EvaluateContext(ctx context.Context, req *BasicPublicTokenRequest) ([]byte, error) {
+	tokenKeyID := i.TokenKeyID()
+	done := tokens.ObserveRequest(i.observer, tokens.RequestInfo{
+		Operation: tokens.OperationEvaluate,
+		TokenType: BasicPublicTokenType,
+		KeyID:     tokenKeyID,
+	})
+	tokenResponse, err := i.evaluate(ctx, tokenKeyID, req)
+	done(err)
+	return tokenResponse, err
+}
+
+func (i BasicPublicIssuer) evaluate(ctx context.Context, tokenKeyID []byte, req *BasicPublicTokenRequest) ([]byte, error) {
+	if req.TokenKeyID != tokenKeyID[len(tokenKeyID)-1] {
+		return nil, tokens.Errorf(tokens.ErrUnknownKey, "unknown token key ID %02x", req.TokenKeyID)
+	}
+
+	nk, err := util.TokenKeySize(&i.tokenKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.BlindedReq) != nk {
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blinded message length %d, expected %d", len(req.BlindedReq), nk)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	signer := blindrsa.NewRSASigner(i.tokenKey)
 	blindSignature, err := signer.BlindSign(req.BlindedReq)
 	if err != nil {
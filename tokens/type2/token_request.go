@@ -3,6 +3,8 @@ package type2
 import (
 	"bytes"
 
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/util"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -13,7 +15,7 @@ var (
 type BasicPublicTokenRequest struct {
 	raw        []byte
 	TokenKeyID uint8
-	BlindedReq []byte // 256 bytes
+	BlindedReq []byte // Nk bytes
 }
 
 func (r BasicPublicTokenRequest) Type() uint16 {
@@ -42,6 +44,9 @@ func (r *BasicPublicTokenRequest) Marshal() []byte {
 	return r.raw
 }
 
+// Unmarshal decodes a token request whose blinded message is the size of a
+// supported token key. The issuer must check that the size matches the token
+// key identified by TokenKeyID before evaluating the request.
 func (r *BasicPublicTokenRequest) Unmarshal(data []byte) bool {
 	s := cryptobyte.String(data)
 
@@ -49,9 +54,20 @@ func (r *BasicPublicTokenRequest) Unmarshal(data []byte) bool {
 	if !s.ReadUint16(&tokenType) ||
 		tokenType != BasicPublicTokenType ||
 		!s.ReadUint8(&r.TokenKeyID) ||
-		!s.ReadBytes(&r.BlindedReq, 256) {
+		!util.IsSupportedTokenKeySize(len(s)) ||
+		!s.ReadBytes(&r.BlindedReq, len(s)) {
 		return false
 	}
 
 	return true
 }
+
+// UnmarshalBasicPublicTokenRequest decodes a token request as Unmarshal does,
+// returning an error matching tokens.ErrMalformedEncoding if it is invalid.
+func UnmarshalBasicPublicTokenRequest(data []byte) (BasicPublicTokenRequest, error) {
+	var r BasicPublicTokenRequest
+	if !r.Unmarshal(data) {
+		return BasicPublicTokenRequest{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid BasicPublicTokenRequest encoding")
+	}
+	return r, nil
+}
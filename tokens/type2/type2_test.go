@@ -2,6 +2,7 @@ package type2
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
@@ -10,10 +11,13 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/cryptobyte"
 	"golang.org/x/crypto/hkdf"
@@ -103,7 +107,10 @@ func TestBasicPublicIssuanceRoundTrip(t *testing.T) {
 	client := BasicPublicClient{}
 
 	tokenChallenge := createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"origin.example"})
-	challenge := tokenChallenge.Marshal()
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	nonce := make([]byte, 32)
 	rand.Reader.Read(nonce)
@@ -146,6 +153,321 @@ func TestBasicPublicIssuanceRoundTrip(t *testing.T) {
 	}
 }
 
+func TestBasicPublicVerifyForOrigin(t *testing.T) {
+	tokenKey := loadPrivateKey(t)
+	issuer := NewBasicPublicIssuer(tokenKey)
+	client := BasicPublicClient{}
+	verifier := NewBasicPublicVerifier(issuer.TokenKey())
+
+	tokenChallenge := createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"foo.example", "bar.example:8443"})
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindedSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifier.VerifyForOrigin(token, tokenChallenge, "bar.example:8443")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifier.VerifyForOrigin(token, tokenChallenge, "bar.example")
+	if err == nil {
+		t.Fatal("Expected verification at a different port to fail")
+	}
+
+	token.Authenticator[0] ^= 0xFF
+	err = verifier.VerifyForOrigin(token, tokenChallenge, "foo.example")
+	if err == nil {
+		t.Fatal("Expected verification of a corrupted token to fail")
+	}
+}
+
+func TestBasicPublicIssuanceKeySizes(t *testing.T) {
+	for _, bits := range []int{2048, 3072, 4096} {
+		t.Run(fmt.Sprintf("%d", bits), func(t *testing.T) {
+			var tokenKey *rsa.PrivateKey
+			if bits == 2048 {
+				tokenKey = loadPrivateKey(t)
+			} else {
+				if testing.Short() {
+					t.Skip("skipping RSA key generation in short mode")
+				}
+				var err error
+				tokenKey, err = rsa.GenerateKey(rand.Reader, bits)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			issuer := NewBasicPublicIssuer(tokenKey)
+			client := BasicPublicClient{}
+			verifier := NewBasicPublicVerifier(issuer.TokenKey())
+
+			tokenChallenge := createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"origin.example"})
+			challenge, err := tokenChallenge.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nonce := make([]byte, 32)
+			rand.Reader.Read(nonce)
+
+			requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var request BasicPublicTokenRequest
+			if !request.Unmarshal(requestState.Request().Marshal()) || !request.Equal(*requestState.Request()) {
+				t.Fatal("token request did not round trip")
+			}
+			if len(request.BlindedReq) != bits/8 {
+				t.Fatalf("unexpected blinded message length %d", len(request.BlindedReq))
+			}
+
+			blindedSignature, err := issuer.Evaluate(&request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := requestState.FinalizeToken(blindedSignature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verifier.VerifyForOrigin(token, tokenChallenge, "origin.example"); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := UnmarshalTokenWithKey(token.Marshal(), issuer.TokenKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded.Marshal(), token.Marshal()) {
+				t.Fatal("token did not round trip")
+			}
+			if _, err := UnmarshalToken(token.Marshal()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestBasicPublicIssuanceRejectsKeySizeMismatch(t *testing.T) {
+	tokenKey := loadPrivateKey(t)
+	issuer := NewBasicPublicIssuer(tokenKey)
+	client := BasicPublicClient{}
+
+	tokenChallenge := createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"origin.example"})
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A blinded message sized for a 3072-bit key is well formed on the wire,
+	// but must not be evaluated with a 2048-bit key.
+	oversized := BasicPublicTokenRequest{
+		TokenKeyID: requestState.Request().TokenKeyID,
+		BlindedReq: make([]byte, 384),
+	}
+	var request BasicPublicTokenRequest
+	if !request.Unmarshal(oversized.Marshal()) {
+		t.Fatal("failed to decode a 3072-bit token request")
+	}
+	if _, err := issuer.Evaluate(&request); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("Evaluate = %v, expected a malformed encoding error", err)
+	}
+	if request.Unmarshal(append(requestState.Request().Marshal(), 0x00)) {
+		t.Fatal("decoded a blinded message of an unsupported size")
+	}
+
+	blindedSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeToken(append(blindedSignature, make([]byte, 128)...)); err == nil {
+		t.Fatal("finalized a blind signature of the wrong size")
+	}
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	largerKey := &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 3071), E: 65537}
+	if _, err := UnmarshalTokenWithKey(token.Marshal(), largerKey); err == nil {
+		t.Fatal("decoded a token with an authenticator of the wrong size")
+	}
+	if _, err := UnmarshalToken(token.Marshal()[:len(token.Marshal())-1]); err == nil {
+		t.Fatal("decoded a token with a truncated authenticator")
+	}
+}
+
+func TestBasicPublicErrorKinds(t *testing.T) {
+	issuer := NewBasicPublicIssuer(loadPrivateKey(t))
+	client := BasicPublicClient{}
+	verifier := NewBasicPublicVerifier(issuer.TokenKey())
+
+	tokenChallenge := createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"origin.example"})
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestEnc := requestState.Request().Marshal()
+
+	if _, err := UnmarshalBasicPublicTokenRequest(requestEnc[:len(requestEnc)-1]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalBasicPublicTokenRequest = %v, expected a malformed encoding error", err)
+	}
+	if _, err := UnmarshalBasicPublicTokenRequest(append(requestEnc[:len(requestEnc):len(requestEnc)], 0x00)); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalBasicPublicTokenRequest = %v, expected a malformed encoding error", err)
+	}
+	request, err := UnmarshalBasicPublicTokenRequest(requestEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.TokenKeyID ^= 0xFF
+	if _, err := issuer.Evaluate(&request); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Evaluate = %v, expected an unknown key error", err)
+	}
+
+	blindedSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeToken(blindedSignature[1:]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("FinalizeToken = %v, expected a malformed encoding error", err)
+	}
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UnmarshalToken(token.Marshal()[:10]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalToken = %v, expected a malformed encoding error", err)
+	}
+	if err := verifier.VerifyForOrigin(token, tokenChallenge, "other.example"); !errors.Is(err, tokens.ErrUnknownOrigin) {
+		t.Fatalf("VerifyForOrigin = %v, expected an unknown origin error", err)
+	}
+	wrongType := token
+	wrongType.TokenType = 0x0001
+	if err := verifier.Verify(wrongType); !errors.Is(err, tokens.ErrUnexpectedTokenType) {
+		t.Fatalf("Verify = %v, expected an unexpected token type error", err)
+	}
+
+	token.Authenticator[0] ^= 0xFF
+	if err := verifier.Verify(token); !errors.Is(err, tokens.ErrSignatureInvalid) || !errors.Is(err, rsa.ErrVerification) {
+		t.Fatalf("Verify = %v, expected an invalid signature error", err)
+	}
+	token.KeyID = make([]byte, 32)
+	if err := verifier.Verify(token); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Verify = %v, expected an unknown key error", err)
+	}
+}
+
+func TestBasicPublicIssuerObserver(t *testing.T) {
+	issuer := NewBasicPublicIssuer(loadPrivateKey(t))
+	observer := tokens.NewMemoryObserver()
+	issuer.SetObserver(observer)
+	client := BasicPublicClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Evaluate(requestState.Request()); err != nil {
+		t.Fatal(err)
+	}
+	request := *requestState.Request()
+	request.TokenKeyID ^= 0xFF
+	if _, err := issuer.Evaluate(&request); err == nil {
+		t.Fatal("evaluated a request for an unknown key")
+	}
+
+	if n := observer.Received(tokens.OperationEvaluate, BasicPublicTokenType); n != 2 {
+		t.Fatalf("received %d requests, expected 2", n)
+	}
+	if n := observer.Completed(tokens.OperationEvaluate, BasicPublicTokenType); n != 2 {
+		t.Fatalf("completed %d requests, expected 2", n)
+	}
+	if n := observer.Failed(tokens.OperationEvaluate, BasicPublicTokenType, tokens.ErrUnknownKey); n != 1 {
+		t.Fatalf("%d unknown key failures, expected 1", n)
+	}
+	if observer.Duration(tokens.OperationEvaluate, BasicPublicTokenType) <= 0 {
+		t.Fatal("no evaluation time recorded")
+	}
+	if !bytes.Equal(observer.LastKeyID(tokens.OperationEvaluate, BasicPublicTokenType), issuer.TokenKeyID()) {
+		t.Fatal("key ID mismatch")
+	}
+}
+
+func TestBasicPublicEvaluateContext(t *testing.T) {
+	issuer := NewBasicPublicIssuer(loadPrivateKey(t))
+	client := BasicPublicClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	blindedSignature, err := issuer.EvaluateContext(ctx, requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeToken(blindedSignature); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := issuer.EvaluateContext(expired, requestState.Request()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("EvaluateContext = %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
 // /////
 // Basic issuance test vector
 type rawBasicIssuanceTestVector struct {
@@ -232,7 +554,10 @@ func (etv *basicIssuanceTestVector) UnmarshalJSON(data []byte) error {
 }
 
 func generateBasicIssuanceTestVector(t *testing.T, client *BasicPublicClient, issuer *BasicPublicIssuer, tokenChallenge tokens.TokenChallenge) basicIssuanceTestVector {
-	challenge := tokenChallenge.Marshal()
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	nonce := make([]byte, 32)
 	rand.Reader.Read(nonce)
@@ -318,7 +643,7 @@ func TestVectorGenerateBasicIssuance(t *testing.T) {
 	challenges := []tokens.TokenChallenge{
 		createTokenChallenge(BasicPublicTokenType, redemptionContext, "issuer.example", []string{"origin.example"}),
 		createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"origin.example"}),
-		createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"foo.example,bar.example"}),
+		createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{"foo.example", "bar.example"}),
 		createTokenChallenge(BasicPublicTokenType, nil, "issuer.example", []string{}),
 		createTokenChallenge(BasicPublicTokenType, redemptionContext, "issuer.example", []string{}),
 	}
@@ -0,0 +1,31 @@
+package type2
+
+import (
+	"crypto/rsa"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+// BasicPublicVerifier verifies publicly verifiable tokens using only the
+// issuer's public token key, as an origin would.
+type BasicPublicVerifier struct {
+	tokenKey *rsa.PublicKey
+}
+
+func NewBasicPublicVerifier(key *rsa.PublicKey) BasicPublicVerifier {
+	return BasicPublicVerifier{
+		tokenKey: key,
+	}
+}
+
+// Verify checks that the token was issued under the verifier's key and checks
+// the token signature.
+func (v BasicPublicVerifier) Verify(token tokens.Token) error {
+	return tokens.VerifyPublicToken(token, BasicPublicTokenType, v.tokenKey)
+}
+
+// VerifyForOrigin checks that token was issued for challenge, that the
+// challenge allows redemption at origin, and that the token signature is valid.
+func (v BasicPublicVerifier) VerifyForOrigin(token tokens.Token, challenge tokens.TokenChallenge, origin string) error {
+	return tokens.VerifyPublicTokenForOrigin(token, challenge, origin, BasicPublicTokenType, v.tokenKey)
+}
@@ -1,12 +1,11 @@
 package type3
 
 import (
-	"bytes"
+	"context"
 	"crypto"
 	"crypto/elliptic"
 	"crypto/sha512"
 	"encoding/hex"
-	"fmt"
 	"io"
 	"math/big"
 
@@ -14,6 +13,7 @@ import (
 	"golang.org/x/crypto/hkdf"
 
 	"github.com/cloudflare/pat-go/ecdsa"
+	"github.com/cloudflare/pat-go/tokens"
 )
 
 var (
@@ -28,7 +28,8 @@ type ClientState struct {
 }
 
 type RateLimitedAttester struct {
-	cache ClientStateCache
+	cache    ClientStateCache
+	observer tokens.Observer
 }
 
 type ClientStateCache interface {
@@ -36,36 +37,46 @@ type ClientStateCache interface {
 	Put(clientID string, state *ClientState)
 }
 
+// ContextClientStateCache is a ClientStateCache, e.g. one backed by a remote
+// store, whose operations take the context of the attester call that needs
+// them. The attester uses these methods instead of Get and Put when the cache
+// implements them.
+type ContextClientStateCache interface {
+	ClientStateCache
+	GetContext(ctx context.Context, clientID string) (*ClientState, bool, error)
+	PutContext(ctx context.Context, clientID string, state *ClientState) error
+}
+
 func NewRateLimitedAttester(cache ClientStateCache) *RateLimitedAttester {
 	return &RateLimitedAttester{
 		cache: cache,
 	}
 }
 
-func unmarshalPublicKey(curve elliptic.Curve, encodedKey []byte) (*ecdsa.PublicKey, error) {
-	x, y := elliptic.UnmarshalCompressed(curve, encodedKey)
-	if x == nil || y == nil {
-		return nil, fmt.Errorf("invalid public key")
-	}
-	publicKey := &ecdsa.PublicKey{
-		curve, x, y,
-	}
-	return publicKey, nil
+// SetObserver sets the observer notified of each call to VerifyRequest and
+// FinalizeIndex.
+func (a *RateLimitedAttester) SetObserver(observer tokens.Observer) {
+	a.observer = observer
 }
 
-func (a *RateLimitedAttester) innerVerifyRequest(tokenRequest RateLimitedTokenRequest) error {
-	// Deserialize the request key
-	curve := elliptic.P384()
-	requestKey, err := unmarshalPublicKey(curve, tokenRequest.RequestKey)
-	if err != nil {
-		return err
+func (a *RateLimitedAttester) getClientState(ctx context.Context, clientID string) (*ClientState, bool, error) {
+	if cache, ok := a.cache.(ContextClientStateCache); ok {
+		return cache.GetContext(ctx, clientID)
 	}
+	state, ok := a.cache.Get(clientID)
+	return state, ok, nil
+}
 
-	scalarLen := (curve.Params().Params().BitSize + 7) / 8
-	r := new(big.Int).SetBytes(tokenRequest.Signature[:scalarLen])
-	s := new(big.Int).SetBytes(tokenRequest.Signature[scalarLen:])
+func (a *RateLimitedAttester) putClientState(ctx context.Context, clientID string, state *ClientState) error {
+	if cache, ok := a.cache.(ContextClientStateCache); ok {
+		return cache.PutContext(ctx, clientID, state)
+	}
+	a.cache.Put(clientID, state)
+	return nil
+}
 
-	// Verify the request signature
+// requestDigest returns the digest of the message signed by the request key.
+func requestDigest(tokenRequest RateLimitedTokenRequest) []byte {
 	b := cryptobyte.NewBuilder(nil)
 	b.AddUint16(RateLimitedTokenType)
 	b.AddBytes(tokenRequest.RequestKey)
@@ -77,61 +88,91 @@ func (a *RateLimitedAttester) innerVerifyRequest(tokenRequest RateLimitedTokenRe
 
 	hash := sha512.New384()
 	hash.Write(message)
-	digest := hash.Sum(nil)
+	return hash.Sum(nil)
+}
 
-	valid := ecdsa.Verify(requestKey, digest, r, s)
-	if !valid {
-		return fmt.Errorf("Request signature invalid")
-	}
+// VerifyRequest checks that the request key of tokenRequest is the client key
+// blinded by blindKeyEnc and that the request is signed by it, and creates
+// state for a new client. The blind key is needed here and not a proof of the
+// blinding, because FinalizeIndex must unblind the issuer's response with it.
+func (a *RateLimitedAttester) VerifyRequest(tokenRequest RateLimitedTokenRequest, blindKeyEnc, clientKeyEnc, anonymousOrigin []byte) error {
+	return a.VerifyRequestContext(context.Background(), tokenRequest, blindKeyEnc, clientKeyEnc, anonymousOrigin)
+}
 
-	return nil
+// VerifyRequestContext is like VerifyRequest, but returns ctx.Err() if ctx is
+// done before the request signature is verified, and passes ctx to the client
+// state cache.
+func (a *RateLimitedAttester) VerifyRequestContext(ctx context.Context, tokenRequest RateLimitedTokenRequest, blindKeyEnc, clientKeyEnc, anonymousOrigin []byte) error {
+	done := tokens.ObserveRequest(a.observer, tokens.RequestInfo{
+		Operation: tokens.OperationVerifyRequest,
+		TokenType: RateLimitedTokenType,
+		KeyID:     tokenRequest.NameKeyID,
+	})
+	err := a.verifyRequest(ctx, tokenRequest, blindKeyEnc, clientKeyEnc, anonymousOrigin)
+	done(err)
+	return err
 }
 
-func (a *RateLimitedAttester) VerifyRequest(tokenRequest RateLimitedTokenRequest, blindKeyEnc, clientKeyEnc, anonymousOrigin []byte) error {
-	err := a.innerVerifyRequest(tokenRequest)
+func (a *RateLimitedAttester) verifyRequest(ctx context.Context, tokenRequest RateLimitedTokenRequest, blindKeyEnc, clientKeyEnc, anonymousOrigin []byte) error {
+	curve := elliptic.P384()
+	requestKey, err := ecdsa.UnmarshalCompressed(curve, tokenRequest.RequestKey)
 	if err != nil {
-		return nil
+		return tokens.Errorf(tokens.ErrMalformedEncoding, "invalid request key: %w", err)
 	}
 
-	curve := elliptic.P384()
-	clientKey, err := unmarshalPublicKey(curve, clientKeyEnc)
+	clientKey, err := ecdsa.UnmarshalCompressed(curve, clientKeyEnc)
 	if err != nil {
-		return err
+		return tokens.Errorf(tokens.ErrMalformedEncoding, "invalid client key: %w", err)
 	}
 
 	blindKey, err := ecdsa.CreateKey(curve, blindKeyEnc)
 	if err != nil {
-		return nil
+		return err
+	}
+
+	scalarLen := (curve.Params().Params().BitSize + 7) / 8
+	if len(tokenRequest.Signature) != 2*scalarLen {
+		return tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid request signature length")
 	}
+	r := new(big.Int).SetBytes(tokenRequest.Signature[:scalarLen])
+	s := new(big.Int).SetBytes(tokenRequest.Signature[scalarLen:])
 
+	// Verify the request signature and that the request key is the client key
+	// blinded by the client's blind
 	b := cryptobyte.NewBuilder(nil)
 	b.AddUint16(RateLimitedTokenType)
 	b.AddBytes([]byte("ClientBlind"))
-	ctx := b.BytesOrPanic()
-	blindedPublicKey, err := ecdsa.BlindPublicKeyWithContext(curve, clientKey, blindKey, ctx)
-	if err != nil {
+	blindContext := b.BytesOrPanic()
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	blindedPublicKeyEnc := elliptic.MarshalCompressed(curve, blindedPublicKey.X, blindedPublicKey.Y)
-	if !bytes.Equal(blindedPublicKeyEnc, tokenRequest.RequestKey) {
-		return fmt.Errorf("Mismatch blinded public key")
+	err = ecdsa.VerifyBlindKeySignatureWithContext(clientKey, requestKey, blindKey, requestDigest(tokenRequest), r, s, blindContext)
+	if err != nil {
+		return tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	cacheKey := hex.EncodeToString(clientKeyEnc)
-	_, ok := a.cache.Get(hex.EncodeToString(clientKeyEnc))
+	_, ok, err := a.getClientState(ctx, cacheKey)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		state := &ClientState{
 			originIndices: make(map[string]string),
 			clientIndices: make(map[string]string),
 			originCounts:  make(map[string]int),
 		}
-		a.cache.Put(cacheKey, state)
+		if err := a.putClientState(ctx, cacheKey, state); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func computeIndex(clientKey, indexKey []byte) ([]byte, error) {
+// ComputeIndex derives the anonymous issuer origin ID, or index, from the
+// encoded client public key and the unblinded index key.
+func ComputeIndex(clientKey, indexKey []byte) ([]byte, error) {
 	hkdf := hkdf.New(sha512.New384, indexKey, clientKey, []byte("IssuerOriginAlias"))
 	clientOriginIndex := make([]byte, crypto.SHA384.Size())
 	if _, err := io.ReadFull(hkdf, clientOriginIndex); err != nil {
@@ -142,38 +183,64 @@ func computeIndex(clientKey, indexKey []byte) ([]byte, error) {
 
 // https://ietf-wg-privacypass.github.io/draft-ietf-privacypass-rate-limit-tokens/draft-ietf-privacypass-rate-limit-tokens.html#name-attester-behavior-index-com
 func (a *RateLimitedAttester) FinalizeIndex(clientKey, blindEnc, blindedRequestKeyEnc, anonOriginId []byte) ([]byte, error) {
+	return a.FinalizeIndexContext(context.Background(), clientKey, blindEnc, blindedRequestKeyEnc, anonOriginId)
+}
+
+// FinalizeIndexContext is like FinalizeIndex, but returns ctx.Err() if ctx is
+// done before the index is computed, and passes ctx to the client state cache.
+// The updated client state is written back to the cache.
+func (a *RateLimitedAttester) FinalizeIndexContext(ctx context.Context, clientKey, blindEnc, blindedRequestKeyEnc, anonOriginId []byte) ([]byte, error) {
+	done := tokens.ObserveRequest(a.observer, tokens.RequestInfo{
+		Operation: tokens.OperationFinalizeIndex,
+		TokenType: RateLimitedTokenType,
+	})
+	index, err := a.finalizeIndex(ctx, clientKey, blindEnc, blindedRequestKeyEnc, anonOriginId)
+	done(err)
+	return index, err
+}
+
+func (a *RateLimitedAttester) finalizeIndex(ctx context.Context, clientKey, blindEnc, blindedRequestKeyEnc, anonOriginId []byte) ([]byte, error) {
 	curve := elliptic.P384()
-	blindedRequestKey, err := unmarshalPublicKey(curve, blindedRequestKeyEnc)
+	blindedRequestKey, err := ecdsa.UnmarshalCompressed(curve, blindedRequestKeyEnc)
 	if err != nil {
-		return nil, err
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blinded request key: %w", err)
 	}
 
 	blindKey, err := ecdsa.CreateKey(curve, blindEnc)
 	if err != nil {
-		return nil, err
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blind key: %w", err)
 	}
 
 	b := cryptobyte.NewBuilder(nil)
 	b.AddUint16(RateLimitedTokenType)
 	b.AddBytes([]byte("ClientBlind"))
-	ctx := b.BytesOrPanic()
-	indexKey, err := ecdsa.UnblindPublicKeyWithContext(curve, blindedRequestKey, blindKey, ctx)
+	blindContext := b.BytesOrPanic()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	indexKey, err := ecdsa.UnblindPublicKeyWithContext(curve, blindedRequestKey, blindKey, blindContext)
 	if err != nil {
 		return nil, err
 	}
 
 	// Compute the anonymous issuer origin ID (index)
-	indexKeyEnc := elliptic.MarshalCompressed(curve, indexKey.X, indexKey.Y)
-	index, err := computeIndex(clientKey, indexKeyEnc)
+	indexKeyEnc, err := ecdsa.MarshalCompressed(indexKey)
+	if err != nil {
+		return nil, err
+	}
+	index, err := ComputeIndex(clientKey, indexKeyEnc)
 	if err != nil {
 		return nil, err
 	}
 
 	// Look up per-client cached state
 	clientKeyEnc := hex.EncodeToString(clientKey)
-	state, ok := a.cache.Get(clientKeyEnc)
+	state, ok, err := a.getClientState(ctx, clientKeyEnc)
+	if err != nil {
+		return nil, err
+	}
 	if !ok {
-		return nil, fmt.Errorf("Unknown client ID: %s", clientKeyEnc)
+		return nil, tokens.Errorf(tokens.ErrUnknownClient, "Unknown client ID: %s", clientKeyEnc)
 	}
 
 	// Check to make sure anonymous origin ID and anonymous issuer origin ID invariants are not violated
@@ -189,11 +256,18 @@ func (a *RateLimitedAttester) FinalizeIndex(clientKey, blindEnc, blindedRequestK
 	expectedOriginID, ok := state.clientIndices[indexEnc]
 	if ok && expectedOriginID != anonOriginIdEnc {
 		// There was an anonymous origin ID that had the same anonymous issuer origin ID, so fail
-		return nil, fmt.Errorf("Repeated anonymous origin ID across client-committed origins")
+		err = tokens.Errorf(tokens.ErrRateLimited, "Repeated anonymous origin ID across client-committed origins")
 	} else {
 		// Otherwise, set the anonymous issuer origin ID and anonymous origin ID pair
 		state.clientIndices[indexEnc] = anonOriginIdEnc
 	}
 
+	if putErr := a.putClientState(ctx, clientKeyEnc, state); putErr != nil {
+		return nil, putErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	return index, nil
 }
@@ -13,15 +13,23 @@ import (
 	"github.com/cloudflare/circl/blindsign/blindrsa"
 	"github.com/cloudflare/pat-go/ecdsa"
 	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/util"
 	"golang.org/x/crypto/cryptobyte"
 )
 
 type RateLimitedClient struct {
-	curve     elliptic.Curve
-	secretKey *ecdsa.PrivateKey
+	curve             elliptic.Curve
+	secretKey         *ecdsa.PrivateKey
+	originNamePadding OriginNamePadding
 }
 
 func NewRateLimitedClientFromSecret(secret []byte) RateLimitedClient {
+	return NewRateLimitedClientFromSecretWithPadding(secret, PadOriginNameBlock32)
+}
+
+// NewRateLimitedClientFromSecretWithPadding creates a client that pads origin
+// names using the given padding policy.
+func NewRateLimitedClientFromSecretWithPadding(secret []byte, padding OriginNamePadding) RateLimitedClient {
 	curve := elliptic.P384()
 	secretKey, err := ecdsa.CreateKey(curve, secret)
 	if err != nil {
@@ -29,34 +37,23 @@ func NewRateLimitedClientFromSecret(secret []byte) RateLimitedClient {
 	}
 
 	return RateLimitedClient{
-		curve:     elliptic.P384(),
-		secretKey: secretKey,
+		curve:             elliptic.P384(),
+		secretKey:         secretKey,
+		originNamePadding: padding,
 	}
 }
 
-func padOriginName(originName string) []byte {
-	N := 31 - ((len(originName) - 1) % 32)
-	zeroes := make([]byte, N)
-	return append([]byte(originName), zeroes...)
-}
-
-func unpadOriginName(paddedOriginName []byte) string {
-	lastNonZero := len(paddedOriginName) - 1
-	for {
-		if lastNonZero < 0 {
-			// The plaintext was empty, so the input was the empty string
-			return ""
-		}
-		if paddedOriginName[lastNonZero] != 0x00 {
-			break
-		}
-		lastNonZero--
+// EncryptOriginTokenRequest pads originName and encrypts it along with the
+// blinded message to the issuer name key. It returns the name key ID, the
+// encrypted token request, and the secret used to decrypt the response.
+//
+// https://ietf-wg-privacypass.github.io/draft-ietf-privacypass-rate-limit-tokens/draft-ietf-privacypass-rate-limit-tokens.html#name-encrypting-origin-token-req
+func EncryptOriginTokenRequest(nameKey EncapKey, tokenKeyID uint8, blindedMessage []byte, requestKey []byte, originName string, padding OriginNamePadding) ([]byte, []byte, []byte, error) {
+	paddedOriginName, err := padOriginName(originName, padding)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	return string(paddedOriginName[0 : lastNonZero+1])
-}
 
-// https://ietf-wg-privacypass.github.io/draft-ietf-privacypass-rate-limit-tokens/draft-ietf-privacypass-rate-limit-tokens.html#name-encrypting-origin-token-req
-func encryptOriginTokenRequest(nameKey EncapKey, tokenKeyID uint8, blindedMessage []byte, requestKey []byte, originName string) ([]byte, []byte, []byte, error) {
 	issuerKeyEnc := nameKey.Marshal()
 	issuerKeyID := sha256.Sum256(issuerKeyEnc)
 
@@ -77,7 +74,7 @@ func encryptOriginTokenRequest(nameKey EncapKey, tokenKeyID uint8, blindedMessag
 	tokenRequest := InnerTokenRequest{
 		blindedMsg:   blindedMessage,
 		tokenKeyId:   tokenKeyID,
-		paddedOrigin: padOriginName(originName),
+		paddedOrigin: paddedOriginName,
 	}
 	input := tokenRequest.Marshal()
 
@@ -118,6 +115,9 @@ func (s RateLimitedTokenRequestState) ClientKey() []byte {
 func (s RateLimitedTokenRequestState) FinalizeToken(encryptedtokenResponse []byte) (tokens.Token, error) {
 	// response_nonce = random(max(Nn, Nk)), taken from the encapsualted response
 	responseNonceLen := max(s.nameKey.suite.AEAD.KeySize(), s.nameKey.suite.AEAD.NonceSize())
+	if len(encryptedtokenResponse) < responseNonceLen {
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid token response length %d", len(encryptedtokenResponse))
+	}
 
 	// salt = concat(enc, response_nonce)
 	salt := append(s.encapEnc, encryptedtokenResponse[:responseNonceLen]...)
@@ -139,16 +139,19 @@ func (s RateLimitedTokenRequestState) FinalizeToken(encryptedtokenResponse []byt
 	// reponse, error = Open(aead_key, aead_nonce, "", ct)
 	blindSignature, err := cipher.Open(nil, nonce, encryptedtokenResponse[responseNonceLen:], nil)
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "malformed encrypted token response: %w", err)
 	}
 
+	if len(blindSignature) != s.verificationKey.Size() {
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blind signature length %d, expected %d", len(blindSignature), s.verificationKey.Size())
+	}
 	signature, err := s.verifier.Finalize(blindSignature)
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	tokenData := append(s.tokenInput, signature...)
-	token, err := UnmarshalToken(tokenData)
+	token, err := UnmarshalTokenWithKey(tokenData, s.verificationKey)
 	if err != nil {
 		return tokens.Token{}, err
 	}
@@ -166,7 +169,7 @@ func (s RateLimitedTokenRequestState) FinalizeToken(encryptedtokenResponse []byt
 		SaltLength: crypto.SHA384.Size(),
 	})
 	if err != nil {
-		return tokens.Token{}, err
+		return tokens.Token{}, tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	return token, nil
@@ -179,7 +182,10 @@ func (c RateLimitedClient) CreateTokenRequest(challenge, nonce, blindKeyEnc []by
 		return RateLimitedTokenRequestState{}, err
 	}
 
-	clientKeyEnc := elliptic.MarshalCompressed(c.curve, c.secretKey.PublicKey.X, c.secretKey.PublicKey.Y)
+	clientKeyEnc, err := ecdsa.MarshalCompressed(&c.secretKey.PublicKey)
+	if err != nil {
+		return RateLimitedTokenRequestState{}, err
+	}
 
 	b := cryptobyte.NewBuilder(nil)
 	b.AddUint16(RateLimitedTokenType)
@@ -189,8 +195,14 @@ func (c RateLimitedClient) CreateTokenRequest(challenge, nonce, blindKeyEnc []by
 	if err != nil {
 		return RateLimitedTokenRequestState{}, err
 	}
-	blindedPublicKeyEnc := elliptic.MarshalCompressed(c.curve, blindedPublicKey.X, blindedPublicKey.Y)
+	blindedPublicKeyEnc, err := ecdsa.MarshalCompressed(blindedPublicKey)
+	if err != nil {
+		return RateLimitedTokenRequestState{}, err
+	}
 
+	if _, err := util.TokenKeySize(tokenKey); err != nil {
+		return RateLimitedTokenRequestState{}, err
+	}
 	verifier := blindrsa.NewRSAVerifier(tokenKey, crypto.SHA384)
 
 	context := sha256.Sum256(challenge)
@@ -207,7 +219,7 @@ func (c RateLimitedClient) CreateTokenRequest(challenge, nonce, blindKeyEnc []by
 		return RateLimitedTokenRequestState{}, err
 	}
 
-	nameKeyID, encryptedTokenRequest, secret, err := encryptOriginTokenRequest(nameKey, tokenKeyID[0], blindedMessage, blindedPublicKeyEnc, originName)
+	nameKeyID, encryptedTokenRequest, secret, err := EncryptOriginTokenRequest(nameKey, tokenKeyID[0], blindedMessage, blindedPublicKeyEnc, originName, c.originNamePadding)
 	if err != nil {
 		return RateLimitedTokenRequestState{}, err
 	}
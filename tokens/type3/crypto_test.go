@@ -14,6 +14,7 @@ import (
 
 	"github.com/cloudflare/pat-go/ecdsa"
 	"github.com/cloudflare/pat-go/ed25519"
+	"github.com/cloudflare/pat-go/ed448"
 )
 
 const (
@@ -22,6 +23,9 @@ const (
 
 	outputEd25519BlindingTestVectorEnvironmentKey = "ED25519_BLINDING_TEST_VECTORS_OUT"
 	inputEd25519BlindingTestVectorEnvironmentKey  = "ED25519_BLINDING_TEST_VECTORS_IN"
+
+	outputEd448BlindingTestVectorEnvironmentKey = "ED448_BLINDING_TEST_VECTORS_OUT"
+	inputEd448BlindingTestVectorEnvironmentKey  = "ED448_BLINDING_TEST_VECTORS_IN"
 )
 
 // /////
@@ -36,6 +40,7 @@ type rawECDSABlindingTestVector struct {
 	Message        string `json:"message"`
 	Context        string `json:"context"`
 	Signature      string `json:"signature"`
+	Deterministic  bool   `json:"deterministic,omitempty"`
 }
 
 type ecdsaBlindingTestVector struct {
@@ -49,6 +54,9 @@ type ecdsaBlindingTestVector struct {
 	context []byte
 	r       *big.Int
 	s       *big.Int
+	// deterministic is set when the signature was produced with RFC 6979
+	// nonces, in which case it must be reproduced exactly.
+	deterministic bool
 }
 
 type ecdsaBlindingTestVectorArray struct {
@@ -103,6 +111,7 @@ func (etv ecdsaBlindingTestVector) MarshalJSON() ([]byte, error) {
 		Message:        mustHex(etv.message),
 		Context:        mustHex(etv.context),
 		Signature:      mustHex(sig),
+		Deterministic:  etv.deterministic,
 	})
 }
 
@@ -164,6 +173,7 @@ func (etv *ecdsaBlindingTestVector) UnmarshalJSON(data []byte) error {
 	etv.s = new(big.Int).SetBytes(sigEnc[scalarLen:])
 	etv.c = curve
 	etv.h = hash
+	etv.deterministic = raw.Deterministic
 
 	return nil
 }
@@ -190,7 +200,10 @@ func generateECDSABlindingTestVector(t *testing.T, c elliptic.Curve, h crypto.Ha
 	digester := h.New()
 	digester.Write(message)
 	digest := digester.Sum(nil)
-	r, s, err := ecdsa.BlindKeySignWithContext(rand.Reader, skS, skB, digest, context[0:contextLen])
+	r, s, err := ecdsa.BlindKeySignDeterministicWithContext(skS, skB, h, digest, context[0:contextLen])
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	return ecdsaBlindingTestVector{
 		c:       c,
@@ -202,6 +215,8 @@ func generateECDSABlindingTestVector(t *testing.T, c elliptic.Curve, h crypto.Ha
 		context: context[0:contextLen],
 		r:       r,
 		s:       s,
+
+		deterministic: true,
 	}
 }
 
@@ -221,6 +236,16 @@ func verifyECDSABlindingTestVector(t *testing.T, vector ecdsaBlindingTestVector)
 	if !valid {
 		t.Fatal("Signature with blinded key verification failed")
 	}
+
+	if vector.deterministic {
+		r, s, err := ecdsa.BlindKeySignDeterministicWithContext(vector.skS, vector.bk, vector.h, digest, vector.context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Cmp(vector.r) != 0 || s.Cmp(vector.s) != 0 {
+			t.Fatal("Deterministic signature mismatch")
+		}
+	}
 }
 
 func verifyECDSABlindingTestVectors(t *testing.T, encoded []byte) {
@@ -454,6 +479,192 @@ func TestVectorVerifyEd25519Blinding(t *testing.T) {
 	verifyEd25519BlindingTestVectors(t, encoded)
 }
 
+// /////
+// Ed448 key blinding test vector
+type rawEd448BlindingTestVector struct {
+	PrivateKey     string `json:"skS"`
+	PublicKey      string `json:"pkS"`
+	PrivateBlind   string `json:"bk"`
+	PublicBlind    string `json:"pkB"`
+	BlindPublicKey string `json:"pkR"`
+	Message        string `json:"message"`
+	Context        string `json:"context"`
+	Signature      string `json:"signature"`
+}
+
+type ed448BlindingTestVector struct {
+	t         *testing.T
+	skS       []byte
+	pkS       ed448.PublicKey
+	skB       []byte
+	pkB       ed448.PublicKey
+	pkR       ed448.PublicKey
+	message   []byte
+	context   []byte
+	signature []byte
+}
+
+type ed448BlindingTestVectorArray struct {
+	t       *testing.T
+	vectors []ed448BlindingTestVector
+}
+
+func (tva ed448BlindingTestVectorArray) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tva.vectors)
+}
+
+func (tva *ed448BlindingTestVectorArray) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &tva.vectors)
+	if err != nil {
+		return err
+	}
+
+	for i := range tva.vectors {
+		tva.vectors[i].t = tva.t
+	}
+	return nil
+}
+
+func (etv ed448BlindingTestVector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rawEd448BlindingTestVector{
+		PrivateKey:     mustHex(etv.skS),
+		PublicKey:      mustHex(etv.pkS),
+		PrivateBlind:   mustHex(etv.skB),
+		PublicBlind:    mustHex(etv.pkB),
+		BlindPublicKey: mustHex(etv.pkR),
+		Message:        mustHex(etv.message),
+		Context:        mustHex(etv.context),
+		Signature:      mustHex(etv.signature),
+	})
+}
+
+func (etv *ed448BlindingTestVector) UnmarshalJSON(data []byte) error {
+	raw := rawEd448BlindingTestVector{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	etv.skS = mustUnhex(nil, raw.PrivateKey)
+	etv.pkS = mustUnhex(nil, raw.PublicKey)
+	etv.skB = mustUnhex(nil, raw.PrivateBlind)
+	etv.pkB = mustUnhex(nil, raw.PublicBlind)
+	etv.pkR = mustUnhex(nil, raw.BlindPublicKey)
+	etv.message = mustUnhex(nil, raw.Message)
+	etv.context = mustUnhex(nil, raw.Context)
+	etv.signature = mustUnhex(nil, raw.Signature)
+
+	return nil
+}
+
+func generateEd448BlindingTestVector(t *testing.T, blindLen int, contextLen int) ed448BlindingTestVector {
+	skS := make([]byte, ed448.SeedSize)
+	rand.Reader.Read(skS)
+
+	skB := make([]byte, ed448.BlindSize)
+	rand.Reader.Read(skB[0:blindLen])
+
+	context := make([]byte, 32)
+	rand.Reader.Read(context)
+
+	privateKey := ed448.NewKeyFromSeed(skS)
+	publicKey := privateKey.Public().(ed448.PublicKey)
+	publicBlind, err := ed448.BlindPublicKeyWithContext(publicKey, skB, context[0:contextLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("hello world")
+	signature := ed448.BlindKeySignWithContext(privateKey, message, skB, context[0:contextLen])
+
+	return ed448BlindingTestVector{
+		skS:       skS,
+		pkS:       publicKey,
+		skB:       skB,
+		pkR:       publicBlind,
+		message:   message,
+		context:   context[0:contextLen],
+		signature: signature,
+	}
+}
+
+func verifyEd448BlindingTestVector(t *testing.T, vector ed448BlindingTestVector) {
+	privateKey := ed448.NewKeyFromSeed(vector.skS)
+	publicKey := privateKey.Public().(ed448.PublicKey)
+	if !bytes.Equal(publicKey, vector.pkS) {
+		t.Fatal("Public key mismatch")
+	}
+
+	publicBlind, err := ed448.BlindPublicKeyWithContext(publicKey, vector.skB, vector.context)
+	if err != nil {
+		t.Fatal("BlindKey failed")
+	}
+	if !bytes.Equal(publicBlind, vector.pkR) {
+		t.Fatal("Blinded public key mismatch")
+	}
+
+	signature := ed448.BlindKeySignWithContext(privateKey, vector.message, vector.skB, vector.context)
+	if !bytes.Equal(signature, vector.signature) {
+		t.Fatal("Signature mismatch")
+	}
+
+	valid := ed448.Verify(publicBlind, vector.message, vector.signature)
+	if !valid {
+		t.Fatal("Signature with blinded key verification failed")
+	}
+}
+
+func verifyEd448BlindingTestVectors(t *testing.T, encoded []byte) {
+	vectors := ed448BlindingTestVectorArray{t: t}
+	err := json.Unmarshal(encoded, &vectors)
+	if err != nil {
+		t.Fatalf("Error decoding test vector string: %v", err)
+	}
+
+	for _, vector := range vectors.vectors {
+		verifyEd448BlindingTestVector(t, vector)
+	}
+}
+
+func TestVectorGenerateEd448Blinding(t *testing.T) {
+	vectors := make([]ed448BlindingTestVector, 0)
+	vectors = append(vectors, generateEd448BlindingTestVector(t, ed448.BlindSize, 0))
+	vectors = append(vectors, generateEd448BlindingTestVector(t, 0, 0))
+	vectors = append(vectors, generateEd448BlindingTestVector(t, ed448.BlindSize, 32))
+	vectors = append(vectors, generateEd448BlindingTestVector(t, 0, 32))
+
+	// Encode the test vectors
+	encoded, err := json.Marshal(vectors)
+	if err != nil {
+		t.Fatalf("Error producing test vectors: %v", err)
+	}
+
+	// Verify that we process them correctly
+	verifyEd448BlindingTestVectors(t, encoded)
+
+	var outputFile string
+	if outputFile = os.Getenv(outputEd448BlindingTestVectorEnvironmentKey); len(outputFile) > 0 {
+		err := ioutil.WriteFile(outputFile, encoded, 0644)
+		if err != nil {
+			t.Fatalf("Error writing test vectors: %v", err)
+		}
+	}
+}
+
+func TestVectorVerifyEd448Blinding(t *testing.T) {
+	var inputFile string
+	if inputFile = os.Getenv(inputEd448BlindingTestVectorEnvironmentKey); len(inputFile) == 0 {
+		t.Skip("Test vectors were not provided")
+	}
+
+	encoded, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		t.Fatalf("Failed reading test vectors: %v", err)
+	}
+
+	verifyEd448BlindingTestVectors(t, encoded)
+}
+
 func BenchmarkEd25519(b *testing.B) {
 	b.Run("KeyGen", func(b *testing.B) {
 		for n := 0; n < b.N; n++ {
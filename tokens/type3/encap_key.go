@@ -2,9 +2,14 @@ package type3
 
 import (
 	"bytes"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"strconv"
 
 	hpke "github.com/cisco/go-hpke"
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/util"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -64,7 +69,9 @@ func (k PrivateEncapKey) IsEqual(o PrivateEncapKey) bool {
 	if k.id != o.id {
 		return false
 	}
-	if k.suite != o.suite {
+	if k.suite.KEM.ID() != o.suite.KEM.ID() ||
+		k.suite.KDF.ID() != o.suite.KDF.ID() ||
+		k.suite.AEAD.ID() != o.suite.AEAD.ID() {
 		return false
 	}
 	if !bytes.Equal(k.suite.KEM.SerializePublicKey(k.publicKey), k.suite.KEM.SerializePublicKey(o.publicKey)) {
@@ -104,35 +111,35 @@ func UnmarshalEncapKey(data []byte) (EncapKey, error) {
 	var kemID uint16
 	if !s.ReadUint8(&id) ||
 		!s.ReadUint16(&kemID) {
-		return EncapKey{}, fmt.Errorf("Invalid EncapKey")
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey")
 	}
 
 	kem := hpke.KEMID(kemID)
 	suite, err := hpke.AssembleCipherSuite(kem, fixedKDF, fixedAEAD)
 	if err != nil {
-		return EncapKey{}, fmt.Errorf("Invalid EncapKey")
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey")
 	}
 
 	publicKeyBytes := make([]byte, suite.KEM.PublicKeySize())
 	if !s.ReadBytes(&publicKeyBytes, len(publicKeyBytes)) {
-		return EncapKey{}, fmt.Errorf("Invalid EncapKey")
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey")
 	}
 
 	var kdfID uint16
 	var aeadID uint16
 	if !s.ReadUint16(&kdfID) ||
 		!s.ReadUint16(&aeadID) {
-		return EncapKey{}, fmt.Errorf("Invalid EncapKey")
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey")
 	}
 
 	suite, err = hpke.AssembleCipherSuite(kem, hpke.KDFID(kdfID), hpke.AEADID(aeadID))
 	if err != nil {
-		return EncapKey{}, fmt.Errorf("Invalid EncapKey")
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey")
 	}
 
 	publicKey, err := suite.KEM.DeserializePublicKey(publicKeyBytes)
 	if err != nil {
-		return EncapKey{}, fmt.Errorf("Invalid EncapKey")
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey")
 	}
 
 	return EncapKey{
@@ -141,3 +148,213 @@ func UnmarshalEncapKey(data []byte) (EncapKey, error) {
 		publicKey: publicKey,
 	}, nil
 }
+
+// Marshal encodes the private key in the same layout as EncapKey, with the
+// serialized HPKE private key in place of the public key.
+//
+//	struct {
+//	  uint8 key_id;
+//	  HpkeKemId kem_id;
+//	  HpkePrivateKey private_key;
+//	  HpkeKdfId kdf_id;
+//	  HpkeAeadId aead_id;
+//	} PrivateEncapKey;
+func (k PrivateEncapKey) Marshal() []byte {
+	b := cryptobyte.NewBuilder(nil)
+
+	b.AddUint8(k.id)
+	b.AddUint16(uint16(k.suite.KEM.ID()))
+	b.AddBytes(k.suite.KEM.SerializePrivateKey(k.privateKey))
+	b.AddUint16(uint16(k.suite.KDF.ID()))
+	b.AddUint16(uint16(k.suite.AEAD.ID()))
+	return b.BytesOrPanic()
+}
+
+func UnmarshalPrivateEncapKey(data []byte) (PrivateEncapKey, error) {
+	s := cryptobyte.String(data)
+
+	var id uint8
+	var kemID uint16
+	if !s.ReadUint8(&id) ||
+		!s.ReadUint16(&kemID) {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey")
+	}
+
+	kem := hpke.KEMID(kemID)
+	suite, err := hpke.AssembleCipherSuite(kem, fixedKDF, fixedAEAD)
+	if err != nil {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey")
+	}
+
+	privateKeyBytes := make([]byte, suite.KEM.PrivateKeySize())
+	if !s.ReadBytes(&privateKeyBytes, len(privateKeyBytes)) {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey")
+	}
+
+	var kdfID uint16
+	var aeadID uint16
+	if !s.ReadUint16(&kdfID) ||
+		!s.ReadUint16(&aeadID) ||
+		!s.Empty() {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey")
+	}
+
+	suite, err = hpke.AssembleCipherSuite(kem, hpke.KDFID(kdfID), hpke.AEADID(aeadID))
+	if err != nil {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey")
+	}
+
+	privateKey, err := suite.KEM.DeserializePrivateKey(privateKeyBytes)
+	if err != nil {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey")
+	}
+
+	return PrivateEncapKey{
+		id:         id,
+		suite:      suite,
+		privateKey: privateKey,
+		publicKey:  privateKey.PublicKey(),
+	}, nil
+}
+
+const (
+	encapKeyPEMType        = "ENCAP KEY"
+	privateEncapKeyPEMType = "PRIVATE ENCAP KEY"
+)
+
+// MarshalEncapKeyPEM encodes k as an "ENCAP KEY" PEM block.
+func MarshalEncapKeyPEM(k EncapKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  encapKeyPEMType,
+		Bytes: k.Marshal(),
+	})
+}
+
+func UnmarshalEncapKeyPEM(data []byte) (EncapKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encapKeyPEMType {
+		return EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey PEM encoding")
+	}
+	return UnmarshalEncapKey(block.Bytes)
+}
+
+// MarshalPrivateEncapKeyPEM encodes k as a "PRIVATE ENCAP KEY" PEM block.
+func MarshalPrivateEncapKeyPEM(k PrivateEncapKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  privateEncapKeyPEMType,
+		Bytes: k.Marshal(),
+	})
+}
+
+func UnmarshalPrivateEncapKeyPEM(data []byte) (PrivateEncapKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != privateEncapKeyPEMType {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid PrivateEncapKey PEM encoding")
+	}
+	return UnmarshalPrivateEncapKey(block.Bytes)
+}
+
+// encapKeyJWK returns the JSON Web Key (RFC 8037) encoding of an X25519 name
+// key. The key ID is carried as "kid"; the KDF and AEAD are fixed.
+func encapKeyJWK(k EncapKey) (*util.JWK, error) {
+	if k.suite.KEM.ID() != hpke.DHKEM_X25519 || k.suite.KDF.ID() != fixedKDF || k.suite.AEAD.ID() != fixedAEAD {
+		return nil, fmt.Errorf("Unsupported EncapKey ciphersuite for JWK encoding")
+	}
+	return &util.JWK{
+		KeyType: "OKP",
+		Curve:   "X25519",
+		KeyID:   strconv.Itoa(int(k.id)),
+		X:       util.EncodeJWKMember(k.suite.KEM.SerializePublicKey(k.publicKey)),
+	}, nil
+}
+
+func parseEncapKeyJWK(data []byte) (*util.JWK, EncapKey, error) {
+	jwk, err := util.UnmarshalJWK(data)
+	if err != nil {
+		return nil, EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK: %v", err)
+	}
+	if jwk.KeyType != "OKP" || jwk.Curve != "X25519" {
+		return nil, EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Unsupported EncapKey JWK key type %q with curve %q", jwk.KeyType, jwk.Curve)
+	}
+	id, err := strconv.ParseUint(jwk.KeyID, 10, 8)
+	if err != nil {
+		return nil, EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK key ID %q", jwk.KeyID)
+	}
+
+	suite, err := hpke.AssembleCipherSuite(hpke.DHKEM_X25519, fixedKDF, fixedAEAD)
+	if err != nil {
+		return nil, EncapKey{}, err
+	}
+	publicKeyBytes, err := util.DecodeJWKMember("x", jwk.X, 0)
+	if err != nil {
+		return nil, EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK public key: %v", err)
+	}
+	publicKey, err := suite.KEM.DeserializePublicKey(publicKeyBytes)
+	if err != nil {
+		return nil, EncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK public key")
+	}
+
+	return jwk, EncapKey{
+		id:        uint8(id),
+		suite:     suite,
+		publicKey: publicKey,
+	}, nil
+}
+
+// MarshalEncapKeyJWK encodes k as an "OKP" JSON Web Key. Only X25519 keys with
+// the fixed KDF and AEAD can be encoded.
+func MarshalEncapKeyJWK(k EncapKey) ([]byte, error) {
+	jwk, err := encapKeyJWK(k)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwk)
+}
+
+func UnmarshalEncapKeyJWK(data []byte) (EncapKey, error) {
+	_, key, err := parseEncapKeyJWK(data)
+	return key, err
+}
+
+// MarshalPrivateEncapKeyJWK encodes k as an "OKP" JSON Web Key holding both the
+// public and private keys. Only X25519 keys with the fixed KDF and AEAD can be
+// encoded.
+func MarshalPrivateEncapKeyJWK(k PrivateEncapKey) ([]byte, error) {
+	jwk, err := encapKeyJWK(k.Public())
+	if err != nil {
+		return nil, err
+	}
+	jwk.D = util.EncodeJWKMember(k.suite.KEM.SerializePrivateKey(k.privateKey))
+	return json.Marshal(jwk)
+}
+
+func UnmarshalPrivateEncapKeyJWK(data []byte) (PrivateEncapKey, error) {
+	jwk, publicKey, err := parseEncapKeyJWK(data)
+	if err != nil {
+		return PrivateEncapKey{}, err
+	}
+	if jwk.D == "" {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK: missing private key")
+	}
+
+	privateKeyBytes, err := util.DecodeJWKMember("d", jwk.D, 0)
+	if err != nil {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK private key: %v", err)
+	}
+	privateKey, err := publicKey.suite.KEM.DeserializePrivateKey(privateKeyBytes)
+	if err != nil {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK private key")
+	}
+
+	key := PrivateEncapKey{
+		id:         publicKey.id,
+		suite:      publicKey.suite,
+		privateKey: privateKey,
+		publicKey:  privateKey.PublicKey(),
+	}
+	if !bytes.Equal(key.Public().Marshal(), publicKey.Marshal()) {
+		return PrivateEncapKey{}, tokens.Errorf(tokens.ErrMalformedEncoding, "Invalid EncapKey JWK: private key does not match public key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,142 @@
+package type3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+)
+
+func newTestPrivateEncapKey(t *testing.T) PrivateEncapKey {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		t.Fatal(err)
+	}
+	key, err := CreatePrivateEncapKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestPrivateEncapKeyMarshalRoundTrip(t *testing.T) {
+	key := newTestPrivateEncapKey(t)
+
+	enc := key.Marshal()
+	recoveredKey, err := UnmarshalPrivateEncapKey(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recoveredKey.IsEqual(key) {
+		t.Fatal("private key mismatch")
+	}
+	if !bytes.Equal(recoveredKey.Marshal(), enc) {
+		t.Fatal("private key encoding mismatch")
+	}
+
+	for _, malformed := range [][]byte{
+		enc[:len(enc)-1],
+		append(append([]byte{}, enc...), 0x00),
+		key.Public().Marshal()[:3],
+	} {
+		if _, err := UnmarshalPrivateEncapKey(malformed); err == nil {
+			t.Fatalf("decoded malformed private key %x", malformed)
+		}
+	}
+}
+
+func TestEncapKeyPEMRoundTrip(t *testing.T) {
+	key := newTestPrivateEncapKey(t)
+
+	publicKey, err := UnmarshalEncapKeyPEM(MarshalEncapKeyPEM(key.Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicKey.Marshal(), key.Public().Marshal()) {
+		t.Fatal("public key mismatch")
+	}
+
+	privateKey, err := UnmarshalPrivateEncapKeyPEM(MarshalPrivateEncapKeyPEM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privateKey.IsEqual(key) {
+		t.Fatal("private key mismatch")
+	}
+
+	if _, err := UnmarshalEncapKeyPEM(MarshalPrivateEncapKeyPEM(key)); err == nil {
+		t.Fatal("decoded a private key block as a public key")
+	}
+	if _, err := UnmarshalPrivateEncapKeyPEM(MarshalEncapKeyPEM(key.Public())); err == nil {
+		t.Fatal("decoded a public key block as a private key")
+	}
+}
+
+func TestEncapKeyJWKRoundTrip(t *testing.T) {
+	key := newTestPrivateEncapKey(t)
+
+	publicKeyJWK, err := MarshalEncapKeyJWK(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members map[string]string
+	if err := json.Unmarshal(publicKeyJWK, &members); err != nil {
+		t.Fatal(err)
+	}
+	if members["kty"] != "OKP" || members["crv"] != "X25519" || members["kid"] != "1" {
+		t.Fatalf("unexpected JWK members %v", members)
+	}
+	publicKey, err := UnmarshalEncapKeyJWK(publicKeyJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicKey.Marshal(), key.Public().Marshal()) {
+		t.Fatal("public key mismatch")
+	}
+	if _, err := UnmarshalPrivateEncapKeyJWK(publicKeyJWK); err == nil {
+		t.Fatal("decoded a public JWK as a private key")
+	}
+
+	privateKeyJWK, err := MarshalPrivateEncapKeyJWK(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := UnmarshalPrivateEncapKeyJWK(privateKeyJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privateKey.IsEqual(key) {
+		t.Fatal("private key mismatch")
+	}
+
+	// A private key paired with another key's public key is rejected.
+	otherJWK, err := MarshalEncapKeyJWK(newTestPrivateEncapKey(t).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mismatched map[string]string
+	if err := json.Unmarshal(otherJWK, &mismatched); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(privateKeyJWK, &members); err != nil {
+		t.Fatal(err)
+	}
+	mismatched["d"] = members["d"]
+	mismatchedJWK, err := json.Marshal(mismatched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalPrivateEncapKeyJWK(mismatchedJWK); err == nil {
+		t.Fatal("decoded a private key that does not match its public key")
+	}
+
+	for _, jwk := range []string{
+		`{"kty":"OKP","crv":"X448","kid":"1","x":"AA"}`,
+		`{"kty":"OKP","crv":"X25519","kid":"1","x":"AA"}`,
+		`{"kty":"OKP","crv":"X25519","kid":"256","x":"` + members["x"] + `"}`,
+	} {
+		if _, err := UnmarshalEncapKeyJWK([]byte(jwk)); err == nil {
+			t.Errorf("decoded invalid JWK %s", jwk)
+		}
+	}
+}
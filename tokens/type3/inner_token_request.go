@@ -1,6 +1,9 @@
 package type3
 
-import "golang.org/x/crypto/cryptobyte"
+import (
+	"github.com/cloudflare/pat-go/tokens"
+	"golang.org/x/crypto/cryptobyte"
+)
 
 type InnerTokenRequest struct {
 	raw          []byte
@@ -9,6 +12,26 @@ type InnerTokenRequest struct {
 	paddedOrigin []byte
 }
 
+// TokenKeyID returns the truncated token key ID of the request.
+func (r InnerTokenRequest) TokenKeyID() uint8 {
+	return r.tokenKeyId
+}
+
+// BlindedMessage returns the blinded message to be signed by the issuer.
+func (r InnerTokenRequest) BlindedMessage() []byte {
+	return r.blindedMsg
+}
+
+// PaddedOriginName returns the origin name with its padding.
+func (r InnerTokenRequest) PaddedOriginName() []byte {
+	return r.paddedOrigin
+}
+
+// OriginName returns the origin name with the padding removed.
+func (r InnerTokenRequest) OriginName() (string, error) {
+	return unpadOriginName(r.paddedOrigin)
+}
+
 func (r *InnerTokenRequest) Marshal() []byte {
 	if r.raw != nil {
 		return r.raw
@@ -25,15 +48,28 @@ func (r *InnerTokenRequest) Marshal() []byte {
 	return r.raw
 }
 
+// defaultTokenKeySize is the blinded message size, in bytes, assumed by
+// Unmarshal: that of a 2048-bit token key.
+const defaultTokenKeySize = 256
+
+// Unmarshal decodes an inner token request for a 2048-bit token key. Use
+// UnmarshalWithKeySize for requests for larger keys.
 func (r *InnerTokenRequest) Unmarshal(data []byte) bool {
+	return r.UnmarshalWithKeySize(data, defaultTokenKeySize)
+}
+
+// UnmarshalWithKeySize decodes an inner token request whose blinded message is
+// tokenKeySize bytes long. The encoding does not carry the size, so it must
+// come from the token key the request is for.
+func (r *InnerTokenRequest) UnmarshalWithKeySize(data []byte, tokenKeySize int) bool {
 	s := cryptobyte.String(data)
 
-	if !s.ReadUint8(&r.tokenKeyId) || !s.ReadBytes(&r.blindedMsg, 256) {
+	if !s.ReadUint8(&r.tokenKeyId) || !s.ReadBytes(&r.blindedMsg, tokenKeySize) {
 		return false
 	}
 
 	var paddedOriginName cryptobyte.String
-	if !s.ReadUint16LengthPrefixed(&paddedOriginName) {
+	if !s.ReadUint16LengthPrefixed(&paddedOriginName) || !s.Empty() {
 		return false
 	}
 	r.paddedOrigin = make([]byte, len(paddedOriginName))
@@ -41,3 +77,14 @@ func (r *InnerTokenRequest) Unmarshal(data []byte) bool {
 
 	return true
 }
+
+// UnmarshalInnerTokenRequest decodes an inner token request as
+// UnmarshalWithKeySize does,
+// returning an error matching tokens.ErrMalformedEncoding if it is invalid.
+func UnmarshalInnerTokenRequest(data []byte, tokenKeySize int) (InnerTokenRequest, error) {
+	var r InnerTokenRequest
+	if !r.UnmarshalWithKeySize(data, tokenKeySize) {
+		return InnerTokenRequest{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid InnerTokenRequest encoding")
+	}
+	return r, nil
+}
@@ -1,17 +1,19 @@
 package type3
 
 import (
+	"bytes"
+	"context"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/sha512"
-	"fmt"
 	"math/big"
 
 	hpke "github.com/cisco/go-hpke"
 	"github.com/cloudflare/circl/blindsign/blindrsa"
 	"github.com/cloudflare/pat-go/ecdsa"
+	"github.com/cloudflare/pat-go/tokens"
 	"github.com/cloudflare/pat-go/util"
 	"golang.org/x/crypto/cryptobyte"
 )
@@ -21,6 +23,7 @@ type RateLimitedIssuer struct {
 	nameKey         PrivateEncapKey
 	tokenKey        *rsa.PrivateKey
 	originIndexKeys map[string]*ecdsa.PrivateKey
+	observer        tokens.Observer
 }
 
 func NewRateLimitedIssuer(key *rsa.PrivateKey) *RateLimitedIssuer {
@@ -43,6 +46,12 @@ func NewRateLimitedIssuer(key *rsa.PrivateKey) *RateLimitedIssuer {
 		privateKey: privateKey,
 	}
 
+	return NewRateLimitedIssuerWithNameKey(key, nameKey)
+}
+
+// NewRateLimitedIssuerWithNameKey creates an issuer that decrypts origin token
+// requests with the given name key rather than a freshly generated one.
+func NewRateLimitedIssuerWithNameKey(key *rsa.PrivateKey, nameKey PrivateEncapKey) *RateLimitedIssuer {
 	return &RateLimitedIssuer{
 		curve:           elliptic.P384(),
 		nameKey:         nameKey,
@@ -51,6 +60,11 @@ func NewRateLimitedIssuer(key *rsa.PrivateKey) *RateLimitedIssuer {
 	}
 }
 
+// SetObserver sets the observer notified of each call to Evaluate.
+func (i *RateLimitedIssuer) SetObserver(observer tokens.Observer) {
+	i.observer = observer
+}
+
 func (i *RateLimitedIssuer) NameKey() EncapKey {
 	return i.nameKey.Public()
 }
@@ -100,7 +114,17 @@ func max(a, b int) int {
 	return b
 }
 
-func decryptOriginTokenRequest(nameKey PrivateEncapKey, requestKey []byte, encryptedTokenRequest []byte) (InnerTokenRequest, []byte, error) {
+// DecryptOriginTokenRequest decrypts an encrypted token request for a 2048-bit
+// token key with the issuer name key. It returns the inner token request and
+// the secret used to encrypt the response.
+func DecryptOriginTokenRequest(nameKey PrivateEncapKey, requestKey []byte, encryptedTokenRequest []byte) (InnerTokenRequest, []byte, error) {
+	return DecryptOriginTokenRequestWithKeySize(nameKey, defaultTokenKeySize, requestKey, encryptedTokenRequest)
+}
+
+// DecryptOriginTokenRequestWithKeySize is like DecryptOriginTokenRequest, but
+// requires the blinded message of the inner token request to be tokenKeySize
+// bytes long.
+func DecryptOriginTokenRequestWithKeySize(nameKey PrivateEncapKey, tokenKeySize int, requestKey []byte, encryptedTokenRequest []byte) (InnerTokenRequest, []byte, error) {
 	issuerConfigID := sha256.Sum256(nameKey.Public().Marshal())
 
 	// Decrypt the origin name
@@ -114,53 +138,94 @@ func decryptOriginTokenRequest(nameKey PrivateEncapKey, requestKey []byte, encry
 	b.AddBytes(issuerConfigID[:])
 	aad := b.BytesOrPanic()
 
+	if len(encryptedTokenRequest) < nameKey.suite.KEM.PublicKeySize() {
+		return InnerTokenRequest{}, nil, tokens.Errorf(tokens.ErrMalformedEncoding, "malformed encrypted token request")
+	}
 	enc := encryptedTokenRequest[0:nameKey.suite.KEM.PublicKeySize()]
 	ct := encryptedTokenRequest[nameKey.suite.KEM.PublicKeySize():]
 
 	context, err := hpke.SetupBaseR(nameKey.suite, nameKey.privateKey, enc, []byte("TokenRequest"))
 	if err != nil {
-		return InnerTokenRequest{}, nil, err
+		return InnerTokenRequest{}, nil, tokens.Errorf(tokens.ErrMalformedEncoding, "malformed encrypted token request: %w", err)
 	}
 
 	tokenRequestEnc, err := context.Open(aad, ct)
 	if err != nil {
-		return InnerTokenRequest{}, nil, err
+		return InnerTokenRequest{}, nil, tokens.Errorf(tokens.ErrMalformedEncoding, "malformed encrypted token request: %w", err)
 	}
 
 	tokenRequest := &InnerTokenRequest{}
-	if !tokenRequest.Unmarshal(tokenRequestEnc) {
-		return InnerTokenRequest{}, nil, err
+	if !tokenRequest.UnmarshalWithKeySize(tokenRequestEnc, tokenKeySize) {
+		return InnerTokenRequest{}, nil, tokens.Errorf(tokens.ErrMalformedEncoding, "malformed inner token request")
 	}
 
 	secret := context.Export([]byte("TokenResponse"), nameKey.suite.AEAD.KeySize())
 
-	return *tokenRequest, secret, err
+	return *tokenRequest, secret, nil
 }
 
 // https://ietf-wg-privacypass.github.io/draft-ietf-privacypass-rate-limit-tokens/draft-ietf-privacypass-rate-limit-tokens.html#name-issuer-to-attester-response
 func (i RateLimitedIssuer) Evaluate(encodedRequest []byte) ([]byte, []byte, error) {
+	return i.EvaluateContext(context.Background(), encodedRequest)
+}
+
+// EvaluateContext is like Evaluate, but returns ctx.Err() if ctx is done
+// before the token request is decrypted, before its signature is verified or
+// before the blind signature computation starts.
+func (i RateLimitedIssuer) EvaluateContext(ctx context.Context, encodedRequest []byte) ([]byte, []byte, error) {
+	tokenKeyID := i.TokenKeyID()
+	done := tokens.ObserveRequest(i.observer, tokens.RequestInfo{
+		Operation: tokens.OperationEvaluate,
+		TokenType: RateLimitedTokenType,
+		KeyID:     tokenKeyID,
+	})
+	encryptedTokenResponse, blindedRequestKey, err := i.evaluate(ctx, tokenKeyID, encodedRequest)
+	done(err)
+	return encryptedTokenResponse, blindedRequestKey, err
+}
+
+func (i RateLimitedIssuer) evaluate(ctx context.Context, tokenKeyID, encodedRequest []byte) ([]byte, []byte, error) {
 	req := &RateLimitedTokenRequest{}
 	if !req.Unmarshal(encodedRequest) {
-		return nil, nil, fmt.Errorf("malformed request")
+		return nil, nil, tokens.Errorf(tokens.ErrMalformedEncoding, "malformed request")
+	}
+
+	nameKeyID := sha256.Sum256(i.nameKey.Public().Marshal())
+	if !bytes.Equal(req.NameKeyID, nameKeyID[:]) {
+		return nil, nil, tokens.Errorf(tokens.ErrUnknownKey, "unknown issuer name key ID %x", req.NameKeyID)
+	}
+
+	tokenKeySize, err := util.TokenKeySize(&i.tokenKey.PublicKey)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Recover and validate the origin name
-	originTokenRequest, secret, err := decryptOriginTokenRequest(i.nameKey, req.RequestKey, req.EncryptedTokenRequest)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	originTokenRequest, secret, err := DecryptOriginTokenRequestWithKeySize(i.nameKey, tokenKeySize, req.RequestKey, req.EncryptedTokenRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if originTokenRequest.tokenKeyId != tokenKeyID[0] {
+		return nil, nil, tokens.Errorf(tokens.ErrUnknownKey, "unknown token key ID %02x", originTokenRequest.tokenKeyId)
+	}
+	originName, err := unpadOriginName(originTokenRequest.paddedOrigin)
 	if err != nil {
 		return nil, nil, err
 	}
-	originName := unpadOriginName(originTokenRequest.paddedOrigin)
 
 	// Check to see if it's a registered origin
 	originIndexKey, ok := i.originIndexKeys[originName]
 	if !ok {
-		return nil, nil, fmt.Errorf("unknown origin: %s", originName)
+		return nil, nil, tokens.Errorf(tokens.ErrUnknownOrigin, "unknown origin: %s", originName)
 	}
 
 	// Deserialize the request key
-	requestKey, err := unmarshalPublicKey(i.curve, req.RequestKey)
+	requestKey, err := ecdsa.UnmarshalCompressed(i.curve, req.RequestKey)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid request key: %w", err)
 	}
 
 	scalarLen := (i.curve.Params().Params().BitSize + 7) / 8
@@ -181,23 +246,32 @@ func (i RateLimitedIssuer) Evaluate(encodedRequest []byte) ([]byte, []byte, erro
 	hash.Write(message)
 	digest := hash.Sum(nil)
 
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	valid := ecdsa.Verify(requestKey, digest, r, s)
 	if !valid {
-		return nil, nil, fmt.Errorf("invalid request signature")
+		return nil, nil, tokens.Errorf(tokens.ErrSignatureInvalid, "invalid request signature")
 	}
 
 	// Compute the request key
 	b = cryptobyte.NewBuilder(nil)
 	b.AddUint16(RateLimitedTokenType)
 	b.AddBytes([]byte("IssuerBlind"))
-	ctx := b.BytesOrPanic()
-	blindedRequestKey, err := ecdsa.BlindPublicKeyWithContext(i.curve, requestKey, originIndexKey, ctx)
+	blindContext := b.BytesOrPanic()
+	blindedRequestKey, err := ecdsa.BlindPublicKeyWithContext(i.curve, requestKey, originIndexKey, blindContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	blindedRequestKeyEnc, err := ecdsa.MarshalCompressed(blindedRequestKey)
 	if err != nil {
 		return nil, nil, err
 	}
-	blindedRequestKeyEnc := elliptic.MarshalCompressed(i.curve, blindedRequestKey.X, blindedRequestKey.Y)
 
 	// Compute the blinded signature
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	signer := blindrsa.NewRSASigner(i.tokenKey)
 	blindSignature, err := signer.BlindSign(originTokenRequest.blindedMsg)
 	if err != nil {
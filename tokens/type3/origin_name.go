@@ -0,0 +1,133 @@
+package type3
+
+import (
+	"fmt"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+// OriginNamePadding selects how the client pads the origin name before
+// encrypting it to the issuer. The issuer accepts every policy.
+type OriginNamePadding int
+
+const (
+	// PadOriginNameBlock32 pads the origin name to the next multiple of 32 bytes.
+	PadOriginNameBlock32 OriginNamePadding = iota
+	// PadOriginNameFixed256 pads every origin name to exactly 256 bytes.
+	PadOriginNameFixed256
+	// PadOriginNamePowerOfTwo pads the origin name to the next power of two, with a minimum of 32 bytes.
+	PadOriginNamePowerOfTwo
+)
+
+const (
+	originNameBlockSize     = 32
+	originNameFixedSize     = 256
+	originNameMinBucketSize = 32
+)
+
+var originNamePaddingPolicies = []OriginNamePadding{
+	PadOriginNameBlock32,
+	PadOriginNameFixed256,
+	PadOriginNamePowerOfTwo,
+}
+
+func (p OriginNamePadding) String() string {
+	switch p {
+	case PadOriginNameBlock32:
+		return "block32"
+	case PadOriginNameFixed256:
+		return "fixed256"
+	case PadOriginNamePowerOfTwo:
+		return "power_of_two"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// OriginNamePaddingPolicies returns every supported origin name padding policy.
+func OriginNamePaddingPolicies() []OriginNamePadding {
+	policies := make([]OriginNamePadding, len(originNamePaddingPolicies))
+	copy(policies, originNamePaddingPolicies)
+	return policies
+}
+
+// ParseOriginNamePadding returns the padding policy with the given name.
+func ParseOriginNamePadding(name string) (OriginNamePadding, error) {
+	for _, p := range originNamePaddingPolicies {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown origin name padding policy: %s", name)
+}
+
+// PaddedLength returns the length of an origin name of nameLen bytes once padded.
+func (p OriginNamePadding) PaddedLength(nameLen int) (int, error) {
+	switch p {
+	case PadOriginNameBlock32:
+		if nameLen == 0 {
+			return originNameBlockSize, nil
+		}
+		return ((nameLen + originNameBlockSize - 1) / originNameBlockSize) * originNameBlockSize, nil
+	case PadOriginNameFixed256:
+		if nameLen > originNameFixedSize {
+			return 0, fmt.Errorf("origin name too long for fixed padding: %d bytes", nameLen)
+		}
+		return originNameFixedSize, nil
+	case PadOriginNamePowerOfTwo:
+		bucket := originNameMinBucketSize
+		for bucket < nameLen {
+			bucket <<= 1
+		}
+		return bucket, nil
+	default:
+		return 0, fmt.Errorf("unknown origin name padding policy: %d", int(p))
+	}
+}
+
+func padOriginName(originName string, padding OriginNamePadding) ([]byte, error) {
+	for i := 0; i < len(originName); i++ {
+		if originName[i] == 0x00 {
+			return nil, fmt.Errorf("origin name contains a zero byte")
+		}
+	}
+
+	paddedLen, err := padding.PaddedLength(len(originName))
+	if err != nil {
+		return nil, err
+	}
+	if paddedLen > 0xFFFF {
+		return nil, fmt.Errorf("origin name too long: %d bytes", len(originName))
+	}
+
+	paddedOriginName := make([]byte, paddedLen)
+	copy(paddedOriginName, originName)
+	return paddedOriginName, nil
+}
+
+// unpadOriginName removes the zero padding from an origin name. The padding
+// must consist only of zero bytes and the padded length must match one of the
+// supported padding policies.
+func unpadOriginName(paddedOriginName []byte) (string, error) {
+	nameLen := len(paddedOriginName)
+	for i, b := range paddedOriginName {
+		if b == 0x00 {
+			nameLen = i
+			break
+		}
+	}
+	for _, b := range paddedOriginName[nameLen:] {
+		if b != 0x00 {
+			return "", tokens.Errorf(tokens.ErrMalformedEncoding, "invalid origin name padding")
+		}
+	}
+
+	for _, p := range originNamePaddingPolicies {
+		paddedLen, err := p.PaddedLength(nameLen)
+		if err == nil && paddedLen == len(paddedOriginName) {
+			return string(paddedOriginName[:nameLen]), nil
+		}
+	}
+
+	return "", tokens.Errorf(tokens.ErrMalformedEncoding, "invalid origin name padding length: %d", len(paddedOriginName))
+}
@@ -0,0 +1,118 @@
+package type3
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOriginNamePaddingRoundTrip(t *testing.T) {
+	for _, padding := range originNamePaddingPolicies {
+		for _, nameLen := range []int{0, 1, 31, 32, 33, 64, 100, 200, 256} {
+			originName := strings.Repeat("a", nameLen)
+			paddedOriginName, err := padOriginName(originName, padding)
+			if err != nil {
+				t.Fatalf("%s: failed padding %d byte origin name: %v", padding, nameLen, err)
+			}
+
+			expectedLen, err := padding.PaddedLength(nameLen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paddedOriginName) != expectedLen {
+				t.Fatalf("%s: padded length mismatch: got %d, expected %d", padding, len(paddedOriginName), expectedLen)
+			}
+
+			recoveredOriginName, err := unpadOriginName(paddedOriginName)
+			if err != nil {
+				t.Fatalf("%s: failed unpadding %d byte origin name: %v", padding, nameLen, err)
+			}
+			if recoveredOriginName != originName {
+				t.Fatalf("%s: origin name mismatch: got %s, expected %s", padding, recoveredOriginName, originName)
+			}
+		}
+	}
+}
+
+func TestOriginNamePaddingLengths(t *testing.T) {
+	cases := []struct {
+		padding  OriginNamePadding
+		nameLen  int
+		expected int
+	}{
+		{PadOriginNameBlock32, 0, 32},
+		{PadOriginNameBlock32, 12, 32},
+		{PadOriginNameBlock32, 33, 64},
+		{PadOriginNameFixed256, 0, 256},
+		{PadOriginNameFixed256, 200, 256},
+		{PadOriginNamePowerOfTwo, 0, 32},
+		{PadOriginNamePowerOfTwo, 33, 64},
+		{PadOriginNamePowerOfTwo, 65, 128},
+		{PadOriginNamePowerOfTwo, 300, 512},
+	}
+
+	for _, c := range cases {
+		paddedLen, err := c.padding.PaddedLength(c.nameLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paddedLen != c.expected {
+			t.Fatalf("%s: padded length of %d byte name is %d, expected %d", c.padding, c.nameLen, paddedLen, c.expected)
+		}
+	}
+}
+
+func TestOriginNamePaddingFailures(t *testing.T) {
+	if _, err := padOriginName(strings.Repeat("a", 257), PadOriginNameFixed256); err == nil {
+		t.Fatal("Expected fixed padding of an oversized origin name to fail")
+	}
+	if _, err := padOriginName("origin\x00.example", PadOriginNameBlock32); err == nil {
+		t.Fatal("Expected padding of an origin name with a zero byte to fail")
+	}
+
+	paddedOriginName, err := padOriginName("origin.example", PadOriginNameBlock32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Non-zero byte in the padding
+	corrupted := make([]byte, len(paddedOriginName))
+	copy(corrupted, paddedOriginName)
+	corrupted[len(corrupted)-1] = 0x01
+	if _, err := unpadOriginName(corrupted); err == nil {
+		t.Fatal("Expected unpadding with non-zero padding to fail")
+	}
+
+	// Padded length that matches none of the padding policies
+	if _, err := unpadOriginName(append(paddedOriginName, 0x00)); err == nil {
+		t.Fatal("Expected unpadding with an invalid padded length to fail")
+	}
+	if _, err := unpadOriginName([]byte("origin.example")); err == nil {
+		t.Fatal("Expected unpadding of an unpadded origin name to fail")
+	}
+}
+
+func TestRateLimitedIssuanceOriginNamePadding(t *testing.T) {
+	for _, padding := range originNamePaddingPolicies {
+		issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+		testOrigin := "origin.example"
+		issuer.AddOrigin(testOrigin)
+
+		secret := make([]byte, 48)
+		secret[47] = 0x01
+		blind := make([]byte, 48)
+		blind[47] = 0x02
+		client := NewRateLimitedClientFromSecretWithPadding(secret, padding)
+
+		challenge := make([]byte, 32)
+		nonce := make([]byte, 32)
+		requestState, err := client.CreateTokenRequest(challenge, nonce, blind, issuer.TokenKeyID(), issuer.TokenKey(), testOrigin, issuer.NameKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = issuer.Evaluate(requestState.Request().Marshal())
+		if err != nil {
+			t.Fatalf("%s: %v", padding, err)
+		}
+	}
+}
@@ -1,23 +1,48 @@
 package type3
 
 import (
-	"fmt"
+	"crypto/rsa"
 
 	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/util"
 	"golang.org/x/crypto/cryptobyte"
 )
 
-func UnmarshalToken(data []byte) (tokens.Token, error) {
+func unmarshalToken(data []byte, nk int) (tokens.Token, error) {
 	s := cryptobyte.String(data)
 
 	token := tokens.Token{}
 	if !s.ReadUint16(&token.TokenType) ||
 		!s.ReadBytes(&token.Nonce, 32) ||
 		!s.ReadBytes(&token.Context, 32) ||
-		!s.ReadBytes(&token.KeyID, 32) ||
-		!s.ReadBytes(&token.Authenticator, 256) {
-		return tokens.Token{}, fmt.Errorf("invalid Token encoding")
+		!s.ReadBytes(&token.KeyID, 32) {
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid Token encoding")
+	}
+	if nk < 0 {
+		nk = len(s)
+		if !util.IsSupportedTokenKeySize(nk) {
+			return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid Token encoding")
+		}
+	}
+	if !s.ReadBytes(&token.Authenticator, nk) || !s.Empty() {
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid Token encoding")
 	}
 
 	return token, nil
 }
+
+// UnmarshalToken decodes a token whose authenticator is the size of a
+// supported token key. Use UnmarshalTokenWithKey when the token key is known.
+func UnmarshalToken(data []byte) (tokens.Token, error) {
+	return unmarshalToken(data, -1)
+}
+
+// UnmarshalTokenWithKey decodes a token whose authenticator is the size of
+// tokenKey.
+func UnmarshalTokenWithKey(data []byte, tokenKey *rsa.PublicKey) (tokens.Token, error) {
+	nk, err := util.TokenKeySize(tokenKey)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	return unmarshalToken(data, nk)
+}
@@ -3,6 +3,7 @@ package type3
 import (
 	"bytes"
 
+	"github.com/cloudflare/pat-go/tokens"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -77,3 +78,13 @@ func (r *RateLimitedTokenRequest) Unmarshal(data []byte) bool {
 
 	return true
 }
+
+// UnmarshalRateLimitedTokenRequest decodes a token request, returning an
+// error matching tokens.ErrMalformedEncoding if it is invalid.
+func UnmarshalRateLimitedTokenRequest(data []byte) (RateLimitedTokenRequest, error) {
+	var r RateLimitedTokenRequest
+	if !r.Unmarshal(data) {
+		return RateLimitedTokenRequest{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid RateLimitedTokenRequest encoding")
+	}
+	return r, nil
+}
@@ -2,6 +2,7 @@ package type3
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -12,6 +13,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -22,6 +24,7 @@ import (
 
 	"github.com/cloudflare/pat-go/ecdsa"
 	"github.com/cloudflare/pat-go/ed25519"
+	"github.com/cloudflare/pat-go/tokens"
 )
 
 // 2048-bit RSA private key
@@ -109,6 +112,34 @@ func (c MemoryClientStateCache) Put(clientID string, state *ClientState) {
 	c.cache[clientID] = state
 }
 
+type contextKey struct{}
+
+// contextClientStateCache is a MemoryClientStateCache that records the value of
+// contextKey in the contexts it is called with.
+type contextClientStateCache struct {
+	MemoryClientStateCache
+	gets []interface{}
+	puts []interface{}
+}
+
+func (c *contextClientStateCache) GetContext(ctx context.Context, clientID string) (*ClientState, bool, error) {
+	c.gets = append(c.gets, ctx.Value(contextKey{}))
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	state, ok := c.Get(clientID)
+	return state, ok, nil
+}
+
+func (c *contextClientStateCache) PutContext(ctx context.Context, clientID string, state *ClientState) error {
+	c.puts = append(c.puts, ctx.Value(contextKey{}))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Put(clientID, state)
+	return nil
+}
+
 func TestSignatureDifferences(t *testing.T) {
 	_, secretKey, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
@@ -133,6 +164,66 @@ func TestSignatureDifferences(t *testing.T) {
 	}
 }
 
+func TestRateLimitedAttesterRejectsInvalidRequest(t *testing.T) {
+	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+	testOrigin := "origin.example"
+	issuer.AddOrigin(testOrigin)
+
+	curve := elliptic.P384()
+	clientSecretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRateLimitedClientFromSecret(clientSecretKey.D.Bytes())
+	attester := NewRateLimitedAttester(NewMemoryClientStateCache())
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	anonymousOriginID := make([]byte, 32)
+	rand.Reader.Read(anonymousOriginID)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, requestKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), testOrigin, issuer.NameKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := *requestState.Request()
+
+	publicKeyEnc := elliptic.MarshalCompressed(curve, client.secretKey.PublicKey.X, client.secretKey.PublicKey.Y)
+	otherKeyEnc := elliptic.MarshalCompressed(curve, otherKey.PublicKey.X, otherKey.PublicKey.Y)
+
+	if err := attester.VerifyRequest(request, requestKey.D.Bytes(), publicKeyEnc, anonymousOriginID); err != nil {
+		t.Fatal(err)
+	}
+	if err := attester.VerifyRequest(request, otherKey.D.Bytes(), publicKeyEnc, anonymousOriginID); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Errorf("request with the wrong blind: %v, expected an invalid signature error", err)
+	}
+	if err := attester.VerifyRequest(request, requestKey.D.Bytes(), otherKeyEnc, anonymousOriginID); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Errorf("request for the wrong client key: %v, expected an invalid signature error", err)
+	}
+
+	tampered := request
+	tampered.Signature = append([]byte{}, request.Signature...)
+	tampered.Signature[len(tampered.Signature)-1] ^= 0x01
+	if err := attester.VerifyRequest(tampered, requestKey.D.Bytes(), publicKeyEnc, anonymousOriginID); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Errorf("request with an invalid signature: %v, expected an invalid signature error", err)
+	}
+
+	tampered.Signature = request.Signature[:len(request.Signature)-1]
+	if err := attester.VerifyRequest(tampered, requestKey.D.Bytes(), publicKeyEnc, anonymousOriginID); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Errorf("request with a truncated signature: %v, expected a malformed encoding error", err)
+	}
+}
+
 func TestRateLimitedIssuanceRoundTrip(t *testing.T) {
 	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
 	testOrigin := "origin.example"
@@ -185,7 +276,7 @@ func TestRateLimitedIssuanceRoundTrip(t *testing.T) {
 	}
 	expectedIndexKeyEnc := elliptic.MarshalCompressed(curve, expectedIndexKey.X, expectedIndexKey.Y)
 
-	expectedIndex, err := computeIndex(publicKeyEnc, expectedIndexKeyEnc)
+	expectedIndex, err := ComputeIndex(publicKeyEnc, expectedIndexKeyEnc)
 	if err != nil {
 		t.Error(err)
 	}
@@ -302,8 +393,422 @@ func TestRateLimitedIssuerOriginRepeatFailure(t *testing.T) {
 
 	publicKeyEnc = elliptic.MarshalCompressed(curve, client.secretKey.PublicKey.X, client.secretKey.PublicKey.Y)
 	_, err = attester.FinalizeIndex(publicKeyEnc, blindKey.D.Bytes(), blindedPublicKey, anonymousOriginIDB)
+	if !errors.Is(err, tokens.ErrRateLimited) {
+		t.Errorf("Expected failure due to origin index repeat, got %v", err)
+	}
+}
+
+func TestRateLimitedVerifyForOrigin(t *testing.T) {
+	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+	testOrigin := "origin.example"
+	issuer.AddOrigin(testOrigin)
+	verifier := NewRateLimitedVerifier(issuer.TokenKey())
+
+	curve := elliptic.P384()
+	secretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRateLimitedClientFromSecret(secretKey.D.Bytes())
+
+	tokenChallenge := tokens.TokenChallenge{
+		TokenType:  RateLimitedTokenType,
+		IssuerName: "issuer.example",
+		OriginInfo: []string{testOrigin},
+	}
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, blindKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), testOrigin, issuer.NameKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindedSignature, _, err := issuer.Evaluate(requestState.Request().Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifier.VerifyForOrigin(token, tokenChallenge, "ORIGIN.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifier.VerifyForOrigin(token, tokenChallenge, "other.example")
 	if err == nil {
-		t.Error("Expected failure due to origin index repeat, but didn't fail")
+		t.Fatal("Expected verification at an unlisted origin to fail")
+	}
+}
+
+func TestRateLimitedIssuerErrorKinds(t *testing.T) {
+	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+	testOrigin := "origin.example"
+	issuer.AddOrigin(testOrigin)
+
+	curve := elliptic.P384()
+	secretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRateLimitedClientFromSecret(secretKey.D.Bytes())
+	attester := NewRateLimitedAttester(NewMemoryClientStateCache())
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	createRequest := func(origin string, nameKey EncapKey) RateLimitedTokenRequestState {
+		requestState, err := client.CreateTokenRequest(challenge, nonce, blindKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), origin, nameKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return requestState
+	}
+	requestState := createRequest(testOrigin, issuer.NameKey())
+	requestEnc := requestState.Request().Marshal()
+
+	if _, err := UnmarshalRateLimitedTokenRequest(requestEnc[:len(requestEnc)-1]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalRateLimitedTokenRequest = %v, expected a malformed encoding error", err)
+	}
+	if _, _, err := issuer.Evaluate(requestEnc[:len(requestEnc)-1]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("Evaluate = %v, expected a malformed encoding error", err)
+	}
+
+	otherNameKey, err := CreatePrivateEncapKeyFromSeed(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRequest := createRequest(testOrigin, otherNameKey.Public())
+	if _, _, err := issuer.Evaluate(otherRequest.Request().Marshal()); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Evaluate = %v, expected an unknown key error", err)
+	}
+
+	otherRequest = createRequest("other.example", issuer.NameKey())
+	if _, _, err := issuer.Evaluate(otherRequest.Request().Marshal()); !errors.Is(err, tokens.ErrUnknownOrigin) {
+		t.Fatalf("Evaluate = %v, expected an unknown origin error", err)
+	}
+
+	request, err := UnmarshalRateLimitedTokenRequest(requestEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Signature = append([]byte{}, request.Signature...)
+	request.Signature[len(request.Signature)-1] ^= 0x01
+	if _, _, err := issuer.Evaluate(request.Marshal()); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Fatalf("Evaluate = %v, expected an invalid signature error", err)
+	}
+
+	blindedSignature, blindedPublicKey, err := issuer.Evaluate(requestEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKeyEnc := elliptic.MarshalCompressed(curve, secretKey.PublicKey.X, secretKey.PublicKey.Y)
+	if _, err := attester.FinalizeIndex(clientKeyEnc, blindKey.D.Bytes(), blindedPublicKey, make([]byte, 32)); !errors.Is(err, tokens.ErrUnknownClient) {
+		t.Fatalf("FinalizeIndex = %v, expected an unknown client error", err)
+	}
+
+	if _, err := attester.FinalizeIndex(clientKeyEnc, blindKey.D.Bytes(), blindedPublicKey[:1], make([]byte, 32)); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("FinalizeIndex = %v, expected a malformed encoding error", err)
+	}
+
+	if _, err := requestState.FinalizeToken(blindedSignature[:4]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("FinalizeToken = %v, expected a malformed encoding error", err)
+	}
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewRateLimitedVerifier(issuer.TokenKey())
+	token.Authenticator[0] ^= 0xFF
+	if err := verifier.Verify(token); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Fatalf("Verify = %v, expected an invalid signature error", err)
+	}
+	token.KeyID = make([]byte, 32)
+	if err := verifier.Verify(token); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Verify = %v, expected an unknown key error", err)
+	}
+}
+
+func TestRateLimitedObserver(t *testing.T) {
+	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+	testOrigin := "origin.example"
+	issuer.AddOrigin(testOrigin)
+	issuerObserver := tokens.NewMemoryObserver()
+	issuer.SetObserver(issuerObserver)
+
+	attester := NewRateLimitedAttester(NewMemoryClientStateCache())
+	attesterObserver := tokens.NewMemoryObserver()
+	attester.SetObserver(attesterObserver)
+
+	curve := elliptic.P384()
+	secretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRateLimitedClientFromSecret(secretKey.D.Bytes())
+	clientKeyEnc := elliptic.MarshalCompressed(curve, secretKey.PublicKey.X, secretKey.PublicKey.Y)
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+	anonymousOriginID := make([]byte, 32)
+	rand.Reader.Read(anonymousOriginID)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, blindKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), testOrigin, issuer.NameKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := attester.VerifyRequest(*requestState.Request(), blindKey.D.Bytes(), clientKeyEnc, anonymousOriginID); err != nil {
+		t.Fatal(err)
+	}
+	_, blindedRequestKey, err := issuer.Evaluate(requestState.Request().Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := issuer.Evaluate(nil); err == nil {
+		t.Fatal("evaluated an empty request")
+	}
+	if _, err := attester.FinalizeIndex(clientKeyEnc, blindKey.D.Bytes(), blindedRequestKey, anonymousOriginID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := issuerObserver.Completed(tokens.OperationEvaluate, RateLimitedTokenType); n != 2 {
+		t.Fatalf("issuer completed %d requests, expected 2", n)
+	}
+	if n := issuerObserver.Failed(tokens.OperationEvaluate, RateLimitedTokenType, tokens.ErrMalformedEncoding); n != 1 {
+		t.Fatalf("issuer had %d malformed encoding failures, expected 1", n)
+	}
+	if !bytes.Equal(issuerObserver.LastKeyID(tokens.OperationEvaluate, RateLimitedTokenType), issuer.TokenKeyID()) {
+		t.Fatal("issuer key ID mismatch")
+	}
+	for _, operation := range []tokens.Operation{tokens.OperationVerifyRequest, tokens.OperationFinalizeIndex} {
+		if n := attesterObserver.Received(operation, RateLimitedTokenType); n != 1 {
+			t.Fatalf("attester received %d %s requests, expected 1", n, operation)
+		}
+		if n := attesterObserver.Completed(operation, RateLimitedTokenType); n != 1 {
+			t.Fatalf("attester completed %d %s requests, expected 1", n, operation)
+		}
+	}
+	if !bytes.Equal(attesterObserver.LastKeyID(tokens.OperationVerifyRequest, RateLimitedTokenType), requestState.Request().NameKeyID) {
+		t.Fatal("attester key ID mismatch")
+	}
+}
+
+func TestRateLimitedContext(t *testing.T) {
+	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+	testOrigin := "origin.example"
+	issuer.AddOrigin(testOrigin)
+
+	cache := &contextClientStateCache{MemoryClientStateCache: NewMemoryClientStateCache()}
+	attester := NewRateLimitedAttester(cache)
+
+	curve := elliptic.P384()
+	secretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRateLimitedClientFromSecret(secretKey.D.Bytes())
+	clientKeyEnc := elliptic.MarshalCompressed(curve, secretKey.PublicKey.X, secretKey.PublicKey.Y)
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+	anonymousOriginID := make([]byte, 32)
+	rand.Reader.Read(anonymousOriginID)
+
+	requestState, err := client.CreateTokenRequest(challenge, nonce, blindKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), testOrigin, issuer.NameKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := attester.VerifyRequestContext(cancelled, *requestState.Request(), blindKey.D.Bytes(), clientKeyEnc, anonymousOriginID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("VerifyRequestContext = %v, expected %v", err, context.Canceled)
+	}
+	if _, _, err := issuer.EvaluateContext(cancelled, requestState.Request().Marshal()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EvaluateContext = %v, expected %v", err, context.Canceled)
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "request")
+	if err := attester.VerifyRequestContext(ctx, *requestState.Request(), blindKey.D.Bytes(), clientKeyEnc, anonymousOriginID); err != nil {
+		t.Fatal(err)
+	}
+	_, blindedRequestKey, err := issuer.EvaluateContext(ctx, requestState.Request().Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := attester.FinalizeIndexContext(cancelled, clientKeyEnc, blindKey.D.Bytes(), blindedRequestKey, anonymousOriginID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FinalizeIndexContext = %v, expected %v", err, context.Canceled)
+	}
+	if _, err := attester.FinalizeIndexContext(ctx, clientKeyEnc, blindKey.D.Bytes(), blindedRequestKey, anonymousOriginID); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cache.gets) == 0 || len(cache.puts) == 0 {
+		t.Fatalf("cache called %d times for Get and %d times for Put, expected both", len(cache.gets), len(cache.puts))
+	}
+	for _, value := range append(cache.gets, cache.puts...) {
+		if value != "request" {
+			t.Fatalf("cache called with context value %v, expected %q", value, "request")
+		}
+	}
+}
+
+func generateTokenKey(t *testing.T, bits int) *rsa.PrivateKey {
+	if bits == 2048 {
+		return loadPrivateKey(t)
+	}
+	if testing.Short() {
+		t.Skip("skipping RSA key generation in short mode")
+	}
+	tokenKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenKey
+}
+
+func TestRateLimitedIssuanceKeySizes(t *testing.T) {
+	for _, bits := range []int{2048, 3072, 4096} {
+		t.Run(fmt.Sprintf("%d", bits), func(t *testing.T) {
+			issuer := NewRateLimitedIssuer(generateTokenKey(t, bits))
+			testOrigin := "origin.example"
+			issuer.AddOrigin(testOrigin)
+			verifier := NewRateLimitedVerifier(issuer.TokenKey())
+
+			curve := elliptic.P384()
+			secretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blindKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := NewRateLimitedClientFromSecret(secretKey.D.Bytes())
+
+			tokenChallenge := tokens.TokenChallenge{
+				TokenType:  RateLimitedTokenType,
+				IssuerName: "issuer.example",
+				OriginInfo: []string{testOrigin},
+			}
+			challenge, err := tokenChallenge.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nonce := make([]byte, 32)
+			rand.Reader.Read(nonce)
+
+			requestState, err := client.CreateTokenRequest(challenge, nonce, blindKey.D.Bytes(), issuer.TokenKeyID(), issuer.TokenKey(), testOrigin, issuer.NameKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			blindedSignature, _, err := issuer.Evaluate(requestState.Request().Marshal())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := requestState.FinalizeToken(blindedSignature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(token.Authenticator) != bits/8 {
+				t.Fatalf("unexpected authenticator length %d", len(token.Authenticator))
+			}
+			if err := verifier.VerifyForOrigin(token, tokenChallenge, testOrigin); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := UnmarshalTokenWithKey(token.Marshal(), issuer.TokenKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded.Marshal(), token.Marshal()) {
+				t.Fatal("token did not round trip")
+			}
+		})
+	}
+}
+
+func TestRateLimitedIssuerRejectsKeySizeMismatch(t *testing.T) {
+	issuer := NewRateLimitedIssuer(loadPrivateKey(t))
+	largerIssuer := NewRateLimitedIssuerWithNameKey(generateTokenKey(t, 3072), issuer.nameKey)
+	testOrigin := "origin.example"
+	issuer.AddOrigin(testOrigin)
+	largerIssuer.AddOrigin(testOrigin)
+
+	curve := elliptic.P384()
+	secretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRateLimitedClientFromSecret(secretKey.D.Bytes())
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	// The inner request carries a blinded message for the 3072-bit key, which
+	// the issuer holding the 2048-bit key must refuse to decode.
+	requestState, err := client.CreateTokenRequest(challenge, nonce, blindKey.D.Bytes(), largerIssuer.TokenKeyID(), largerIssuer.TokenKey(), testOrigin, largerIssuer.NameKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := issuer.Evaluate(requestState.Request().Marshal()); err == nil {
+		t.Fatal("evaluated a blinded message of the wrong size")
+	}
+
+	blindedSignature, _, err := largerIssuer.Evaluate(requestState.Request().Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeToken(blindedSignature[:len(blindedSignature)-1]); err == nil {
+		t.Fatal("finalized a truncated token response")
+	}
+	token, err := requestState.FinalizeToken(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalTokenWithKey(token.Marshal(), issuer.TokenKey()); err == nil {
+		t.Fatal("decoded a token with an authenticator of the wrong size")
 	}
 }
 
@@ -344,6 +849,7 @@ type rawOriginEncryptionTestVector struct {
 	TokenKeyID            uint8       `json:"token_key_id"`
 	BlindMessage          string      `json:"blinded_msg"`
 	OriginName            string      `json:"origin_name"`
+	OriginNamePadding     string      `json:"origin_name_padding"`
 	EncapSecret           string      `json:"encap_secret"`
 	EncryptedTokenRequest string      `json:"encrypted_token_request"`
 }
@@ -361,6 +867,7 @@ type originEncryptionTestVector struct {
 	blindMessage          []byte
 	issuerKeyID           []byte
 	originName            string
+	originNamePadding     OriginNamePadding
 	encryptedTokenRequest []byte
 	encapSecret           []byte
 }
@@ -399,6 +906,7 @@ func (etv originEncryptionTestVector) MarshalJSON() ([]byte, error) {
 		BlindMessage:          mustHex(etv.blindMessage),
 		OriginNameKeyID:       mustHex(etv.issuerKeyID),
 		OriginName:            mustHex([]byte(etv.originName)),
+		OriginNamePadding:     etv.originNamePadding.String(),
 		EncryptedTokenRequest: mustHex(etv.encryptedTokenRequest),
 		EncapSecret:           mustHex(etv.encapSecret),
 	})
@@ -437,13 +945,19 @@ func (etv *originEncryptionTestVector) UnmarshalJSON(data []byte) error {
 	etv.blindMessage = mustUnhex(nil, raw.BlindMessage)
 	etv.issuerKeyID = mustUnhex(nil, raw.OriginNameKeyID)
 	etv.originName = string(mustUnhex(nil, raw.OriginName))
+	if raw.OriginNamePadding != "" {
+		etv.originNamePadding, err = ParseOriginNamePadding(raw.OriginNamePadding)
+		if err != nil {
+			return err
+		}
+	}
 	etv.encryptedTokenRequest = mustUnhex(nil, raw.EncryptedTokenRequest)
 	etv.encapSecret = mustUnhex(nil, raw.EncapSecret)
 
 	return nil
 }
 
-func generateOriginEncryptionTestVector(t *testing.T, kemID hpke.KEMID, kdfID hpke.KDFID, aeadID hpke.AEADID) originEncryptionTestVector {
+func generateOriginEncryptionTestVector(t *testing.T, kemID hpke.KEMID, kdfID hpke.KDFID, aeadID hpke.AEADID, originName string, padding OriginNamePadding) originEncryptionTestVector {
 	ikm := make([]byte, 32)
 	rand.Reader.Read(ikm)
 	nameKey, err := CreatePrivateEncapKeyFromSeed(ikm)
@@ -459,8 +973,7 @@ func generateOriginEncryptionTestVector(t *testing.T, kemID hpke.KEMID, kdfID hp
 	blindMessage := make([]byte, 256)
 	rand.Reader.Read(blindMessage)
 
-	originName := "test.example"
-	_, encryptedTokenRequest, secret, err := encryptOriginTokenRequest(nameKey.Public(), tokenKeyIDBuf[0], blindMessage, requestKey, originName)
+	_, encryptedTokenRequest, secret, err := EncryptOriginTokenRequest(nameKey.Public(), tokenKeyIDBuf[0], blindMessage, requestKey, originName, padding)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -480,6 +993,7 @@ func generateOriginEncryptionTestVector(t *testing.T, kemID hpke.KEMID, kdfID hp
 		tokenKeyID:            tokenKeyIDBuf[0],
 		blindMessage:          blindMessage,
 		originName:            originName,
+		originNamePadding:     padding,
 		encryptedTokenRequest: encryptedTokenRequest,
 		encapSecret:           secret,
 	}
@@ -503,15 +1017,26 @@ func verifyOriginEncryptionTestVector(t *testing.T, vector originEncryptionTestV
 		t.Fatal(err)
 	}
 
-	originTokenRequest, _, err := decryptOriginTokenRequest(privateNameKey, vector.requestKey, vector.encryptedTokenRequest)
+	originTokenRequest, _, err := DecryptOriginTokenRequestWithKeySize(privateNameKey, len(vector.blindMessage), vector.requestKey, vector.encryptedTokenRequest)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	unpaddedOriginName := unpadOriginName(originTokenRequest.paddedOrigin)
+	unpaddedOriginName, err := unpadOriginName(originTokenRequest.paddedOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if unpaddedOriginName != vector.originName {
 		t.Fatalf("origin decryption mismatch: got %s, expected %s", unpaddedOriginName, vector.originName)
 	}
+
+	expectedPaddedLen, err := vector.originNamePadding.PaddedLength(len(vector.originName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(originTokenRequest.paddedOrigin) != expectedPaddedLen {
+		t.Fatalf("origin padding length mismatch: got %d, expected %d", len(originTokenRequest.paddedOrigin), expectedPaddedLen)
+	}
 }
 
 func verifyOriginEncryptionTestVectors(t *testing.T, encoded []byte) {
@@ -527,8 +1052,17 @@ func verifyOriginEncryptionTestVectors(t *testing.T, encoded []byte) {
 }
 
 func TestVectorGenerateOriginEncryption(t *testing.T) {
+	originNames := []string{
+		"test.example",
+		"a-considerably-longer-origin-name-used-to-cross-padding-boundaries.test.example",
+	}
+
 	vectors := make([]originEncryptionTestVector, 0)
-	vectors = append(vectors, generateOriginEncryptionTestVector(t, hpke.DHKEM_X25519, hpke.KDF_HKDF_SHA256, hpke.AEAD_AESGCM128))
+	for _, padding := range originNamePaddingPolicies {
+		for _, originName := range originNames {
+			vectors = append(vectors, generateOriginEncryptionTestVector(t, hpke.DHKEM_X25519, hpke.KDF_HKDF_SHA256, hpke.AEAD_AESGCM128, originName, padding))
+		}
+	}
 
 	// Encode the test vectors
 	encoded, err := json.Marshal(vectors)
@@ -684,7 +1218,7 @@ func generateAnonOriginIDTestVector(t *testing.T) anonOriginIDTestVector {
 	clientPublicKeyEnc := elliptic.MarshalCompressed(curve, clientSecretKey.X, clientSecretKey.Y)
 	indexKeyEnc := elliptic.MarshalCompressed(curve, indexKey.X, indexKey.Y)
 
-	index, err := computeIndex(clientPublicKeyEnc, indexKeyEnc)
+	index, err := ComputeIndex(clientPublicKeyEnc, indexKeyEnc)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -721,7 +1255,7 @@ func verifyAnonOriginIDTestVector(t *testing.T, vector anonOriginIDTestVector) {
 	clientPublicKeyEnc := elliptic.MarshalCompressed(vector.curve, vector.clientSecret.X, vector.clientSecret.Y)
 	indexKeyEnc := elliptic.MarshalCompressed(vector.curve, indexKey.X, indexKey.Y)
 
-	index, err := computeIndex(clientPublicKeyEnc, indexKeyEnc)
+	index, err := ComputeIndex(clientPublicKeyEnc, indexKeyEnc)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -860,3 +1394,25 @@ func BenchmarkRateLimitedTokenRoundTrip(b *testing.B) {
 		}
 	})
 }
+
+func TestInnerTokenRequestKeySizes(t *testing.T) {
+	for _, tokenKeySize := range []int{256, 384, 512} {
+		request := InnerTokenRequest{
+			tokenKeyId:   0x01,
+			blindedMsg:   make([]byte, tokenKeySize),
+			paddedOrigin: []byte("origin.example"),
+		}
+		requestEnc := request.Marshal()
+
+		var decoded InnerTokenRequest
+		if ok := decoded.Unmarshal(requestEnc); ok != (tokenKeySize == defaultTokenKeySize) {
+			t.Fatalf("Unmarshal of a request for a %d-byte key returned %v", tokenKeySize, ok)
+		}
+		if !decoded.UnmarshalWithKeySize(requestEnc, tokenKeySize) {
+			t.Fatalf("failed to decode a request for a %d-byte key", tokenKeySize)
+		}
+		if !bytes.Equal(decoded.Marshal(), requestEnc) {
+			t.Fatal("inner token request encoding mismatch")
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package type3
+
+import (
+	"crypto/rsa"
+
+	"github.com/cloudflare/pat-go/tokens"
+)
+
+// RateLimitedVerifier verifies rate-limited tokens using only the
+// issuer's public token key, as an origin would.
+type RateLimitedVerifier struct {
+	tokenKey *rsa.PublicKey
+}
+
+func NewRateLimitedVerifier(key *rsa.PublicKey) RateLimitedVerifier {
+	return RateLimitedVerifier{
+		tokenKey: key,
+	}
+}
+
+// Verify checks that the token was issued under the verifier's key and checks
+// the token signature.
+func (v RateLimitedVerifier) Verify(token tokens.Token) error {
+	return tokens.VerifyPublicToken(token, RateLimitedTokenType, v.tokenKey)
+}
+
+// VerifyForOrigin checks that token was issued for challenge, that the
+// challenge allows redemption at origin, and that the token signature is valid.
+func (v RateLimitedVerifier) VerifyForOrigin(token tokens.Token, challenge tokens.TokenChallenge, origin string) error {
+	return tokens.VerifyPublicTokenForOrigin(token, challenge, origin, RateLimitedTokenType, v.tokenKey)
+}
@@ -2,7 +2,6 @@ package typeF91A
 
 import (
 	"crypto/sha256"
-	"fmt"
 
 	"github.com/cloudflare/circl/group"
 	"github.com/cloudflare/circl/oprf"
@@ -30,27 +29,42 @@ func (s BatchedPrivateTokenRequestState) Request() *BatchedPrivateTokenRequest {
 	return s.request
 }
 
+// Blinds returns the encoded OPRF blinds used to create the request, which can
+// be passed to CreateTokenRequestWithBlinds to reproduce it.
+func (s BatchedPrivateTokenRequestState) Blinds() ([][]byte, error) {
+	blinds := s.verifier.CopyBlinds()
+	blindEncs := make([][]byte, len(blinds))
+	for i := 0; i < len(blinds); i++ {
+		blindEnc, err := blinds[i].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		blindEncs[i] = blindEnc
+	}
+	return blindEncs, nil
+}
+
 func (s BatchedPrivateTokenRequestState) FinalizeTokens(tokenResponseEnc []byte) ([]tokens.Token, error) {
 	var encodedElements cryptobyte.String
 	reader := cryptobyte.String(tokenResponseEnc)
 	if !reader.ReadUint16LengthPrefixed(&encodedElements) || encodedElements.Empty() {
-		return nil, fmt.Errorf("invalid batch token response list encoding")
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid batch token response list encoding")
 	}
 
 	elementLength := int(group.Ristretto255.Params().CompressedElementLength)
 	if len(encodedElements)%elementLength != 0 {
-		return nil, fmt.Errorf("invalid batch token response encoding")
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid batch token response encoding")
 	}
 	numElements := len(encodedElements) / elementLength
 	if numElements != len(s.tokenInputs) {
-		return nil, fmt.Errorf("invalid batch token response")
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid batch token response")
 	}
 	elements := make([]group.Element, numElements)
 	for i := 0; i < numElements; i++ {
 		elements[i] = group.Ristretto255.NewElement()
 		err := elements[i].UnmarshalBinary(encodedElements[i*elementLength : (i+1)*elementLength])
 		if err != nil {
-			return nil, err
+			return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid batch token response element: %w", err)
 		}
 	}
 
@@ -58,13 +72,13 @@ func (s BatchedPrivateTokenRequestState) FinalizeTokens(tokenResponseEnc []byte)
 	proofLength := int(2 * group.Ristretto255.Params().ScalarLength)
 	proofEnc := make([]byte, proofLength)
 	if !reader.ReadBytes(&proofEnc, proofLength) {
-		return nil, fmt.Errorf("invalid batch token response proof encoding")
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid batch token response proof encoding")
 	}
 
 	proof := new(dleq.Proof)
 	err := proof.UnmarshalBinary(group.Ristretto255, proofEnc)
 	if err != nil {
-		return nil, err
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid batch token response proof: %w", err)
 	}
 
 	evaluation := &oprf.Evaluation{
@@ -73,7 +87,7 @@ func (s BatchedPrivateTokenRequestState) FinalizeTokens(tokenResponseEnc []byte)
 	}
 	outputs, err := s.client.Finalize(s.verifier, evaluation)
 	if err != nil {
-		return nil, err
+		return nil, tokens.Errorf(tokens.ErrSignatureInvalid, "%w", err)
 	}
 
 	tokens := make([]tokens.Token, numElements)
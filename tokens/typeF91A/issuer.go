@@ -2,8 +2,11 @@ package typeF91A
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
-	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cloudflare/circl/group"
 	"github.com/cloudflare/circl/oprf"
@@ -11,8 +14,14 @@ import (
 	"golang.org/x/crypto/cryptobyte"
 )
 
+// DefaultMaxBatchSize is the largest number of token requests that a
+// BatchedPrivateIssuer evaluates in one batch unless configured otherwise.
+const DefaultMaxBatchSize = 1024
+
 type BatchedPrivateIssuer struct {
-	tokenKey *oprf.PrivateKey
+	tokenKey     *oprf.PrivateKey
+	observer     tokens.Observer
+	maxBatchSize int
 }
 
 func NewBatchedPrivateIssuer(key *oprf.PrivateKey) *BatchedPrivateIssuer {
@@ -34,18 +43,151 @@ func (i *BatchedPrivateIssuer) TokenKeyID() []byte {
 	return keyID[:]
 }
 
+// SetObserver sets the observer notified of each call to Evaluate.
+func (i *BatchedPrivateIssuer) SetObserver(observer tokens.Observer) {
+	i.observer = observer
+}
+
+// SetMaxBatchSize sets the largest number of token requests accepted in one
+// batch. EvaluateEncodedContext rejects larger batches before any element is
+// decoded; Evaluate and EvaluateContext reject them before evaluation. A size
+// of zero or less restores DefaultMaxBatchSize.
+func (i *BatchedPrivateIssuer) SetMaxBatchSize(maxBatchSize int) {
+	i.maxBatchSize = maxBatchSize
+}
+
+// MaxBatchSize returns the largest number of token requests accepted in one
+// batch.
+func (i *BatchedPrivateIssuer) MaxBatchSize() int {
+	if i.maxBatchSize <= 0 {
+		return DefaultMaxBatchSize
+	}
+	return i.maxBatchSize
+}
+
 func (i BatchedPrivateIssuer) Evaluate(req *BatchedPrivateTokenRequest) ([]byte, error) {
+	return i.EvaluateContext(context.Background(), req)
+}
+
+// EvaluateContext is like Evaluate, but stops and returns ctx.Err() once ctx
+// is done. The context is checked between the elements of the batch as they
+// are decoded and encoded, and before the OPRF evaluation.
+//
+// Elements are decoded and encoded in parallel, using up to GOMAXPROCS
+// goroutines.
+func (i BatchedPrivateIssuer) EvaluateContext(ctx context.Context, req *BatchedPrivateTokenRequest) ([]byte, error) {
+	tokenKeyID := i.TokenKeyID()
+	done := tokens.ObserveRequest(i.observer, tokens.RequestInfo{
+		Operation: tokens.OperationEvaluate,
+		TokenType: BatchedPrivateTokenType,
+		KeyID:     tokenKeyID,
+	})
+	tokenResponse, err := i.evaluate(ctx, tokenKeyID, req)
+	done(err)
+	return tokenResponse, err
+}
+
+// EvaluateEncodedContext decodes an encoded BatchedPrivateTokenRequest and
+// evaluates it like EvaluateContext. Requests with more than MaxBatchSize
+// elements are rejected from their length prefix, before any element is
+// decoded.
+func (i BatchedPrivateIssuer) EvaluateEncodedContext(ctx context.Context, data []byte) ([]byte, error) {
+	tokenKeyID := i.TokenKeyID()
+	done := tokens.ObserveRequest(i.observer, tokens.RequestInfo{
+		Operation: tokens.OperationEvaluate,
+		TokenType: BatchedPrivateTokenType,
+		KeyID:     tokenKeyID,
+	})
+	var req BatchedPrivateTokenRequest
+	if maxBatchSize := i.MaxBatchSize(); !req.UnmarshalWithMaxBatchSize(data, maxBatchSize) {
+		err := tokens.Errorf(tokens.ErrMalformedEncoding, "invalid BatchedPrivateTokenRequest encoding or more than %d token requests", maxBatchSize)
+		done(err)
+		return nil, err
+	}
+	tokenResponse, err := i.evaluate(ctx, tokenKeyID, &req)
+	done(err)
+	return tokenResponse, err
+}
+
+// forEach calls fn for each index in [0, n), spreading the calls across up to
+// GOMAXPROCS goroutines. It stops early once ctx is done or a call fails, and
+// returns ctx.Err() or the error of a failed call.
+func forEach(ctx context.Context, n int, fn func(i int) error) error {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Each worker handles a contiguous range of indices.
+	var failed int32
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*n/workers, (w+1)*n/workers
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				if atomic.LoadInt32(&failed) != 0 {
+					return
+				}
+				if err := ctx.Err(); err != nil {
+					errs[w] = err
+					atomic.StoreInt32(&failed, 1)
+					return
+				}
+				if err := fn(i); err != nil {
+					errs[w] = err
+					atomic.StoreInt32(&failed, 1)
+					return
+				}
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i BatchedPrivateIssuer) evaluate(ctx context.Context, tokenKeyID []byte, req *BatchedPrivateTokenRequest) ([]byte, error) {
+	numRequests := len(req.BlindedReq)
+	if maxBatchSize := i.MaxBatchSize(); numRequests > maxBatchSize {
+		return nil, tokens.Errorf(tokens.ErrMalformedEncoding, "batch of %d token requests exceeds the maximum of %d", numRequests, maxBatchSize)
+	}
+
+	if req.TokenKeyID != tokenKeyID[len(tokenKeyID)-1] {
+		return nil, tokens.Errorf(tokens.ErrUnknownKey, "unknown token key ID %02x", req.TokenKeyID)
+	}
+
 	server := oprf.NewVerifiableServer(oprf.SuiteRistretto255, i.tokenKey)
 
 	elementLength := int(oprf.SuiteRistretto255.Group().Params().CompressedElementLength)
-	numRequests := len(req.BlindedReq)
 	elements := make([]group.Element, numRequests)
-	for i := 0; i < numRequests; i++ {
-		elements[i] = group.Ristretto255.NewElement()
-		err := elements[i].UnmarshalBinary(req.BlindedReq[i])
-		if err != nil {
-			return nil, err
+	err := forEach(ctx, numRequests, func(j int) error {
+		elements[j] = group.Ristretto255.NewElement()
+		if err := elements[j].UnmarshalBinary(req.BlindedReq[j]); err != nil {
+			return tokens.Errorf(tokens.ErrMalformedEncoding, "invalid blinded element %d: %w", j, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Create the batch evaluation request
@@ -54,6 +196,9 @@ func (i BatchedPrivateIssuer) Evaluate(req *BatchedPrivateTokenRequest) ([]byte,
 	}
 
 	// Evaluate the input
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	evaluation, err := server.Evaluate(evalRequest)
 	if err != nil {
 		return nil, err
@@ -61,14 +206,18 @@ func (i BatchedPrivateIssuer) Evaluate(req *BatchedPrivateTokenRequest) ([]byte,
 
 	// Build TokenResponse
 	encodedElements := make([][]byte, numRequests)
-	for i := 0; i < numRequests; i++ {
-		encEvaluatedElement, err := evaluation.Elements[i].MarshalBinaryCompress()
+	err = forEach(ctx, numRequests, func(j int) error {
+		encEvaluatedElement, err := evaluation.Elements[j].MarshalBinaryCompress()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		encodedElements[i] = make([]byte, elementLength)
-		copy(encodedElements[i], encEvaluatedElement)
+		encodedElements[j] = make([]byte, elementLength)
+		copy(encodedElements[j], encEvaluatedElement)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	encProof, err := evaluation.Proof.MarshalBinary()
@@ -88,6 +237,10 @@ func (i BatchedPrivateIssuer) Evaluate(req *BatchedPrivateTokenRequest) ([]byte,
 }
 
 func (i BatchedPrivateIssuer) Verify(token tokens.Token) error {
+	if !bytes.Equal(token.KeyID, i.TokenKeyID()) {
+		return tokens.Errorf(tokens.ErrUnknownKey, "unknown token key ID %x", token.KeyID)
+	}
+
 	server := oprf.NewVerifiableServer(oprf.SuiteRistretto255, i.tokenKey)
 
 	tokenInput := token.AuthenticatorInput()
@@ -96,8 +249,20 @@ func (i BatchedPrivateIssuer) Verify(token tokens.Token) error {
 		return err
 	}
 	if !bytes.Equal(output, token.Authenticator) {
-		return fmt.Errorf("token authentication mismatch")
+		return tokens.Errorf(tokens.ErrSignatureInvalid, "token authentication mismatch")
 	}
 
 	return nil
 }
+
+// VerifyForOrigin checks that token was issued for challenge, that the
+// challenge allows redemption at origin, and that the token is authentic.
+func (i BatchedPrivateIssuer) VerifyForOrigin(token tokens.Token, challenge tokens.TokenChallenge, origin string) error {
+	if token.TokenType != BatchedPrivateTokenType {
+		return tokens.Errorf(tokens.ErrUnexpectedTokenType, "invalid token type: %04x", token.TokenType)
+	}
+	if err := tokens.VerifyTokenChallenge(token, challenge, origin); err != nil {
+		return err
+	}
+	return i.Verify(token)
+}
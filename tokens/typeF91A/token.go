@@ -1,8 +1,6 @@
 package typeF91A
 
 import (
-	"fmt"
-
 	"github.com/cloudflare/pat-go/tokens"
 	"golang.org/x/crypto/cryptobyte"
 )
@@ -16,7 +14,7 @@ func UnmarshalBatchedPrivateToken(data []byte) (tokens.Token, error) {
 		!s.ReadBytes(&token.Context, 32) ||
 		!s.ReadBytes(&token.KeyID, 32) ||
 		!s.ReadBytes(&token.Authenticator, 64) {
-		return tokens.Token{}, fmt.Errorf("invalid Token encoding")
+		return tokens.Token{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid Token encoding")
 	}
 
 	return token, nil
@@ -3,6 +3,7 @@ package typeF91A
 import (
 	"bytes"
 
+	"github.com/cloudflare/pat-go/tokens"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -53,6 +54,14 @@ func (r *BatchedPrivateTokenRequest) Marshal() []byte {
 }
 
 func (r *BatchedPrivateTokenRequest) Unmarshal(data []byte) bool {
+	return r.UnmarshalWithMaxBatchSize(data, 0)
+}
+
+// UnmarshalWithMaxBatchSize is like Unmarshal, but fails if the request holds
+// more than maxBatchSize blinded elements. The count is checked against the
+// length prefix, before any element is copied. A maxBatchSize of zero or less
+// means no limit.
+func (r *BatchedPrivateTokenRequest) UnmarshalWithMaxBatchSize(data []byte, maxBatchSize int) bool {
 	s := cryptobyte.String(data)
 
 	var tokenType uint16
@@ -71,6 +80,9 @@ func (r *BatchedPrivateTokenRequest) Unmarshal(data []byte) bool {
 	}
 
 	elementCount := len(blindedRequests) / 32
+	if maxBatchSize > 0 && elementCount > maxBatchSize {
+		return false
+	}
 	r.BlindedReq = make([][]byte, elementCount)
 	for i := 0; i < elementCount; i++ {
 		r.BlindedReq[i] = make([]byte, 32)
@@ -79,3 +91,13 @@ func (r *BatchedPrivateTokenRequest) Unmarshal(data []byte) bool {
 
 	return true
 }
+
+// UnmarshalBatchedPrivateTokenRequest decodes a token request, returning an
+// error matching tokens.ErrMalformedEncoding if it is invalid.
+func UnmarshalBatchedPrivateTokenRequest(data []byte) (BatchedPrivateTokenRequest, error) {
+	var r BatchedPrivateTokenRequest
+	if !r.Unmarshal(data) {
+		return BatchedPrivateTokenRequest{}, tokens.Errorf(tokens.ErrMalformedEncoding, "invalid BatchedPrivateTokenRequest encoding")
+	}
+	return r, nil
+}
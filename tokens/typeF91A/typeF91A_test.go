@@ -2,10 +2,13 @@ package typeF91A
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -18,8 +21,8 @@ import (
 )
 
 const (
-	outputBatchedPrivateIssuanceTestVectorEnvironmentKey = "TYPE19FA_ISSUANCE_TEST_VECTORS_OUT"
-	inputBatchedPrivateIssuanceTestVectorEnvironmentKey  = "TYPE19FA_ISSUANCE_TEST_VECTORS_IN"
+	outputBatchedPrivateIssuanceTestVectorEnvironmentKey = "TYPEF91A_ISSUANCE_TEST_VECTORS_OUT"
+	inputBatchedPrivateIssuanceTestVectorEnvironmentKey  = "TYPEF91A_ISSUANCE_TEST_VECTORS_IN"
 )
 
 func createTokenChallenge(tokenType uint16, redemptionContext []byte, issuerName string, originInfo []string) tokens.TokenChallenge {
@@ -77,6 +80,262 @@ func TestBatchedPrivateIssuanceRoundTrip(t *testing.T) {
 	}
 }
 
+func TestBatchedPrivateErrorKinds(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteRistretto255, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBatchedPrivateIssuer(tokenKey)
+	client := BatchedPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonces := make([][]byte, 2)
+	for i := 0; i < len(nonces); i++ {
+		nonces[i] = make([]byte, 32)
+		rand.Reader.Read(nonces[i])
+	}
+
+	requestState, err := client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestEnc := requestState.Request().Marshal()
+
+	if _, err := UnmarshalBatchedPrivateTokenRequest(requestEnc[:len(requestEnc)-1]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("UnmarshalBatchedPrivateTokenRequest = %v, expected a malformed encoding error", err)
+	}
+	request, err := UnmarshalBatchedPrivateTokenRequest(requestEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.TokenKeyID ^= 0xFF
+	if _, err := issuer.Evaluate(&request); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Evaluate = %v, expected an unknown key error", err)
+	}
+	request.TokenKeyID ^= 0xFF
+	request.BlindedReq[0] = bytes.Repeat([]byte{0xFF}, 32)
+	if _, err := issuer.Evaluate(&request); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("Evaluate = %v, expected a malformed encoding error", err)
+	}
+
+	tokenResponse, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeTokens(tokenResponse[:len(tokenResponse)-1]); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("FinalizeTokens = %v, expected a malformed encoding error", err)
+	}
+	batch, err := requestState.FinalizeTokens(tokenResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := batch[0]
+	token.Authenticator[0] ^= 0xFF
+	if err := issuer.Verify(token); !errors.Is(err, tokens.ErrSignatureInvalid) {
+		t.Fatalf("Verify = %v, expected an invalid signature error", err)
+	}
+	token.KeyID = make([]byte, 32)
+	if err := issuer.Verify(token); !errors.Is(err, tokens.ErrUnknownKey) {
+		t.Fatalf("Verify = %v, expected an unknown key error", err)
+	}
+}
+
+func TestBatchedPrivateIssuerObserver(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteRistretto255, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBatchedPrivateIssuer(tokenKey)
+	observer := tokens.NewMemoryObserver()
+	issuer.SetObserver(observer)
+	client := BatchedPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonces := [][]byte{make([]byte, 32)}
+	rand.Reader.Read(nonces[0])
+
+	requestState, err := client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Evaluate(requestState.Request()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := observer.Completed(tokens.OperationEvaluate, BatchedPrivateTokenType); n != 1 {
+		t.Fatalf("completed %d requests, expected 1", n)
+	}
+	if !bytes.Equal(observer.LastKeyID(tokens.OperationEvaluate, BatchedPrivateTokenType), issuer.TokenKeyID()) {
+		t.Fatal("key ID mismatch")
+	}
+}
+
+func TestBatchedPrivateEvaluateContext(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteRistretto255, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBatchedPrivateIssuer(tokenKey)
+	client := BatchedPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonces := make([][]byte, 10)
+	for i := 0; i < len(nonces); i++ {
+		nonces[i] = make([]byte, 32)
+		rand.Reader.Read(nonces[i])
+	}
+
+	requestState, err := client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tokenResponse, err := issuer.EvaluateContext(ctx, requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeTokens(tokenResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if _, err := issuer.EvaluateContext(ctx, requestState.Request()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EvaluateContext = %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestBatchedPrivateMaxBatchSize(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteRistretto255, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := NewBatchedPrivateIssuer(tokenKey)
+	if issuer.MaxBatchSize() != DefaultMaxBatchSize {
+		t.Fatalf("MaxBatchSize = %d, expected %d", issuer.MaxBatchSize(), DefaultMaxBatchSize)
+	}
+	issuer.SetMaxBatchSize(4)
+	client := BatchedPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+	nonces := make([][]byte, 5)
+	for i := 0; i < len(nonces); i++ {
+		nonces[i] = make([]byte, 32)
+		rand.Reader.Read(nonces[i])
+	}
+
+	requestState, err := client.CreateTokenRequest(challenge, nonces[:4], issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenResponse, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeTokens(tokenResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	requestState, err = client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Evaluate(requestState.Request()); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("Evaluate = %v, expected %v", err, tokens.ErrMalformedEncoding)
+	}
+
+	// Encoded requests are checked against the limit before being decoded
+	requestEnc := requestState.Request().Marshal()
+	var request4 BatchedPrivateTokenRequest
+	if request4.UnmarshalWithMaxBatchSize(requestEnc, 4) {
+		t.Fatal("UnmarshalWithMaxBatchSize accepted a batch over the limit")
+	}
+	if !request4.UnmarshalWithMaxBatchSize(requestEnc, 5) {
+		t.Fatal("UnmarshalWithMaxBatchSize rejected a batch at the limit")
+	}
+	if _, err := issuer.EvaluateEncodedContext(context.Background(), requestEnc); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("EvaluateEncodedContext = %v, expected %v", err, tokens.ErrMalformedEncoding)
+	}
+	issuer.SetMaxBatchSize(5)
+	tokenResponse, err = issuer.EvaluateEncodedContext(context.Background(), requestEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := requestState.FinalizeTokens(tokenResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid element anywhere in the batch fails the whole batch
+	issuer.SetMaxBatchSize(0)
+	requestState, err = client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := *requestState.Request()
+	request.BlindedReq = append([][]byte{}, request.BlindedReq...)
+	request.BlindedReq[3] = bytes.Repeat([]byte{0xFF}, 32)
+	if _, err := issuer.Evaluate(&request); !errors.Is(err, tokens.ErrMalformedEncoding) {
+		t.Fatalf("Evaluate = %v, expected %v", err, tokens.ErrMalformedEncoding)
+	}
+}
+
+func TestBatchedPrivateVerifyForOrigin(t *testing.T) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteRistretto255, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := NewBatchedPrivateIssuer(tokenKey)
+	client := BatchedPrivateClient{}
+
+	tokenChallenge := createTokenChallenge(BatchedPrivateTokenType, nil, "issuer.example", nil)
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonces := make([][]byte, 2)
+	for i := 0; i < len(nonces); i++ {
+		nonces[i] = make([]byte, 32)
+		rand.Reader.Read(nonces[i])
+	}
+
+	requestState, err := client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindedSignature, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := requestState.FinalizeTokens(blindedSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		// Challenges without origins may be redeemed anywhere
+		err = issuer.VerifyForOrigin(tokens[i], tokenChallenge, "any.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	otherChallenge := createTokenChallenge(BatchedPrivateTokenType, nil, "issuer.example", []string{"any.example"})
+	err = issuer.VerifyForOrigin(tokens[0], otherChallenge, "any.example")
+	if err == nil {
+		t.Fatal("Expected verification against a different challenge to fail")
+	}
+}
+
 // /////
 // Batched issuance test vector
 type rawBatchedPrivateIssuanceTestVector struct {
@@ -192,7 +451,10 @@ func (etv *BatchedPrivateIssuanceTestVector) UnmarshalJSON(data []byte) error {
 }
 
 func generateBatchedPrivateIssuanceBlindingTestVector(t *testing.T, client *BatchedPrivateClient, issuer *BatchedPrivateIssuer, tokenChallenge tokens.TokenChallenge) BatchedPrivateIssuanceTestVector {
-	challenge := tokenChallenge.Marshal()
+	challenge, err := tokenChallenge.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	nonces := make([][]byte, 3)
 	for i := 0; i < len(nonces); i++ {
@@ -298,7 +560,7 @@ func TestVectorGenerateBatchedPrivateIssuance(t *testing.T) {
 	challenges := []tokens.TokenChallenge{
 		createTokenChallenge(BatchedPrivateTokenType, redemptionContext, "issuer.example", []string{"origin.example"}),
 		createTokenChallenge(BatchedPrivateTokenType, nil, "issuer.example", []string{"origin.example"}),
-		createTokenChallenge(BatchedPrivateTokenType, nil, "issuer.example", []string{"foo.example,bar.example"}),
+		createTokenChallenge(BatchedPrivateTokenType, nil, "issuer.example", []string{"foo.example", "bar.example"}),
 		createTokenChallenge(BatchedPrivateTokenType, nil, "issuer.example", []string{}),
 		createTokenChallenge(BatchedPrivateTokenType, redemptionContext, "issuer.example", []string{}),
 	}
@@ -306,7 +568,10 @@ func TestVectorGenerateBatchedPrivateIssuance(t *testing.T) {
 	vectors := make([]BatchedPrivateIssuanceTestVector, len(challenges))
 	for i := 0; i < len(challenges); i++ {
 		challenge := challenges[i]
-		challengeEnc := challenge.Marshal()
+		challengeEnc, err := challenge.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		tokenKey, err := oprf.DeriveKey(oprf.SuiteRistretto255, oprf.VerifiableMode, []byte("fixed seed"), challengeEnc)
 		if err != nil {
@@ -350,3 +615,36 @@ func TestVectorVerifyBatchedPrivateIssuance(t *testing.T) {
 
 	verifyBatchedPrivateIssuanceTestVectors(t, encoded)
 }
+
+func BenchmarkBatchedPrivateEvaluate(b *testing.B) {
+	tokenKey, err := oprf.GenerateKey(oprf.SuiteRistretto255, rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	issuer := NewBatchedPrivateIssuer(tokenKey)
+	client := BatchedPrivateClient{}
+
+	challenge := make([]byte, 32)
+	rand.Reader.Read(challenge)
+
+	for _, batchSize := range []int{1, 10, 100, 1000} {
+		nonces := make([][]byte, batchSize)
+		for i := 0; i < batchSize; i++ {
+			nonces[i] = make([]byte, 32)
+			rand.Reader.Read(nonces[i])
+		}
+		requestState, err := client.CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("Tokens=%d", batchSize), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_, err := issuer.Evaluate(requestState.Request())
+				if err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package util
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/cloudflare/circl/oprf"
+)
+
+// This file implements JSON Web Key (RFC 7517) encodings of issuer keys, and
+// the JWK type and member encoding shared with the key encodings of the other
+// packages in this module.
+//
+// RSA token keys use the "RSA" key type (RFC 7518, Section 6.3) with the
+// "PS384" algorithm. P-384 OPRF keys use the "EC" key type with the "P-384"
+// curve (RFC 7518, Section 6.2). Ristretto255 OPRF keys have no registered
+// curve name, so they use the "OKP" key type (RFC 8037) with the curve name
+// "ristretto255" and the encoded element as "x". OPRF keys carry the OPRF
+// suite identifier as "alg".
+
+const (
+	jwkAlgorithmPS384 = "PS384"
+	jwkCurveP384      = "P-384"
+	jwkCurveR255      = "ristretto255"
+)
+
+// JWK holds the members of the JSON Web Key encodings used in this module.
+// Byte-valued members are base64url encoded without padding, as done by
+// EncodeJWKMember and DecodeJWKMember.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	D         string `json:"d,omitempty"`
+	P         string `json:"p,omitempty"`
+	Q         string `json:"q,omitempty"`
+	DP        string `json:"dp,omitempty"`
+	DQ        string `json:"dq,omitempty"`
+	QI        string `json:"qi,omitempty"`
+}
+
+// UnmarshalJWK decodes the JSON encoding of a JWK.
+func UnmarshalJWK(data []byte) (*JWK, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %v", err)
+	}
+	return &jwk, nil
+}
+
+// EncodeJWKMember encodes the value of a byte-valued JWK member.
+func EncodeJWKMember(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeJWKMember decodes the value of the byte-valued JWK member name, which
+// must be present. If size is positive, the decoded value must be exactly size
+// bytes long.
+func DecodeJWKMember(name, value string, size int) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing JWK member %q", name)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK member %q: %v", name, err)
+	}
+	if size > 0 && len(b) != size {
+		return nil, fmt.Errorf("invalid JWK member %q length %d", name, len(b))
+	}
+	return b, nil
+}
+
+func encodeJWKInt(n *big.Int) string {
+	return EncodeJWKMember(n.Bytes())
+}
+
+// jwkDecoder decodes JWK members, remembering the first error.
+type jwkDecoder struct {
+	err error
+}
+
+func (d *jwkDecoder) bytes(name, value string) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b, err := DecodeJWKMember(name, value, 0)
+	if err != nil {
+		d.err = err
+		return nil
+	}
+	return b
+}
+
+func (d *jwkDecoder) int(name, value string) *big.Int {
+	b := d.bytes(name, value)
+	if d.err != nil {
+		return nil
+	}
+	if len(b) == 0 || b[0] == 0 {
+		d.err = fmt.Errorf("invalid JWK member %q: not a minimal integer encoding", name)
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func rsaPublicJWK(key *rsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "RSA",
+		Algorithm: jwkAlgorithmPS384,
+		N:         encodeJWKInt(key.N),
+		E:         encodeJWKInt(big.NewInt(int64(key.E))),
+	}
+}
+
+func parseRSAPublicJWK(jwk *JWK, d *jwkDecoder) (*rsa.PublicKey, error) {
+	if jwk.KeyType != "RSA" {
+		return nil, fmt.Errorf("unexpected JWK key type %q", jwk.KeyType)
+	}
+	if jwk.Algorithm != "" && jwk.Algorithm != jwkAlgorithmPS384 {
+		return nil, fmt.Errorf("unexpected JWK algorithm %q", jwk.Algorithm)
+	}
+	n := d.int("n", jwk.N)
+	e := d.int("e", jwk.E)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA public exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// MarshalTokenKeyJWK encodes an RSA token public key as a JWK.
+func MarshalTokenKeyJWK(key *rsa.PublicKey) ([]byte, error) {
+	return json.Marshal(rsaPublicJWK(key))
+}
+
+// UnmarshalTokenKeyJWK decodes an RSA token public key from a JWK. The "alg"
+// member, if present, must be "PS384".
+func UnmarshalTokenKeyJWK(data []byte) (*rsa.PublicKey, error) {
+	jwk, err := UnmarshalJWK(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRSAPublicJWK(jwk, &jwkDecoder{})
+}
+
+// MarshalTokenPrivateKeyJWK encodes an RSA token private key, including its
+// CRT parameters, as a JWK. Only two-prime keys are supported.
+func MarshalTokenPrivateKeyJWK(key *rsa.PrivateKey) ([]byte, error) {
+	if len(key.Primes) != 2 {
+		return nil, fmt.Errorf("unsupported RSA key with %d primes", len(key.Primes))
+	}
+	key.Precompute()
+
+	jwk := rsaPublicJWK(&key.PublicKey)
+	jwk.D = encodeJWKInt(key.D)
+	jwk.P = encodeJWKInt(key.Primes[0])
+	jwk.Q = encodeJWKInt(key.Primes[1])
+	jwk.DP = encodeJWKInt(key.Precomputed.Dp)
+	jwk.DQ = encodeJWKInt(key.Precomputed.Dq)
+	jwk.QI = encodeJWKInt(key.Precomputed.Qinv)
+	return json.Marshal(jwk)
+}
+
+// UnmarshalTokenPrivateKeyJWK decodes an RSA token private key from a JWK,
+// validating it before returning. The CRT parameters are recomputed and must
+// match the encoded ones.
+func UnmarshalTokenPrivateKeyJWK(data []byte) (*rsa.PrivateKey, error) {
+	jwk, err := UnmarshalJWK(data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &jwkDecoder{}
+	publicKey, err := parseRSAPublicJWK(jwk, d)
+	if err != nil {
+		return nil, err
+	}
+	key := &rsa.PrivateKey{
+		PublicKey: *publicKey,
+		D:         d.int("d", jwk.D),
+		Primes:    []*big.Int{d.int("p", jwk.P), d.int("q", jwk.Q)},
+	}
+	dp := d.int("dp", jwk.DP)
+	dq := d.int("dq", jwk.DQ)
+	qi := d.int("qi", jwk.QI)
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	key.Precompute()
+	if key.Precomputed.Dp.Cmp(dp) != 0 || key.Precomputed.Dq.Cmp(dq) != 0 || key.Precomputed.Qinv.Cmp(qi) != 0 {
+		return nil, fmt.Errorf("invalid RSA private key CRT parameters")
+	}
+
+	return key, nil
+}
+
+func oprfPublicJWK(suite oprf.Suite, key *oprf.PublicKey) (JWK, error) {
+	if _, err := oprfSuite(suite.Identifier()); err != nil {
+		return JWK{}, err
+	}
+	enc, err := key.MarshalBinary()
+	if err != nil {
+		return JWK{}, err
+	}
+
+	if suite.Identifier() != oprf.SuiteP384.Identifier() {
+		return JWK{
+			KeyType:   "OKP",
+			Curve:     jwkCurveR255,
+			Algorithm: suite.Identifier(),
+			X:         EncodeJWKMember(enc),
+		}, nil
+	}
+
+	curve := elliptic.P384()
+	x, y := elliptic.UnmarshalCompressed(curve, enc)
+	if x == nil {
+		return JWK{}, fmt.Errorf("invalid %s public key", suite.Identifier())
+	}
+	byteLen := (curve.Params().BitSize + 7) / 8
+	return JWK{
+		KeyType:   "EC",
+		Curve:     jwkCurveP384,
+		Algorithm: suite.Identifier(),
+		X:         EncodeJWKMember(x.FillBytes(make([]byte, byteLen))),
+		Y:         EncodeJWKMember(y.FillBytes(make([]byte, byteLen))),
+	}, nil
+}
+
+func parseOPRFPublicJWK(jwk *JWK, d *jwkDecoder) (oprf.Suite, *oprf.PublicKey, error) {
+	var suite oprf.Suite
+	var enc []byte
+	switch {
+	case jwk.KeyType == "EC" && jwk.Curve == jwkCurveP384:
+		suite = oprf.SuiteP384
+		curve := elliptic.P384()
+		byteLen := (curve.Params().BitSize + 7) / 8
+		x := d.bytes("x", jwk.X)
+		y := d.bytes("y", jwk.Y)
+		if d.err != nil {
+			return nil, nil, d.err
+		}
+		if len(x) != byteLen || len(y) != byteLen {
+			return nil, nil, fmt.Errorf("invalid %s public key coordinate length", jwkCurveP384)
+		}
+		px, py := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+		if !curve.IsOnCurve(px, py) {
+			return nil, nil, fmt.Errorf("%s public key is not on the curve", jwkCurveP384)
+		}
+		enc = elliptic.MarshalCompressed(curve, px, py)
+	case jwk.KeyType == "OKP" && jwk.Curve == jwkCurveR255:
+		suite = oprf.SuiteRistretto255
+		enc = d.bytes("x", jwk.X)
+		if d.err != nil {
+			return nil, nil, d.err
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported JWK key type %q with curve %q", jwk.KeyType, jwk.Curve)
+	}
+	if jwk.Algorithm != "" && jwk.Algorithm != suite.Identifier() {
+		return nil, nil, fmt.Errorf("unexpected JWK algorithm %q", jwk.Algorithm)
+	}
+
+	key, err := UnmarshalOPRFPublicKey(suite, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return suite, key, nil
+}
+
+// MarshalOPRFPublicKeyJWK encodes an OPRF public key as a JWK.
+func MarshalOPRFPublicKeyJWK(suite oprf.Suite, key *oprf.PublicKey) ([]byte, error) {
+	jwk, err := oprfPublicJWK(suite, key)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwk)
+}
+
+// UnmarshalOPRFPublicKeyJWK decodes an OPRF public key and its suite from a
+// JWK.
+func UnmarshalOPRFPublicKeyJWK(data []byte) (oprf.Suite, *oprf.PublicKey, error) {
+	jwk, err := UnmarshalJWK(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseOPRFPublicJWK(jwk, &jwkDecoder{})
+}
+
+// MarshalOPRFPrivateKeyJWK encodes an OPRF private key, together with its
+// public key, as a JWK.
+func MarshalOPRFPrivateKeyJWK(suite oprf.Suite, key *oprf.PrivateKey) ([]byte, error) {
+	jwk, err := oprfPublicJWK(suite, key.Public())
+	if err != nil {
+		return nil, err
+	}
+	enc, err := key.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	jwk.D = EncodeJWKMember(enc)
+	return json.Marshal(jwk)
+}
+
+// UnmarshalOPRFPrivateKeyJWK decodes an OPRF private key and its suite from a
+// JWK. The encoded public key must match the private key.
+func UnmarshalOPRFPrivateKeyJWK(data []byte) (oprf.Suite, *oprf.PrivateKey, error) {
+	jwk, err := UnmarshalJWK(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := &jwkDecoder{}
+	suite, publicKey, err := parseOPRFPublicJWK(jwk, d)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := d.bytes("d", jwk.D)
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+	key, err := UnmarshalOPRFPrivateKey(suite, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expected, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	actual, err := key.Public().MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(expected, actual) {
+		return nil, nil, fmt.Errorf("%s private key does not match public key", suite.Identifier())
+	}
+
+	return suite, key, nil
+}
@@ -0,0 +1,186 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudflare/circl/oprf"
+)
+
+func TestTokenKeyJWKRoundTrip(t *testing.T) {
+	privateKey := loadPrivateKey(t)
+
+	publicKeyJWK, err := MarshalTokenKeyJWK(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members map[string]string
+	if err := json.Unmarshal(publicKeyJWK, &members); err != nil {
+		t.Fatal(err)
+	}
+	if members["kty"] != "RSA" || members["alg"] != "PS384" || members["e"] != "AQAB" {
+		t.Fatalf("unexpected JWK members %v", members)
+	}
+	publicKey, err := UnmarshalTokenKeyJWK(publicKeyJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		t.Fatal("public key mismatch")
+	}
+
+	privateKeyJWK, err := MarshalTokenPrivateKeyJWK(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveredKey, err := UnmarshalTokenPrivateKeyJWK(privateKeyJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recoveredKey.Equal(privateKey) {
+		t.Fatal("private key mismatch")
+	}
+
+	// The private JWK is also a valid public JWK.
+	publicKey, err = UnmarshalTokenKeyJWK(privateKeyJWK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		t.Fatal("public key mismatch")
+	}
+}
+
+func TestTokenKeyJWKRejectsInvalidKeys(t *testing.T) {
+	privateKey := loadPrivateKey(t)
+	privateKeyJWK, err := MarshalTokenPrivateKeyJWK(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(member, value string) []byte {
+		var members map[string]string
+		if err := json.Unmarshal(privateKeyJWK, &members); err != nil {
+			t.Fatal(err)
+		}
+		members[member] = value
+		out, err := json.Marshal(members)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name string
+		jwk  []byte
+	}{
+		{"wrong algorithm", tamper("alg", "RS256")},
+		{"wrong key type", tamper("kty", "EC")},
+		{"missing d", tamper("d", "")},
+		{"invalid base64", tamper("n", "!!!")},
+		{"non-minimal exponent", tamper("e", "AAEAAQ")},
+		{"wrong CRT parameter", tamper("qi", "AQAB")},
+		{"wrong prime", tamper("p", "AQAB")},
+	} {
+		if _, err := UnmarshalTokenPrivateKeyJWK(tc.jwk); err == nil {
+			t.Errorf("%s: decoded an invalid private key", tc.name)
+		}
+	}
+}
+
+func TestOPRFKeyJWKRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		suite   oprf.Suite
+		keyType string
+		curve   string
+	}{
+		{oprf.SuiteP384, "EC", "P-384"},
+		{oprf.SuiteRistretto255, "OKP", "ristretto255"},
+	} {
+		t.Run(tc.suite.Identifier(), func(t *testing.T) {
+			privateKey, err := oprf.GenerateKey(tc.suite, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			privateKeyJWK, err := MarshalOPRFPrivateKeyJWK(tc.suite, privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var members map[string]string
+			if err := json.Unmarshal(privateKeyJWK, &members); err != nil {
+				t.Fatal(err)
+			}
+			if members["kty"] != tc.keyType || members["crv"] != tc.curve || members["alg"] != tc.suite.Identifier() {
+				t.Fatalf("unexpected JWK members %v", members)
+			}
+
+			suite, recoveredKey, err := UnmarshalOPRFPrivateKeyJWK(privateKeyJWK)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if suite.Identifier() != tc.suite.Identifier() {
+				t.Fatalf("suite mismatch: %s", suite.Identifier())
+			}
+			if !bytes.Equal(MustMarshalPrivateOPRFKey(recoveredKey), MustMarshalPrivateOPRFKey(privateKey)) {
+				t.Fatal("private key mismatch")
+			}
+
+			publicKeyJWK, err := MarshalOPRFPublicKeyJWK(tc.suite, privateKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			suite, publicKey, err := UnmarshalOPRFPublicKeyJWK(publicKeyJWK)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if suite.Identifier() != tc.suite.Identifier() {
+				t.Fatalf("suite mismatch: %s", suite.Identifier())
+			}
+			if !bytes.Equal(MustMarshalPublicOPRFKey(publicKey), MustMarshalPublicOPRFKey(privateKey.Public())) {
+				t.Fatal("public key mismatch")
+			}
+			if _, _, err := UnmarshalOPRFPrivateKeyJWK(publicKeyJWK); err == nil {
+				t.Fatal("decoded a public JWK as a private key")
+			}
+
+			// A private key paired with another key's public key is rejected.
+			otherKey, err := oprf.GenerateKey(tc.suite, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			otherJWK, err := MarshalOPRFPublicKeyJWK(tc.suite, otherKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var mismatched map[string]string
+			if err := json.Unmarshal(otherJWK, &mismatched); err != nil {
+				t.Fatal(err)
+			}
+			mismatched["d"] = members["d"]
+			mismatchedJWK, err := json.Marshal(mismatched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := UnmarshalOPRFPrivateKeyJWK(mismatchedJWK); err == nil {
+				t.Fatal("decoded a private key that does not match its public key")
+			}
+		})
+	}
+}
+
+func TestOPRFKeyJWKRejectsInvalidKeys(t *testing.T) {
+	for _, jwk := range []string{
+		`{"kty":"EC","crv":"P-256","x":"AA","y":"AA"}`,
+		`{"kty":"EC","crv":"P-384","x":"AA","y":"AA"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"AA"}`,
+		`{"kty":"OKP","crv":"ristretto255","alg":"P384-SHA384","x":"4lMNPB0hHDlqjnkeegiKk8AKM1JDEyKxUpDpTpZmLRY"}`,
+	} {
+		if _, _, err := UnmarshalOPRFPublicKeyJWK([]byte(jwk)); err == nil {
+			t.Errorf("decoded invalid JWK %s", jwk)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package util
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/cloudflare/circl/oprf"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+const (
+	pemTypePublicKey      = "PUBLIC KEY"
+	pemTypePrivateKey     = "PRIVATE KEY"
+	pemTypeOPRFPublicKey  = "OPRF PUBLIC KEY"
+	pemTypeOPRFPrivateKey = "OPRF PRIVATE KEY"
+
+	// pemHeaderSuite names the OPRF suite of an OPRF key PEM block.
+	pemHeaderSuite = "Suite"
+)
+
+func decodePEM(data []byte, blockType string) (*pem.Block, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if block.Type != blockType {
+		return nil, fmt.Errorf("unexpected PEM block type %q, expected %q", block.Type, blockType)
+	}
+	return block, nil
+}
+
+// MarshalTokenKeyPEM encodes an RSA token public key as a PEM "PUBLIC KEY"
+// block holding its SubjectPublicKeyInfo with the RSASSA-PSS OID.
+func MarshalTokenKeyPEM(key *rsa.PublicKey) ([]byte, error) {
+	der, err := MarshalTokenKeyPSSOID(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  pemTypePublicKey,
+		Bytes: der,
+	}), nil
+}
+
+// UnmarshalTokenKeyPEM decodes an RSA token public key from a PEM "PUBLIC KEY"
+// block. Like UnmarshalTokenPrivateKeyPEM, it accepts both the RSASSA-PSS and
+// the legacy rsaEncryption encodings.
+func UnmarshalTokenKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, err := decodePEM(data, pemTypePublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalTokenKeyLenient(block.Bytes)
+}
+
+// MarshalTokenPrivateKeyPKCS8 encodes an RSA token private key as a PKCS #8
+// PrivateKeyInfo whose AlgorithmIdentifier is the RSASSA-PSS OID with the
+// token signing parameters, so that the key cannot be used for anything else.
+//
+//	PrivateKeyInfo ::= SEQUENCE {
+//	  version                   INTEGER,
+//	  privateKeyAlgorithm       AlgorithmIdentifier,
+//	  privateKey                OCTET STRING -- RSAPrivateKey
+//	}
+func MarshalTokenPrivateKeyPKCS8(key *rsa.PrivateKey) ([]byte, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(0)
+		addPSSAlgorithmIdentifier(b)
+		b.AddASN1OctetString(x509.MarshalPKCS1PrivateKey(key))
+	})
+
+	return b.Bytes()
+}
+
+// UnmarshalTokenPrivateKeyPKCS8 decodes an RSA token private key from a PKCS #8
+// PrivateKeyInfo. Both the RSASSA-PSS OID, with exactly the token signing
+// parameters, and the rsaEncryption OID are accepted.
+func UnmarshalTokenPrivateKeyPKCS8(der []byte) (*rsa.PrivateKey, error) {
+	s := cryptobyte.String(der)
+
+	var sequenceString cryptobyte.String
+	var version int64
+	var algorithm cryptobyte.String
+	var privateKeyString cryptobyte.String
+	if !s.ReadASN1(&sequenceString, cryptobyte_asn1.SEQUENCE.Constructed()) || !s.Empty() ||
+		!sequenceString.ReadASN1Integer(&version) ||
+		!sequenceString.ReadASN1Element(&algorithm, cryptobyte_asn1.SEQUENCE.Constructed()) ||
+		!sequenceString.ReadASN1(&privateKeyString, cryptobyte_asn1.OCTET_STRING) {
+		return nil, fmt.Errorf("invalid PKCS #8 token key encoding")
+	}
+
+	var pssAlgorithm cryptobyte.Builder
+	addPSSAlgorithmIdentifier(&pssAlgorithm)
+	if !bytes.Equal(algorithm, pssAlgorithm.BytesOrPanic()) {
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unexpected private key type %T", key)
+		}
+		return rsaKey, nil
+	}
+
+	if version != 0 {
+		return nil, fmt.Errorf("unsupported PKCS #8 version %d", version)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(privateKeyString)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// MarshalTokenPrivateKeyPEM encodes an RSA token private key as a PEM
+// "PRIVATE KEY" block holding its PKCS #8 encoding with the RSASSA-PSS OID.
+func MarshalTokenPrivateKeyPEM(key *rsa.PrivateKey) ([]byte, error) {
+	der, err := MarshalTokenPrivateKeyPKCS8(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  pemTypePrivateKey,
+		Bytes: der,
+	}), nil
+}
+
+// UnmarshalTokenPrivateKeyPEM decodes an RSA token private key from a PEM
+// "PRIVATE KEY" block.
+func UnmarshalTokenPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, err := decodePEM(data, pemTypePrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalTokenPrivateKeyPKCS8(block.Bytes)
+}
+
+// oprfSuite returns the OPRF suite with the given identifier, provided it is
+// one used by a token type.
+func oprfSuite(identifier string) (oprf.Suite, error) {
+	switch identifier {
+	case oprf.SuiteP384.Identifier():
+		return oprf.SuiteP384, nil
+	case oprf.SuiteRistretto255.Identifier():
+		return oprf.SuiteRistretto255, nil
+	default:
+		return nil, fmt.Errorf("unsupported OPRF suite %q", identifier)
+	}
+}
+
+// oprfScalarLength returns the length of an encoded private key in suite.
+func oprfScalarLength(suite oprf.Suite) int {
+	if suite.Identifier() == oprf.SuiteP384.Identifier() {
+		return 48
+	}
+	return 32
+}
+
+// UnmarshalOPRFPrivateKey decodes a private key in the given suite from its
+// raw scalar encoding, rejecting encodings of the wrong length and scalars
+// that are zero or out of range.
+func UnmarshalOPRFPrivateKey(suite oprf.Suite, data []byte) (*oprf.PrivateKey, error) {
+	if _, err := oprfSuite(suite.Identifier()); err != nil {
+		return nil, err
+	}
+	if len(data) != oprfScalarLength(suite) {
+		return nil, fmt.Errorf("invalid %s private key length %d", suite.Identifier(), len(data))
+	}
+	if suite.Identifier() == oprf.SuiteP384.Identifier() {
+		d := new(big.Int).SetBytes(data)
+		if d.Sign() == 0 || d.Cmp(elliptic.P384().Params().N) >= 0 {
+			return nil, fmt.Errorf("invalid %s private key scalar", suite.Identifier())
+		}
+	} else if bytes.Equal(data, make([]byte, len(data))) {
+		return nil, fmt.Errorf("invalid %s private key scalar", suite.Identifier())
+	}
+
+	key := new(oprf.PrivateKey)
+	if err := key.UnmarshalBinary(suite, data); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// UnmarshalOPRFPublicKey decodes a public key in the given suite from its
+// compressed element encoding.
+func UnmarshalOPRFPublicKey(suite oprf.Suite, data []byte) (*oprf.PublicKey, error) {
+	if _, err := oprfSuite(suite.Identifier()); err != nil {
+		return nil, err
+	}
+
+	key := new(oprf.PublicKey)
+	if err := key.UnmarshalBinary(suite, data); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// MarshalOPRFPrivateKeyPEM encodes an OPRF private key as a PEM
+// "OPRF PRIVATE KEY" block holding its raw scalar, with a Suite header naming
+// the OPRF suite.
+func MarshalOPRFPrivateKeyPEM(suite oprf.Suite, key *oprf.PrivateKey) ([]byte, error) {
+	if _, err := oprfSuite(suite.Identifier()); err != nil {
+		return nil, err
+	}
+	enc, err := key.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:    pemTypeOPRFPrivateKey,
+		Headers: map[string]string{pemHeaderSuite: suite.Identifier()},
+		Bytes:   enc,
+	}), nil
+}
+
+// UnmarshalOPRFPrivateKeyPEM decodes an OPRF private key and its suite from a
+// PEM "OPRF PRIVATE KEY" block.
+func UnmarshalOPRFPrivateKeyPEM(data []byte) (oprf.Suite, *oprf.PrivateKey, error) {
+	block, err := decodePEM(data, pemTypeOPRFPrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	suite, err := oprfSuite(block.Headers[pemHeaderSuite])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := UnmarshalOPRFPrivateKey(suite, block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return suite, key, nil
+}
+
+// MarshalOPRFPublicKeyPEM encodes an OPRF public key as a PEM
+// "OPRF PUBLIC KEY" block holding its compressed element, with a Suite header
+// naming the OPRF suite.
+func MarshalOPRFPublicKeyPEM(suite oprf.Suite, key *oprf.PublicKey) ([]byte, error) {
+	if _, err := oprfSuite(suite.Identifier()); err != nil {
+		return nil, err
+	}
+	enc, err := key.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:    pemTypeOPRFPublicKey,
+		Headers: map[string]string{pemHeaderSuite: suite.Identifier()},
+		Bytes:   enc,
+	}), nil
+}
+
+// UnmarshalOPRFPublicKeyPEM decodes an OPRF public key and its suite from a
+// PEM "OPRF PUBLIC KEY" block.
+func UnmarshalOPRFPublicKeyPEM(data []byte) (oprf.Suite, *oprf.PublicKey, error) {
+	block, err := decodePEM(data, pemTypeOPRFPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	suite, err := oprfSuite(block.Headers[pemHeaderSuite])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := UnmarshalOPRFPublicKey(suite, block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return suite, key, nil
+}
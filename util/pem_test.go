@@ -0,0 +1,157 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/cloudflare/circl/oprf"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestTokenKeyPEMRoundTrip(t *testing.T) {
+	privateKey := loadPrivateKey(t)
+
+	publicKeyPEM, err := MarshalTokenKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := UnmarshalTokenKeyPEM(publicKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		t.Fatal("public key mismatch")
+	}
+
+	privateKeyPEM, err := MarshalTokenPrivateKeyPEM(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveredKey, err := UnmarshalTokenPrivateKeyPEM(privateKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recoveredKey.Equal(privateKey) {
+		t.Fatal("private key mismatch")
+	}
+
+	// The private key must carry the same PSS AlgorithmIdentifier as the
+	// public key.
+	var algorithm cryptobyte.Builder
+	addPSSAlgorithmIdentifier(&algorithm)
+	block, _ := pem.Decode(privateKeyPEM)
+	if !bytes.Contains(block.Bytes, algorithm.BytesOrPanic()) {
+		t.Fatal("private key is missing the PSS AlgorithmIdentifier")
+	}
+}
+
+func TestTokenPrivateKeyPEMAcceptsRSAEncryption(t *testing.T) {
+	privateKey := loadPrivateKey(t)
+
+	legacyPEM := MustMarshalPrivateKey(privateKey)
+	recoveredKey, err := UnmarshalTokenPrivateKeyPEM(legacyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recoveredKey.Equal(privateKey) {
+		t.Fatal("private key mismatch")
+	}
+}
+
+func TestTokenKeyPEMRejectsWrongBlockType(t *testing.T) {
+	privateKey := loadPrivateKey(t)
+
+	rsaPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+	if _, err := UnmarshalTokenPrivateKeyPEM(rsaPEM); err == nil {
+		t.Fatal("decoded a PKCS #1 block as a PKCS #8 private key")
+	}
+	if _, err := UnmarshalTokenKeyPEM(rsaPEM); err == nil {
+		t.Fatal("decoded a private key block as a public key")
+	}
+	if _, err := UnmarshalTokenKeyPEM([]byte("not PEM")); err == nil {
+		t.Fatal("decoded garbage as a public key")
+	}
+}
+
+func TestOPRFKeyPEMRoundTrip(t *testing.T) {
+	for _, suite := range []oprf.Suite{oprf.SuiteP384, oprf.SuiteRistretto255} {
+		t.Run(suite.Identifier(), func(t *testing.T) {
+			privateKey, err := oprf.GenerateKey(suite, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			privateKeyPEM, err := MarshalOPRFPrivateKeyPEM(suite, privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(privateKeyPEM), "Suite: "+suite.Identifier()) {
+				t.Fatalf("missing suite header:\n%s", privateKeyPEM)
+			}
+			recoveredSuite, recoveredKey, err := UnmarshalOPRFPrivateKeyPEM(privateKeyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recoveredSuite.Identifier() != suite.Identifier() {
+				t.Fatalf("suite mismatch: %s", recoveredSuite.Identifier())
+			}
+			if !bytes.Equal(MustMarshalPrivateOPRFKey(recoveredKey), MustMarshalPrivateOPRFKey(privateKey)) {
+				t.Fatal("private key mismatch")
+			}
+
+			publicKeyPEM, err := MarshalOPRFPublicKeyPEM(suite, privateKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			recoveredSuite, publicKey, err := UnmarshalOPRFPublicKeyPEM(publicKeyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recoveredSuite.Identifier() != suite.Identifier() {
+				t.Fatalf("suite mismatch: %s", recoveredSuite.Identifier())
+			}
+			if !bytes.Equal(MustMarshalPublicOPRFKey(publicKey), MustMarshalPublicOPRFKey(privateKey.Public())) {
+				t.Fatal("public key mismatch")
+			}
+
+			if _, _, err := UnmarshalOPRFPublicKeyPEM(privateKeyPEM); err == nil {
+				t.Fatal("decoded a private key block as a public key")
+			}
+		})
+	}
+}
+
+func TestOPRFKeyPEMRejectsInvalidKeys(t *testing.T) {
+	if _, err := MarshalOPRFPublicKeyPEM(oprf.SuiteP256, nil); err == nil {
+		t.Fatal("encoded a key for an unsupported suite")
+	}
+
+	unknownSuite := pem.EncodeToMemory(&pem.Block{
+		Type:    "OPRF PRIVATE KEY",
+		Headers: map[string]string{"Suite": oprf.SuiteP256.Identifier()},
+		Bytes:   make([]byte, 32),
+	})
+	if _, _, err := UnmarshalOPRFPrivateKeyPEM(unknownSuite); err == nil {
+		t.Fatal("decoded a key for an unsupported suite")
+	}
+
+	for _, scalar := range [][]byte{
+		make([]byte, 48),
+		bytes.Repeat([]byte{0xff}, 48),
+		make([]byte, 32),
+	} {
+		if _, err := UnmarshalOPRFPrivateKey(oprf.SuiteP384, scalar); err == nil {
+			t.Fatalf("decoded invalid P-384 scalar %x", scalar)
+		}
+	}
+	if _, err := UnmarshalOPRFPrivateKey(oprf.SuiteRistretto255, make([]byte, 32)); err == nil {
+		t.Fatal("decoded a zero ristretto255 scalar")
+	}
+}
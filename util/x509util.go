@@ -21,6 +21,7 @@ type pkcs1PSSPublicKey struct {
 }
 
 var (
+	oidPublicKeyRSA    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
 	oidPublicKeyRSAPSS = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
 	oidSHA384          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
 	oidPKCS1MGF        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
@@ -54,6 +55,32 @@ func unmarshalTokenPrivateKey(data []byte) (*rsa.PrivateKey, error) {
 	return privateKey.(*rsa.PrivateKey), nil
 }
 
+// addPSSAlgorithmIdentifier adds the RSASSA-PSS AlgorithmIdentifier used for
+// token keys: SHA-384, MGF1 with SHA-384, and a 48-byte salt.
+func addPSSAlgorithmIdentifier(b *cryptobyte.Builder) {
+	b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oidPublicKeyRSAPSS)
+		b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
+					b.AddASN1ObjectIdentifier(oidSHA384)
+				})
+			})
+			b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
+					b.AddASN1ObjectIdentifier(oidPKCS1MGF)
+					b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
+						b.AddASN1ObjectIdentifier(oidSHA384)
+					})
+				})
+			})
+			b.AddASN1(cryptobyte_asn1.Tag(2).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(48)
+			})
+		})
+	})
+}
+
 func MarshalTokenKeyPSSOID(key *rsa.PublicKey) ([]byte, error) {
 	publicKeyBytes, err := asn1.Marshal(pkcs1PSSPublicKey{
 		N: key.N,
@@ -65,27 +92,7 @@ func MarshalTokenKeyPSSOID(key *rsa.PublicKey) ([]byte, error) {
 
 	var b cryptobyte.Builder
 	b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
-		b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
-			b.AddASN1ObjectIdentifier(oidPublicKeyRSAPSS)
-			b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
-				b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
-					b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
-						b.AddASN1ObjectIdentifier(oidSHA384)
-					})
-				})
-				b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
-					b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
-						b.AddASN1ObjectIdentifier(oidPKCS1MGF)
-						b.AddASN1(cryptobyte_asn1.SEQUENCE.Constructed(), func(b *cryptobyte.Builder) {
-							b.AddASN1ObjectIdentifier(oidSHA384)
-						})
-					})
-				})
-				b.AddASN1(cryptobyte_asn1.Tag(2).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
-					b.AddASN1Int64(48)
-				})
-			})
-		})
+		addPSSAlgorithmIdentifier(b)
 		b.AddASN1BitString(publicKeyBytes)
 	})
 
@@ -104,25 +111,152 @@ func MarshalTokenKey(key *rsa.PublicKey, legacyFormat bool) ([]byte, error) {
 	}
 }
 
-func UnmarshalTokenKey(data []byte) (*rsa.PublicKey, error) {
+// MinTokenKeyBits is the smallest RSA modulus, in bits, accepted for a token
+// key.
+const MinTokenKeyBits = 2048
+
+// tokenKeySizes lists the supported token key sizes Nk, in bytes, for
+// RSA-2048, RSA-3072 and RSA-4096 keys.
+var tokenKeySizes = []int{256, 384, 512}
+
+// IsSupportedTokenKeySize reports whether nk is the size of a supported token
+// key, in bytes.
+func IsSupportedTokenKeySize(nk int) bool {
+	for _, size := range tokenKeySizes {
+		if nk == size {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenKeySize returns Nk, the length in bytes of the modulus of key, which is
+// also the length of blinded messages, blind signatures and token
+// authenticators produced with it.
+func TokenKeySize(key *rsa.PublicKey) (int, error) {
+	if key == nil || key.N == nil {
+		return 0, fmt.Errorf("missing token key")
+	}
+	nk := key.Size()
+	if !IsSupportedTokenKeySize(nk) || key.N.BitLen() != 8*nk {
+		return 0, fmt.Errorf("unsupported token key size (%d bits)", key.N.BitLen())
+	}
+	return nk, nil
+}
+
+// readHashAlgorithm reads an AlgorithmIdentifier for the hash function oid,
+// whose parameters may be absent or NULL (RFC 4055, Section 2.1).
+func readHashAlgorithm(s *cryptobyte.String, oid asn1.ObjectIdentifier) bool {
+	var algorithm cryptobyte.String
+	var algorithmOID asn1.ObjectIdentifier
+	if !s.ReadASN1(&algorithm, cryptobyte_asn1.SEQUENCE) ||
+		!algorithm.ReadASN1ObjectIdentifier(&algorithmOID) ||
+		!algorithmOID.Equal(oid) {
+		return false
+	}
+	if algorithm.PeekASN1Tag(cryptobyte_asn1.NULL) {
+		var null cryptobyte.String
+		if !algorithm.ReadASN1(&null, cryptobyte_asn1.NULL) || !null.Empty() {
+			return false
+		}
+	}
+	return algorithm.Empty()
+}
+
+// checkPSSParameters checks that params hold the RSASSA-PSS-params used for
+// token keys: SHA-384, MGF1 with SHA-384, a 48-byte salt, and the default
+// trailer field.
+//
+//	RSASSA-PSS-params ::= SEQUENCE {
+//	  hashAlgorithm      [0] HashAlgorithm,
+//	  maskGenAlgorithm   [1] MaskGenAlgorithm,
+//	  saltLength         [2] INTEGER,
+//	  trailerField       [3] TrailerField DEFAULT trailerFieldBC
+//	}
+func checkPSSParameters(params cryptobyte.String) error {
+	var pssParams cryptobyte.String
+	if !params.ReadASN1(&pssParams, cryptobyte_asn1.SEQUENCE) || !params.Empty() {
+		return fmt.Errorf("invalid SPKI token key encoding (failed reading PSS parameters)")
+	}
+
+	var hashAlgorithm cryptobyte.String
+	if !pssParams.ReadASN1(&hashAlgorithm, cryptobyte_asn1.Tag(0).ContextSpecific().Constructed()) ||
+		!readHashAlgorithm(&hashAlgorithm, oidSHA384) || !hashAlgorithm.Empty() {
+		return fmt.Errorf("invalid SPKI token key encoding (PSS hash algorithm is not SHA-384)")
+	}
+
+	var maskGenAlgorithm, mgf cryptobyte.String
+	var mgfOID asn1.ObjectIdentifier
+	if !pssParams.ReadASN1(&maskGenAlgorithm, cryptobyte_asn1.Tag(1).ContextSpecific().Constructed()) ||
+		!maskGenAlgorithm.ReadASN1(&mgf, cryptobyte_asn1.SEQUENCE) || !maskGenAlgorithm.Empty() ||
+		!mgf.ReadASN1ObjectIdentifier(&mgfOID) || !mgfOID.Equal(oidPKCS1MGF) ||
+		!readHashAlgorithm(&mgf, oidSHA384) || !mgf.Empty() {
+		return fmt.Errorf("invalid SPKI token key encoding (PSS mask generation function is not MGF1 with SHA-384)")
+	}
+
+	var saltLength cryptobyte.String
+	var saltLengthValue int64
+	if !pssParams.ReadASN1(&saltLength, cryptobyte_asn1.Tag(2).ContextSpecific().Constructed()) ||
+		!saltLength.ReadASN1Integer(&saltLengthValue) || !saltLength.Empty() || saltLengthValue != 48 {
+		return fmt.Errorf("invalid SPKI token key encoding (PSS salt length is not 48)")
+	}
+
+	// DER omits fields equal to their default, so an explicit trailer field
+	// is always invalid.
+	if !pssParams.Empty() {
+		return fmt.Errorf("invalid SPKI token key encoding (unexpected PSS parameters)")
+	}
+
+	return nil
+}
+
+// checkRSAEncryptionParameters checks that params hold the NULL parameters
+// of an rsaEncryption AlgorithmIdentifier.
+func checkRSAEncryptionParameters(params cryptobyte.String) error {
+	var null cryptobyte.String
+	if !params.ReadASN1(&null, cryptobyte_asn1.NULL) || !null.Empty() || !params.Empty() {
+		return fmt.Errorf("invalid SPKI token key encoding (rsaEncryption parameters are not NULL)")
+	}
+	return nil
+}
+
+func unmarshalTokenKey(data []byte, allowRSAEncryption bool) (*rsa.PublicKey, error) {
 	s := cryptobyte.String(data)
 
 	var sequenceString cryptobyte.String
-	if !s.ReadASN1(&sequenceString, cryptobyte_asn1.SEQUENCE.Constructed()) {
+	if !s.ReadASN1(&sequenceString, cryptobyte_asn1.SEQUENCE) || !s.Empty() {
 		return nil, fmt.Errorf("invalid SPKI token key encoding (failed reading outer sequence)")
 	}
 
-	var paramsString cryptobyte.String
-	if !sequenceString.ReadASN1(&paramsString, cryptobyte_asn1.SEQUENCE.Constructed()) {
-		return nil, fmt.Errorf("invalid SPKI token key encoding (failed reading parameters)")
+	var algorithmString cryptobyte.String
+	var algorithm asn1.ObjectIdentifier
+	if !sequenceString.ReadASN1(&algorithmString, cryptobyte_asn1.SEQUENCE) ||
+		!algorithmString.ReadASN1ObjectIdentifier(&algorithm) {
+		return nil, fmt.Errorf("invalid SPKI token key encoding (failed reading algorithm)")
+	}
+
+	switch {
+	case algorithm.Equal(oidPublicKeyRSAPSS):
+		if err := checkPSSParameters(algorithmString); err != nil {
+			return nil, err
+		}
+	case algorithm.Equal(oidPublicKeyRSA) && allowRSAEncryption:
+		if err := checkRSAEncryptionParameters(algorithmString); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid SPKI token key encoding (unsupported algorithm %v)", algorithm)
 	}
 
 	var publicKeyString asn1.BitString
-	if !sequenceString.ReadASN1BitString(&publicKeyString) {
+	if !sequenceString.ReadASN1BitString(&publicKeyString) || !sequenceString.Empty() {
 		return nil, fmt.Errorf("invalid SPKI token key encoding (failed reading public key)")
 	}
+	if publicKeyString.BitLength%8 != 0 {
+		return nil, fmt.Errorf("invalid SPKI token key encoding (public key has unused bits)")
+	}
 
-	der := cryptobyte.String(publicKeyString.RightAlign())
+	der := cryptobyte.String(publicKeyString.Bytes)
 	p := &pkcs1PSSPublicKey{N: new(big.Int)}
 	if !der.ReadASN1(&der, cryptobyte_asn1.SEQUENCE) {
 		return nil, errors.New("x509: invalid RSA public key")
@@ -133,6 +267,20 @@ func UnmarshalTokenKey(data []byte) (*rsa.PublicKey, error) {
 	if !der.ReadASN1Integer(&p.E) {
 		return nil, errors.New("x509: invalid RSA public exponent")
 	}
+	if !der.Empty() {
+		return nil, errors.New("x509: trailing data after RSA public key")
+	}
+
+	if p.N.Sign() <= 0 || p.N.Bit(0) == 0 {
+		return nil, errors.New("x509: RSA modulus is not a positive odd number")
+	}
+	if p.N.BitLen() < MinTokenKeyBits {
+		return nil, fmt.Errorf("RSA token key is too small (%d bits, minimum %d)", p.N.BitLen(), MinTokenKeyBits)
+	}
+	// crypto/rsa only supports public exponents up to 2^31-1.
+	if p.E < 3 || p.E&1 == 0 || p.E > 1<<31-1 {
+		return nil, errors.New("x509: invalid RSA public exponent")
+	}
 
 	key := new(rsa.PublicKey) // Everything else is uninitialized
 	key.N = p.N
@@ -141,6 +289,20 @@ func UnmarshalTokenKey(data []byte) (*rsa.PublicKey, error) {
 	return key, nil
 }
 
+// UnmarshalTokenKey decodes a token key from a SubjectPublicKeyInfo whose
+// algorithm is id-RSASSA-PSS with exactly the token signing parameters:
+// SHA-384, MGF1 with SHA-384, and a 48-byte salt. Moduli smaller than
+// MinTokenKeyBits are rejected.
+func UnmarshalTokenKey(data []byte) (*rsa.PublicKey, error) {
+	return unmarshalTokenKey(data, false)
+}
+
+// UnmarshalTokenKeyLenient is like UnmarshalTokenKey, but also accepts the
+// legacy rsaEncryption encoding produced by MarshalTokenKeyRSAEncryptionOID.
+func UnmarshalTokenKeyLenient(data []byte) (*rsa.PublicKey, error) {
+	return unmarshalTokenKey(data, true)
+}
+
 func MustMarshalPrivateOPRFKey(key *oprf.PrivateKey) []byte {
 	encodedKey, err := key.MarshalBinary()
 	if err != nil {
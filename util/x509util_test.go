@@ -1,10 +1,16 @@
 package util
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/pem"
+	"math/big"
 	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
 // 2048-bit RSA private key
@@ -78,3 +84,215 @@ func TestEncode(t *testing.T) {
 		t.Fatal("E mismatch")
 	}
 }
+
+func TestUnmarshalTokenKeyModes(t *testing.T) {
+	publicKey := &loadPrivateKey(t).PublicKey
+
+	pssEnc, err := MarshalTokenKeyPSSOID(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyEnc, err := MarshalTokenKeyRSAEncryptionOID(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unmarshal := range []func([]byte) (*rsa.PublicKey, error){UnmarshalTokenKey, UnmarshalTokenKeyLenient} {
+		key, err := unmarshal(pssEnc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.Equal(publicKey) {
+			t.Fatal("public key mismatch")
+		}
+	}
+
+	if _, err := UnmarshalTokenKey(legacyEnc); err == nil {
+		t.Fatal("strict parsing accepted the rsaEncryption encoding")
+	}
+	key, err := UnmarshalTokenKeyLenient(legacyEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(publicKey) {
+		t.Fatal("public key mismatch")
+	}
+}
+
+// spkiParams describes a SubjectPublicKeyInfo built by buildSPKI. The zero
+// value, combined with a valid key, describes a valid token key encoding.
+type spkiParams struct {
+	algorithm      asn1.ObjectIdentifier
+	omitPSSParams  bool
+	hash           asn1.ObjectIdentifier
+	hashNULL       bool
+	mgf            asn1.ObjectIdentifier
+	mgfHash        asn1.ObjectIdentifier
+	saltLength     int64
+	trailerField   bool
+	rsaNULL        bool
+	unusedBits     bool
+	publicKeyExtra []byte
+	spkiExtra      []byte
+	trailingData   []byte
+	modulus        *big.Int
+	publicExponent int64
+}
+
+func buildSPKI(key *rsa.PublicKey, p spkiParams) []byte {
+	orDefault := func(oid, def asn1.ObjectIdentifier) asn1.ObjectIdentifier {
+		if oid == nil {
+			return def
+		}
+		return oid
+	}
+	modulus := key.N
+	if p.modulus != nil {
+		modulus = p.modulus
+	}
+	publicExponent := int64(key.E)
+	if p.publicExponent != 0 {
+		publicExponent = p.publicExponent
+	}
+	saltLength := int64(48)
+	if p.saltLength != 0 {
+		saltLength = p.saltLength
+	}
+	algorithm := orDefault(p.algorithm, oidPublicKeyRSAPSS)
+
+	var publicKey cryptobyte.Builder
+	publicKey.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(modulus)
+		b.AddASN1Int64(publicExponent)
+		b.AddBytes(p.publicKeyExtra)
+	})
+	publicKeyBytes := publicKey.BytesOrPanic()
+
+	addHash := func(b *cryptobyte.Builder, oid asn1.ObjectIdentifier) {
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(oid)
+			if p.hashNULL {
+				b.AddASN1NULL()
+			}
+		})
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(algorithm)
+			if p.rsaNULL {
+				b.AddASN1NULL()
+			}
+			if !algorithm.Equal(oidPublicKeyRSAPSS) || p.omitPSSParams {
+				return
+			}
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+					addHash(b, orDefault(p.hash, oidSHA384))
+				})
+				b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+					b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						b.AddASN1ObjectIdentifier(orDefault(p.mgf, oidPKCS1MGF))
+						addHash(b, orDefault(p.mgfHash, oidSHA384))
+					})
+				})
+				b.AddASN1(cryptobyte_asn1.Tag(2).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+					b.AddASN1Int64(saltLength)
+				})
+				if p.trailerField {
+					b.AddASN1(cryptobyte_asn1.Tag(3).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+						b.AddASN1Int64(1)
+					})
+				}
+			})
+		})
+		if p.unusedBits {
+			b.AddASN1(cryptobyte_asn1.BIT_STRING, func(b *cryptobyte.Builder) {
+				b.AddUint8(1)
+				b.AddBytes(publicKeyBytes)
+			})
+		} else {
+			b.AddASN1BitString(publicKeyBytes)
+		}
+		b.AddBytes(p.spkiExtra)
+	})
+	b.AddBytes(p.trailingData)
+
+	return b.BytesOrPanic()
+}
+
+func TestUnmarshalTokenKeyAcceptsValidEncodings(t *testing.T) {
+	publicKey := &loadPrivateKey(t).PublicKey
+
+	pssEnc, err := MarshalTokenKeyPSSOID(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc := buildSPKI(publicKey, spkiParams{}); string(enc) != string(pssEnc) {
+		t.Fatal("buildSPKI does not reproduce MarshalTokenKeyPSSOID")
+	}
+
+	// RFC 4055 allows hash AlgorithmIdentifiers with NULL parameters.
+	key, err := UnmarshalTokenKey(buildSPKI(publicKey, spkiParams{hashNULL: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(publicKey) {
+		t.Fatal("public key mismatch")
+	}
+
+	largeKey, err := rsa.GenerateKey(rand.Reader, 3072)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalTokenKey(buildSPKI(&largeKey.PublicKey, spkiParams{})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnmarshalTokenKeyRejectsInvalidEncodings(t *testing.T) {
+	publicKey := &loadPrivateKey(t).PublicKey
+
+	smallModulus := new(big.Int).Rsh(publicKey.N, 1024)
+	smallModulus.SetBit(smallModulus, 0, 1)
+	evenModulus := new(big.Int).SetBit(publicKey.N, 0, 0)
+
+	corpus := []struct {
+		name    string
+		enc     []byte
+		lenient bool // Whether UnmarshalTokenKeyLenient accepts the encoding
+	}{
+		{"rsaEncryption", buildSPKI(publicKey, spkiParams{algorithm: oidPublicKeyRSA, rsaNULL: true}), true},
+		{"rsaEncryption without NULL", buildSPKI(publicKey, spkiParams{algorithm: oidPublicKeyRSA}), false},
+		{"ecPublicKey", buildSPKI(publicKey, spkiParams{algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}}), false},
+		{"missing PSS parameters", buildSPKI(publicKey, spkiParams{omitPSSParams: true}), false},
+		{"NULL PSS parameters", buildSPKI(publicKey, spkiParams{omitPSSParams: true, rsaNULL: true}), false},
+		{"SHA-256 hash", buildSPKI(publicKey, spkiParams{hash: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}}), false},
+		{"SHA-512 MGF1 hash", buildSPKI(publicKey, spkiParams{mgfHash: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}}), false},
+		{"unknown mask generation function", buildSPKI(publicKey, spkiParams{mgf: asn1.ObjectIdentifier{1, 2, 3}}), false},
+		{"salt length 32", buildSPKI(publicKey, spkiParams{saltLength: 32}), false},
+		{"explicit trailer field", buildSPKI(publicKey, spkiParams{trailerField: true}), false},
+		{"unused bits", buildSPKI(publicKey, spkiParams{unusedBits: true}), false},
+		{"trailing data in public key", buildSPKI(publicKey, spkiParams{publicKeyExtra: []byte{0x02, 0x01, 0x00}}), false},
+		{"trailing data in SPKI", buildSPKI(publicKey, spkiParams{spkiExtra: []byte{0x05, 0x00}}), false},
+		{"trailing data after SPKI", buildSPKI(publicKey, spkiParams{trailingData: []byte{0x00}}), false},
+		{"1024-bit modulus", buildSPKI(publicKey, spkiParams{modulus: smallModulus}), false},
+		{"even modulus", buildSPKI(publicKey, spkiParams{modulus: evenModulus}), false},
+		{"negative modulus", buildSPKI(publicKey, spkiParams{modulus: new(big.Int).Neg(publicKey.N)}), false},
+		{"public exponent 1", buildSPKI(publicKey, spkiParams{publicExponent: 1}), false},
+		{"even public exponent", buildSPKI(publicKey, spkiParams{publicExponent: 65536}), false},
+		{"oversized public exponent", buildSPKI(publicKey, spkiParams{publicExponent: 1<<62 + 1}), false},
+		{"empty", nil, false},
+		{"truncated", buildSPKI(publicKey, spkiParams{})[:100], false},
+	}
+
+	for _, tc := range corpus {
+		if _, err := UnmarshalTokenKey(tc.enc); err == nil {
+			t.Errorf("%s: UnmarshalTokenKey succeeded", tc.name)
+		}
+		if _, err := UnmarshalTokenKeyLenient(tc.enc); (err == nil) != tc.lenient {
+			t.Errorf("%s: UnmarshalTokenKeyLenient returned %v", tc.name, err)
+		}
+	}
+}
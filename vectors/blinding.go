@@ -0,0 +1,395 @@
+package vectors
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/cloudflare/pat-go/ecdsa"
+	"github.com/cloudflare/pat-go/ed25519"
+	"github.com/cloudflare/pat-go/ed448"
+)
+
+type rawECDSABlindingTestVector struct {
+	Curve          string `json:"Curve"`
+	Hash           string `json:"Hash"`
+	PrivateKey     string `json:"skS"`
+	PublicKey      string `json:"pkS"`
+	PrivateBlind   string `json:"bk"`
+	BlindPublicKey string `json:"pkR"`
+	Message        string `json:"message"`
+	Context        string `json:"context"`
+	Signature      string `json:"signature"`
+	Deterministic  bool   `json:"deterministic,omitempty"`
+}
+
+// rawEdDSABlindingTestVector is shared by the Ed25519 and Ed448 families. The
+// type 0x0003 families leave pkB empty.
+type rawEdDSABlindingTestVector struct {
+	PrivateKey     string `json:"skS"`
+	PublicKey      string `json:"pkS"`
+	PrivateBlind   string `json:"bk"`
+	PublicBlind    string `json:"pkB"`
+	BlindPublicKey string `json:"pkR"`
+	Message        string `json:"message"`
+	Context        string `json:"context"`
+	Signature      string `json:"signature"`
+}
+
+var (
+	ecdsaBlindingFamily      = newFamily("ecdsa-blinding", "type3-ecdsa-blinding-test-vectors.json", generateECDSABlindingTestVectors, verifyECDSABlindingTestVector)
+	ed25519BlindingFamily    = newFamily("ed25519-blinding", "type3-ed25519-blinding-test-vectors.json", generateEd25519BlindingTestVectors, verifyEd25519BlindingTestVector)
+	ed448BlindingFamily      = newFamily("ed448-blinding", "type3-ed448-blinding-test-vectors.json", generateEd448BlindingTestVectors, verifyEd448BlindingTestVector)
+	ed25519KeyBlindingFamily = newFamily("ed25519-key-blinding", "ed25519-key-blinding-test-vectors.json", generateEd25519KeyBlindingTestVectors, verifyEd25519KeyBlindingTestVector)
+)
+
+var blindingTestMessage = []byte("hello world")
+
+func randomBytes(length int) []byte {
+	out := make([]byte, length)
+	rand.Reader.Read(out)
+	return out
+}
+
+func generateECDSABlindingTestVectors() ([]rawECDSABlindingTestVector, error) {
+	curve := elliptic.P384()
+	hash := crypto.SHA384
+	scalarLen := (curve.Params().BitSize + 7) / 8
+
+	var vectors []rawECDSABlindingTestVector
+	for _, contextLen := range []int{0, 32} {
+		skS, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		skB, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		context := randomBytes(contextLen)
+
+		pkR, err := ecdsa.BlindPublicKeyWithContext(curve, &skS.PublicKey, skB, context)
+		if err != nil {
+			return nil, err
+		}
+
+		digester := hash.New()
+		digester.Write(blindingTestMessage)
+		r, s, err := ecdsa.BlindKeySignDeterministicWithContext(skS, skB, hash, digester.Sum(nil), context)
+		if err != nil {
+			return nil, err
+		}
+
+		skSEnc := make([]byte, scalarLen)
+		skBEnc := make([]byte, scalarLen)
+		sig := make([]byte, 2*scalarLen)
+		skS.D.FillBytes(skSEnc)
+		skB.D.FillBytes(skBEnc)
+		r.FillBytes(sig[:scalarLen])
+		s.FillBytes(sig[scalarLen:])
+
+		vectors = append(vectors, rawECDSABlindingTestVector{
+			Curve:          curve.Params().Name,
+			Hash:           hash.String(),
+			PrivateKey:     hex.EncodeToString(skSEnc),
+			PublicKey:      hex.EncodeToString(elliptic.MarshalCompressed(curve, skS.X, skS.Y)),
+			PrivateBlind:   hex.EncodeToString(skBEnc),
+			BlindPublicKey: hex.EncodeToString(elliptic.MarshalCompressed(curve, pkR.X, pkR.Y)),
+			Message:        hex.EncodeToString(blindingTestMessage),
+			Context:        hex.EncodeToString(context),
+			Signature:      hex.EncodeToString(sig),
+			Deterministic:  true,
+		})
+	}
+
+	return vectors, nil
+}
+
+// verifyECDSABlindingTestVector checks the blinded key and signature. The
+// signature is only reproduced when the vector was signed with RFC 6979
+// nonces; otherwise it is checked against the blinded key.
+func verifyECDSABlindingTestVector(vector rawECDSABlindingTestVector, d *differ) error {
+	if vector.Curve != elliptic.P384().Params().Name {
+		return fmt.Errorf("unsupported curve %s", vector.Curve)
+	}
+	if vector.Hash != crypto.SHA384.String() {
+		return fmt.Errorf("unsupported hash algorithm %s", vector.Hash)
+	}
+	curve := elliptic.P384()
+	hash := crypto.SHA384
+	scalarLen := (curve.Params().BitSize + 7) / 8
+
+	h := &hexDecoder{}
+	skSEnc := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	skBEnc := h.decode("bk", vector.PrivateBlind)
+	pkR := h.decode("pkR", vector.BlindPublicKey)
+	message := h.decode("message", vector.Message)
+	context := h.decode("context", vector.Context)
+	sig := h.decode("signature", vector.Signature)
+	if h.err != nil {
+		return h.err
+	}
+	if len(sig) != 2*scalarLen {
+		return fmt.Errorf("invalid signature length %d, expected %d", len(sig), 2*scalarLen)
+	}
+
+	skS, err := ecdsa.CreateKey(curve, skSEnc)
+	if err != nil {
+		return err
+	}
+	skB, err := ecdsa.CreateKey(curve, skBEnc)
+	if err != nil {
+		return err
+	}
+	d.compare("pkS", pkS, elliptic.MarshalCompressed(curve, skS.X, skS.Y))
+
+	blindedKey, err := ecdsa.BlindPublicKeyWithContext(curve, &skS.PublicKey, skB, context)
+	if err != nil {
+		return err
+	}
+	d.compare("pkR", pkR, elliptic.MarshalCompressed(curve, blindedKey.X, blindedKey.Y))
+
+	digester := hash.New()
+	digester.Write(message)
+	digest := digester.Sum(nil)
+	if vector.Deterministic {
+		r, s, err := ecdsa.BlindKeySignDeterministicWithContext(skS, skB, hash, digest, context)
+		if err != nil {
+			return err
+		}
+		actualSig := make([]byte, 2*scalarLen)
+		r.FillBytes(actualSig[:scalarLen])
+		s.FillBytes(actualSig[scalarLen:])
+		d.compare("signature", sig, actualSig)
+	}
+
+	r := new(big.Int).SetBytes(sig[:scalarLen])
+	s := new(big.Int).SetBytes(sig[scalarLen:])
+	if !ecdsa.Verify(blindedKey, digest, r, s) {
+		return fmt.Errorf("signature does not verify under the blinded public key")
+	}
+
+	return nil
+}
+
+func generateEd25519BlindingTestVectors() ([]rawEdDSABlindingTestVector, error) {
+	var vectors []rawEdDSABlindingTestVector
+	for _, contextLen := range []int{0, 32} {
+		for _, blindLen := range []int{32, 0} {
+			skS := randomBytes(ed25519.SeedSize)
+			skB := make([]byte, 32)
+			rand.Reader.Read(skB[:blindLen])
+			context := randomBytes(contextLen)
+
+			privateKey := ed25519.NewKeyFromSeed(skS)
+			publicKey := privateKey.Public().(ed25519.PublicKey)
+			blindedKey, err := ed25519.BlindPublicKeyWithContext(publicKey, skB, context)
+			if err != nil {
+				return nil, err
+			}
+			signature := ed25519.BlindKeySignWithContext(privateKey, blindingTestMessage, skB, context)
+
+			vectors = append(vectors, rawEdDSABlindingTestVector{
+				PrivateKey:     hex.EncodeToString(skS),
+				PublicKey:      hex.EncodeToString(publicKey),
+				PrivateBlind:   hex.EncodeToString(skB),
+				BlindPublicKey: hex.EncodeToString(blindedKey),
+				Message:        hex.EncodeToString(blindingTestMessage),
+				Context:        hex.EncodeToString(context),
+				Signature:      hex.EncodeToString(signature),
+			})
+		}
+	}
+
+	return vectors, nil
+}
+
+func verifyEd25519BlindingTestVector(vector rawEdDSABlindingTestVector, d *differ) error {
+	h := &hexDecoder{}
+	skS := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	skB := h.decode("bk", vector.PrivateBlind)
+	pkR := h.decode("pkR", vector.BlindPublicKey)
+	message := h.decode("message", vector.Message)
+	context := h.decode("context", vector.Context)
+	signature := h.decode("signature", vector.Signature)
+	if h.err != nil {
+		return h.err
+	}
+	if len(skS) != ed25519.SeedSize {
+		return fmt.Errorf("invalid skS length %d, expected %d", len(skS), ed25519.SeedSize)
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(skS)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	d.compare("pkS", pkS, publicKey)
+
+	blindedKey, err := ed25519.BlindPublicKeyWithContext(publicKey, skB, context)
+	if err != nil {
+		return err
+	}
+	d.compare("pkR", pkR, blindedKey)
+	d.compare("signature", signature, ed25519.BlindKeySignWithContext(privateKey, message, skB, context))
+
+	if !ed25519.Verify(blindedKey, message, signature) {
+		return fmt.Errorf("signature does not verify under the blinded public key")
+	}
+
+	return nil
+}
+
+func generateEd448BlindingTestVectors() ([]rawEdDSABlindingTestVector, error) {
+	var vectors []rawEdDSABlindingTestVector
+	for _, contextLen := range []int{0, 32} {
+		for _, blindLen := range []int{ed448.BlindSize, 0} {
+			skS := randomBytes(ed448.SeedSize)
+			skB := make([]byte, ed448.BlindSize)
+			rand.Reader.Read(skB[:blindLen])
+			context := randomBytes(contextLen)
+
+			privateKey := ed448.NewKeyFromSeed(skS)
+			publicKey := privateKey.Public().(ed448.PublicKey)
+			blindedKey, err := ed448.BlindPublicKeyWithContext(publicKey, skB, context)
+			if err != nil {
+				return nil, err
+			}
+			signature := ed448.BlindKeySignWithContext(privateKey, blindingTestMessage, skB, context)
+
+			vectors = append(vectors, rawEdDSABlindingTestVector{
+				PrivateKey:     hex.EncodeToString(skS),
+				PublicKey:      hex.EncodeToString(publicKey),
+				PrivateBlind:   hex.EncodeToString(skB),
+				BlindPublicKey: hex.EncodeToString(blindedKey),
+				Message:        hex.EncodeToString(blindingTestMessage),
+				Context:        hex.EncodeToString(context),
+				Signature:      hex.EncodeToString(signature),
+			})
+		}
+	}
+
+	return vectors, nil
+}
+
+func verifyEd448BlindingTestVector(vector rawEdDSABlindingTestVector, d *differ) error {
+	h := &hexDecoder{}
+	skS := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	skB := h.decode("bk", vector.PrivateBlind)
+	pkR := h.decode("pkR", vector.BlindPublicKey)
+	message := h.decode("message", vector.Message)
+	context := h.decode("context", vector.Context)
+	signature := h.decode("signature", vector.Signature)
+	if h.err != nil {
+		return h.err
+	}
+	if len(skS) != ed448.SeedSize {
+		return fmt.Errorf("invalid skS length %d, expected %d", len(skS), ed448.SeedSize)
+	}
+
+	privateKey := ed448.NewKeyFromSeed(skS)
+	publicKey := privateKey.Public().(ed448.PublicKey)
+	d.compare("pkS", pkS, publicKey)
+
+	blindedKey, err := ed448.BlindPublicKeyWithContext(publicKey, skB, context)
+	if err != nil {
+		return err
+	}
+	d.compare("pkR", pkR, blindedKey)
+	d.compare("signature", signature, ed448.BlindKeySignWithContext(privateKey, message, skB, context))
+
+	if !ed448.Verify(blindedKey, message, signature) {
+		return fmt.Errorf("signature does not verify under the blinded public key")
+	}
+
+	return nil
+}
+
+func generateEd25519KeyBlindingTestVectors() ([]rawEdDSABlindingTestVector, error) {
+	var vectors []rawEdDSABlindingTestVector
+	for _, contextLen := range []int{0, 32} {
+		skS := randomBytes(ed25519.SeedSize)
+		skB, err := ed25519.GenerateBlindKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		context := randomBytes(contextLen)
+
+		privateKey := ed25519.NewKeyFromSeed(skS)
+		publicKey := privateKey.Public().(ed25519.PublicKey)
+		publicBlind, err := ed25519.PublicBlindKeyXMD(skB, context)
+		if err != nil {
+			return nil, err
+		}
+		blindedKey, err := ed25519.BlindPublicKeyXMD(publicKey, skB, context)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := ed25519.BlindKeySignXMD(privateKey, blindingTestMessage, skB, context)
+		if err != nil {
+			return nil, err
+		}
+
+		vectors = append(vectors, rawEdDSABlindingTestVector{
+			PrivateKey:     hex.EncodeToString(skS),
+			PublicKey:      hex.EncodeToString(publicKey),
+			PrivateBlind:   hex.EncodeToString(skB),
+			PublicBlind:    hex.EncodeToString(publicBlind),
+			BlindPublicKey: hex.EncodeToString(blindedKey),
+			Message:        hex.EncodeToString(blindingTestMessage),
+			Context:        hex.EncodeToString(context),
+			Signature:      hex.EncodeToString(signature),
+		})
+	}
+
+	return vectors, nil
+}
+
+func verifyEd25519KeyBlindingTestVector(vector rawEdDSABlindingTestVector, d *differ) error {
+	h := &hexDecoder{}
+	skS := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	skB := h.decode("bk", vector.PrivateBlind)
+	pkB := h.decode("pkB", vector.PublicBlind)
+	pkR := h.decode("pkR", vector.BlindPublicKey)
+	message := h.decode("message", vector.Message)
+	context := h.decode("context", vector.Context)
+	signature := h.decode("signature", vector.Signature)
+	if h.err != nil {
+		return h.err
+	}
+	if len(skS) != ed25519.SeedSize {
+		return fmt.Errorf("invalid skS length %d, expected %d", len(skS), ed25519.SeedSize)
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(skS)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	d.compare("pkS", pkS, publicKey)
+
+	publicBlind, err := ed25519.PublicBlindKeyXMD(skB, context)
+	if err != nil {
+		return err
+	}
+	d.compare("pkB", pkB, publicBlind)
+
+	blindedKey, err := ed25519.BlindPublicKeyXMD(publicKey, skB, context)
+	if err != nil {
+		return err
+	}
+	d.compare("pkR", pkR, blindedKey)
+
+	actualSignature, err := ed25519.BlindKeySignXMD(privateKey, message, skB, context)
+	if err != nil {
+		return err
+	}
+	d.compare("signature", signature, actualSignature)
+
+	if !ed25519.Verify(blindedKey, message, signature) {
+		return fmt.Errorf("signature does not verify under the blinded public key")
+	}
+
+	return nil
+}
@@ -0,0 +1,396 @@
+package vectors
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/cloudflare/circl/oprf"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/tokens/type1"
+	"github.com/cloudflare/pat-go/tokens/type2"
+	"github.com/cloudflare/pat-go/tokens/typeF91A"
+	"github.com/cloudflare/pat-go/util"
+)
+
+type rawBasicPrivateIssuanceTestVector struct {
+	PrivateKey    string `json:"skS"`
+	PublicKey     string `json:"pkS"`
+	Challenge     string `json:"token_challenge"`
+	Nonce         string `json:"nonce"`
+	Blind         string `json:"blind"`
+	TokenRequest  string `json:"token_request"`
+	TokenResponse string `json:"token_response"`
+	Token         string `json:"token"`
+}
+
+type rawBasicPublicIssuanceTestVector struct {
+	PrivateKey    string `json:"skS"`
+	PublicKey     string `json:"pkS"`
+	Challenge     string `json:"token_challenge"`
+	Nonce         string `json:"nonce"`
+	Blind         string `json:"blind"`
+	Salt          string `json:"salt"`
+	TokenRequest  string `json:"token_request"`
+	TokenResponse string `json:"token_response"`
+	Token         string `json:"token"`
+}
+
+type rawBatchedPrivateIssuanceTestVector struct {
+	PrivateKey    string   `json:"skS"`
+	PublicKey     string   `json:"pkS"`
+	Challenge     string   `json:"token_challenge"`
+	Nonces        []string `json:"nonces"`
+	Blinds        []string `json:"blinds"`
+	TokenRequest  string   `json:"token_request"`
+	TokenResponse string   `json:"token_response"`
+	Tokens        []string `json:"tokens"`
+}
+
+var (
+	basicPrivateIssuanceFamily   = newFamily("type1", "type1-issuance-test-vectors.json", generateBasicPrivateIssuanceTestVectors, verifyBasicPrivateIssuanceTestVector)
+	basicPublicIssuanceFamily    = newFamily("type2", "type2-issuance-test-vectors.json", generateBasicPublicIssuanceTestVectors, verifyBasicPublicIssuanceTestVector)
+	batchedPrivateIssuanceFamily = newFamily("typeF91A", "typeF91A-issuance-test-vectors.json", generateBatchedPrivateIssuanceTestVectors, verifyBatchedPrivateIssuanceTestVector)
+)
+
+// issuanceChallenges returns the challenges each issuance family produces a
+// vector for. The redemption context is derived from a fixed secret so that
+// it is stable across runs.
+func issuanceChallenges(tokenType uint16) ([][]byte, error) {
+	secret := []byte("test vector secret")
+	kdf := hkdf.New(sha256.New, secret, nil, []byte{0x00, byte(tokenType & 0xFF)})
+
+	redemptionContext := make([]byte, 32)
+	kdf.Read(redemptionContext)
+
+	challenges := []tokens.TokenChallenge{
+		newTestTokenChallenge(tokenType, redemptionContext, "issuer.example", []string{"origin.example"}),
+		newTestTokenChallenge(tokenType, nil, "issuer.example", []string{"origin.example"}),
+		newTestTokenChallenge(tokenType, nil, "issuer.example", []string{"foo.example", "bar.example"}),
+		newTestTokenChallenge(tokenType, nil, "issuer.example", []string{}),
+		newTestTokenChallenge(tokenType, redemptionContext, "issuer.example", []string{}),
+	}
+
+	challengeEncs := make([][]byte, len(challenges))
+	for i := range challenges {
+		challengeEnc, err := challenges[i].Marshal()
+		if err != nil {
+			return nil, err
+		}
+		challengeEncs[i] = challengeEnc
+	}
+	return challengeEncs, nil
+}
+
+func randomNonce() []byte {
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+	return nonce
+}
+
+func unmarshalOPRFKey(suite oprf.Suite, data []byte) (*oprf.PrivateKey, error) {
+	key := new(oprf.PrivateKey)
+	if err := key.UnmarshalBinary(suite, data); err != nil {
+		return nil, fmt.Errorf("invalid skS: %v", err)
+	}
+	return key, nil
+}
+
+func generateBasicPrivateIssuanceTestVectors() ([]rawBasicPrivateIssuanceTestVector, error) {
+	challenges, err := issuanceChallenges(type1.BasicPrivateTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([]rawBasicPrivateIssuanceTestVector, len(challenges))
+	for i, challenge := range challenges {
+		tokenKey, err := oprf.DeriveKey(oprf.SuiteP384, oprf.VerifiableMode, []byte("fixed seed"), challenge)
+		if err != nil {
+			return nil, err
+		}
+		issuer := type1.NewBasicPrivateIssuer(tokenKey)
+		nonce := randomNonce()
+
+		requestState, err := type1.NewBasicPrivateClient().CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+		if err != nil {
+			return nil, err
+		}
+		tokenResponse, err := issuer.Evaluate(requestState.Request())
+		if err != nil {
+			return nil, err
+		}
+		token, err := requestState.FinalizeToken(tokenResponse)
+		if err != nil {
+			return nil, err
+		}
+		blind, err := requestState.Blind()
+		if err != nil {
+			return nil, err
+		}
+
+		vectors[i] = rawBasicPrivateIssuanceTestVector{
+			PrivateKey:    hex.EncodeToString(util.MustMarshalPrivateOPRFKey(tokenKey)),
+			PublicKey:     hex.EncodeToString(util.MustMarshalPublicOPRFKey(tokenKey.Public())),
+			Challenge:     hex.EncodeToString(challenge),
+			Nonce:         hex.EncodeToString(nonce),
+			Blind:         hex.EncodeToString(blind),
+			TokenRequest:  hex.EncodeToString(requestState.Request().Marshal()),
+			TokenResponse: hex.EncodeToString(tokenResponse),
+			Token:         hex.EncodeToString(token.Marshal()),
+		}
+	}
+
+	return vectors, nil
+}
+
+// verifyBasicPrivateIssuanceTestVector replays the token request with the
+// vector's blind and finalizes the vector's token response, which checks its
+// DLEQ proof. The response is randomized, so it is not recomputed; the token it
+// finalizes to is compared instead.
+func verifyBasicPrivateIssuanceTestVector(vector rawBasicPrivateIssuanceTestVector, d *differ) error {
+	h := &hexDecoder{}
+	skS := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	challenge := h.decode("token_challenge", vector.Challenge)
+	nonce := h.decode("nonce", vector.Nonce)
+	blind := h.decode("blind", vector.Blind)
+	tokenRequest := h.decode("token_request", vector.TokenRequest)
+	tokenResponse := h.decode("token_response", vector.TokenResponse)
+	token := h.decode("token", vector.Token)
+	if h.err != nil {
+		return h.err
+	}
+
+	tokenKey, err := unmarshalOPRFKey(oprf.SuiteP384, skS)
+	if err != nil {
+		return err
+	}
+	d.compare("pkS", pkS, util.MustMarshalPublicOPRFKey(tokenKey.Public()))
+
+	issuer := type1.NewBasicPrivateIssuer(tokenKey)
+	requestState, err := type1.NewBasicPrivateClient().CreateTokenRequestWithBlind(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey(), blind)
+	if err != nil {
+		return err
+	}
+	d.compare("token_request", tokenRequest, requestState.Request().Marshal())
+
+	actualToken, err := requestState.FinalizeToken(tokenResponse)
+	if err != nil {
+		return fmt.Errorf("invalid token_response: %v", err)
+	}
+	d.compare("token", token, actualToken.Marshal())
+
+	return nil
+}
+
+func generateBasicPublicIssuanceTestVectors() ([]rawBasicPublicIssuanceTestVector, error) {
+	challenges, err := issuanceChallenges(type2.BasicPublicTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors []rawBasicPublicIssuanceTestVector
+	for _, bits := range testTokenKeyBits {
+		tokenKey, err := loadTestTokenKeyWithSize(bits)
+		if err != nil {
+			return nil, err
+		}
+		sizeVectors, err := generateBasicPublicIssuanceTestVectorsWithKey(tokenKey, challenges)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, sizeVectors...)
+	}
+
+	return vectors, nil
+}
+
+func generateBasicPublicIssuanceTestVectorsWithKey(tokenKey *rsa.PrivateKey, challenges [][]byte) ([]rawBasicPublicIssuanceTestVector, error) {
+	issuer := type2.NewBasicPublicIssuer(tokenKey)
+
+	vectors := make([]rawBasicPublicIssuanceTestVector, len(challenges))
+	for i, challenge := range challenges {
+		nonce := randomNonce()
+
+		requestState, err := type2.NewBasicPublicClient().CreateTokenRequest(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey())
+		if err != nil {
+			return nil, err
+		}
+		tokenResponse, err := issuer.Evaluate(requestState.Request())
+		if err != nil {
+			return nil, err
+		}
+		token, err := requestState.FinalizeToken(tokenResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		vectors[i] = rawBasicPublicIssuanceTestVector{
+			PrivateKey:    hex.EncodeToString(util.MustMarshalPrivateKey(tokenKey)),
+			PublicKey:     hex.EncodeToString(util.MustMarshalPublicKey(&tokenKey.PublicKey)),
+			Challenge:     hex.EncodeToString(challenge),
+			Nonce:         hex.EncodeToString(nonce),
+			Blind:         hex.EncodeToString(requestState.Blind()),
+			Salt:          hex.EncodeToString(requestState.Salt()),
+			TokenRequest:  hex.EncodeToString(requestState.Request().Marshal()),
+			TokenResponse: hex.EncodeToString(tokenResponse),
+			Token:         hex.EncodeToString(token.Marshal()),
+		}
+	}
+
+	return vectors, nil
+}
+
+func verifyBasicPublicIssuanceTestVector(vector rawBasicPublicIssuanceTestVector, d *differ) error {
+	h := &hexDecoder{}
+	skS := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	challenge := h.decode("token_challenge", vector.Challenge)
+	nonce := h.decode("nonce", vector.Nonce)
+	blind := h.decode("blind", vector.Blind)
+	salt := h.decode("salt", vector.Salt)
+	tokenRequest := h.decode("token_request", vector.TokenRequest)
+	tokenResponse := h.decode("token_response", vector.TokenResponse)
+	token := h.decode("token", vector.Token)
+	if h.err != nil {
+		return h.err
+	}
+
+	block, _ := pem.Decode(skS)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return fmt.Errorf("invalid skS: expected a PEM-encoded PKCS #8 private key")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid skS: %v", err)
+	}
+	tokenKey, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("invalid skS: not an RSA private key")
+	}
+	d.compare("pkS", pkS, util.MustMarshalPublicKey(&tokenKey.PublicKey))
+
+	issuer := type2.NewBasicPublicIssuer(tokenKey)
+	requestState, err := type2.NewBasicPublicClient().CreateTokenRequestWithBlind(challenge, nonce, issuer.TokenKeyID(), issuer.TokenKey(), blind, salt)
+	if err != nil {
+		return err
+	}
+	d.compare("token_request", tokenRequest, requestState.Request().Marshal())
+
+	actualResponse, err := issuer.Evaluate(requestState.Request())
+	if err != nil {
+		return err
+	}
+	d.compare("token_response", tokenResponse, actualResponse)
+
+	actualToken, err := requestState.FinalizeToken(actualResponse)
+	if err != nil {
+		return err
+	}
+	d.compare("token", token, actualToken.Marshal())
+
+	return nil
+}
+
+func generateBatchedPrivateIssuanceTestVectors() ([]rawBatchedPrivateIssuanceTestVector, error) {
+	challenges, err := issuanceChallenges(typeF91A.BatchedPrivateTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([]rawBatchedPrivateIssuanceTestVector, len(challenges))
+	for i, challenge := range challenges {
+		tokenKey, err := oprf.DeriveKey(oprf.SuiteRistretto255, oprf.VerifiableMode, []byte("fixed seed"), challenge)
+		if err != nil {
+			return nil, err
+		}
+		issuer := typeF91A.NewBatchedPrivateIssuer(tokenKey)
+		nonces := [][]byte{randomNonce(), randomNonce(), randomNonce()}
+
+		requestState, err := typeF91A.NewBatchedPrivateClient().CreateTokenRequest(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey())
+		if err != nil {
+			return nil, err
+		}
+		tokenResponse, err := issuer.Evaluate(requestState.Request())
+		if err != nil {
+			return nil, err
+		}
+		issuedTokens, err := requestState.FinalizeTokens(tokenResponse)
+		if err != nil {
+			return nil, err
+		}
+		blinds, err := requestState.Blinds()
+		if err != nil {
+			return nil, err
+		}
+
+		tokenEncs := make([][]byte, len(issuedTokens))
+		for j := range issuedTokens {
+			tokenEncs[j] = issuedTokens[j].Marshal()
+		}
+
+		vectors[i] = rawBatchedPrivateIssuanceTestVector{
+			PrivateKey:    hex.EncodeToString(util.MustMarshalPrivateOPRFKey(tokenKey)),
+			PublicKey:     hex.EncodeToString(util.MustMarshalPublicOPRFKey(tokenKey.Public())),
+			Challenge:     hex.EncodeToString(challenge),
+			Nonces:        hexList(nonces),
+			Blinds:        hexList(blinds),
+			TokenRequest:  hex.EncodeToString(requestState.Request().Marshal()),
+			TokenResponse: hex.EncodeToString(tokenResponse),
+			Tokens:        hexList(tokenEncs),
+		}
+	}
+
+	return vectors, nil
+}
+
+// verifyBatchedPrivateIssuanceTestVector replays the token request with the
+// vector's blinds and finalizes the vector's token response. As with type
+// 0x0001, the response is randomized and only the resulting tokens are
+// compared.
+func verifyBatchedPrivateIssuanceTestVector(vector rawBatchedPrivateIssuanceTestVector, d *differ) error {
+	h := &hexDecoder{}
+	skS := h.decode("skS", vector.PrivateKey)
+	pkS := h.decode("pkS", vector.PublicKey)
+	challenge := h.decode("token_challenge", vector.Challenge)
+	nonces := h.decodeList("nonces", vector.Nonces)
+	blinds := h.decodeList("blinds", vector.Blinds)
+	tokenRequest := h.decode("token_request", vector.TokenRequest)
+	tokenResponse := h.decode("token_response", vector.TokenResponse)
+	expectedTokens := h.decodeList("tokens", vector.Tokens)
+	if h.err != nil {
+		return h.err
+	}
+	if len(expectedTokens) != len(nonces) {
+		return fmt.Errorf("vector has %d nonces but %d tokens", len(nonces), len(expectedTokens))
+	}
+
+	tokenKey, err := unmarshalOPRFKey(oprf.SuiteRistretto255, skS)
+	if err != nil {
+		return err
+	}
+	d.compare("pkS", pkS, util.MustMarshalPublicOPRFKey(tokenKey.Public()))
+
+	issuer := typeF91A.NewBatchedPrivateIssuer(tokenKey)
+	requestState, err := typeF91A.NewBatchedPrivateClient().CreateTokenRequestWithBlinds(challenge, nonces, issuer.TokenKeyID(), issuer.TokenKey(), blinds)
+	if err != nil {
+		return err
+	}
+	d.compare("token_request", tokenRequest, requestState.Request().Marshal())
+
+	actualTokens, err := requestState.FinalizeTokens(tokenResponse)
+	if err != nil {
+		return fmt.Errorf("invalid token_response: %v", err)
+	}
+	for i := range actualTokens {
+		d.compare(fmt.Sprintf("tokens[%d]", i), expectedTokens[i], actualTokens[i].Marshal())
+	}
+
+	return nil
+}
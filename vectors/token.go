@@ -0,0 +1,165 @@
+package vectors
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/pat-go/tokens"
+	"github.com/cloudflare/pat-go/util"
+)
+
+type rawTokenTestVector struct {
+	Comment                 string `json:"comment"`
+	TokenType               string `json:"token_type"`
+	IssuerName              string `json:"issuer_name"`
+	RedemptionContext       string `json:"redemption_context"`
+	OriginInfo              string `json:"origin_info"`
+	Nonce                   string `json:"nonce"`
+	TokenKeyId              string `json:"token_key_id"`
+	TokenAuthenticatorInput string `json:"token_authenticator_input"`
+}
+
+var tokenFamily = newFamily("token", "token-test-vectors.json", generateTokenTestVectors, verifyTokenTestVector)
+
+func wrapString(prefix, text string, lineWidth int) string {
+	words := strings.Fields(strings.TrimSpace(text))
+	if len(words) == 0 {
+		return text
+	}
+	wrapped := prefix + " " + words[0]
+	spaceLeft := lineWidth - len(wrapped)
+	for _, word := range words[1:] {
+		if len(word)+1 > spaceLeft {
+			wrapped += "\n" + prefix + " " + word
+			spaceLeft = lineWidth - len(word)
+		} else {
+			wrapped += " " + word
+			spaceLeft -= 1 + len(word)
+		}
+	}
+	return wrapped
+}
+
+func newTestTokenChallenge(tokenType uint16, redemptionContext []byte, issuerName string, originInfo []string) tokens.TokenChallenge {
+	challenge := tokens.TokenChallenge{
+		TokenType:       tokenType,
+		RedemptionNonce: make([]byte, len(redemptionContext)),
+		IssuerName:      issuerName,
+		OriginInfo:      originInfo,
+	}
+	copy(challenge.RedemptionNonce, redemptionContext)
+	return challenge
+}
+
+func tokenAuthenticatorInput(tokenType uint16, redemptionContext []byte, issuerName string, originInfo []string, nonce, tokenKeyID []byte) ([]byte, error) {
+	challenge := newTestTokenChallenge(tokenType, redemptionContext, issuerName, originInfo)
+	challengeEnc, err := challenge.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	context := sha256.Sum256(challengeEnc)
+	token := tokens.Token{
+		TokenType:     tokenType,
+		Nonce:         nonce,
+		Context:       context[:],
+		KeyID:         tokenKeyID,
+		Authenticator: nil, // No signature computed yet
+	}
+	return token.AuthenticatorInput(), nil
+}
+
+func generateTokenTestVectors() ([]rawTokenTestVector, error) {
+	redemptionContext := make([]byte, 32)
+	rand.Reader.Read(redemptionContext)
+
+	nonce := make([]byte, 32)
+	rand.Reader.Read(nonce)
+
+	tokenSigningKey, err := loadTestTokenKey()
+	if err != nil {
+		return nil, err
+	}
+	tokenKeyID := sha256.Sum256(util.MustMarshalPublicKey(&tokenSigningKey.PublicKey))
+
+	issuerName := "issuer.example"
+	singleOriginInfo := []string{"origin.example"}
+	multipleOriginInfo := []string{"foo.example", "bar.example"}
+
+	vectorInputs := []struct {
+		redemptionContext []byte
+		originInfo        []string
+	}{
+		{redemptionContext, singleOriginInfo},
+		{nil, singleOriginInfo},
+		{nil, nil},
+		{redemptionContext, nil},
+		{redemptionContext, multipleOriginInfo},
+	}
+
+	vectors := make([]rawTokenTestVector, len(vectorInputs))
+	for i, input := range vectorInputs {
+		tokenType := uint16(0x0002)
+		authenticatorInput, err := tokenAuthenticatorInput(tokenType, input.redemptionContext, issuerName, input.originInfo, nonce, tokenKeyID[:])
+		if err != nil {
+			return nil, err
+		}
+
+		contextComment := "empty"
+		if len(input.redemptionContext) > 0 {
+			contextComment = "non-empty"
+		}
+		originInfo := strings.Join(input.originInfo, ",")
+		comment := wrapString("//  ", fmt.Sprintf("token_type(%04x), issuer_name(%s), origin_info(%s), redemption_context(%s)", tokenType, issuerName, originInfo, contextComment), 65)
+
+		vectors[i] = rawTokenTestVector{
+			Comment:                 comment,
+			TokenType:               fmt.Sprintf("%04x", tokenType),
+			IssuerName:              hex.EncodeToString([]byte(issuerName)),
+			RedemptionContext:       hex.EncodeToString(input.redemptionContext),
+			OriginInfo:              hex.EncodeToString([]byte(originInfo)),
+			Nonce:                   hex.EncodeToString(nonce),
+			TokenKeyId:              hex.EncodeToString(tokenKeyID[:]),
+			TokenAuthenticatorInput: hex.EncodeToString(authenticatorInput),
+		}
+	}
+
+	return vectors, nil
+}
+
+func verifyTokenTestVector(vector rawTokenTestVector, d *differ) error {
+	tokenType, err := strconv.ParseUint(vector.TokenType, 16, 16)
+	if err != nil {
+		return fmt.Errorf("invalid token_type: %v", err)
+	}
+	if tokenType != 0x0002 && tokenType != 0x0003 {
+		return fmt.Errorf("unsupported token type %04x", tokenType)
+	}
+
+	h := &hexDecoder{}
+	issuerName := h.decode("issuer_name", vector.IssuerName)
+	redemptionContext := h.decode("redemption_context", vector.RedemptionContext)
+	originInfo := h.decode("origin_info", vector.OriginInfo)
+	nonce := h.decode("nonce", vector.Nonce)
+	tokenKeyID := h.decode("token_key_id", vector.TokenKeyId)
+	expected := h.decode("token_authenticator_input", vector.TokenAuthenticatorInput)
+	if h.err != nil {
+		return h.err
+	}
+
+	var origins []string
+	if len(originInfo) > 0 {
+		origins = strings.Split(string(originInfo), ",")
+	}
+
+	authenticatorInput, err := tokenAuthenticatorInput(uint16(tokenType), redemptionContext, string(issuerName), origins, nonce, tokenKeyID)
+	if err != nil {
+		return err
+	}
+	d.compare("token_authenticator_input", expected, authenticatorInput)
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package vectors
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	hpke "github.com/cisco/go-hpke"
+
+	"github.com/cloudflare/pat-go/ecdsa"
+	"github.com/cloudflare/pat-go/tokens/type3"
+	"github.com/cloudflare/pat-go/util"
+)
+
+type rawOriginEncryptionTestVector struct {
+	KEMID                 hpke.KEMID  `json:"kem_id"`
+	KDFID                 hpke.KDFID  `json:"kdf_id"`
+	AEADID                hpke.AEADID `json:"aead_id"`
+	OriginNameKeySeed     string      `json:"issuer_encap_key_seed"`
+	OriginNameKey         string      `json:"issuer_encap_key"`
+	TokenType             uint16      `json:"token_type"`
+	OriginNameKeyID       string      `json:"issuer_encap_key_id"`
+	RequestKey            string      `json:"request_key"`
+	TokenKeyID            uint8       `json:"token_key_id"`
+	BlindMessage          string      `json:"blinded_msg"`
+	OriginName            string      `json:"origin_name"`
+	OriginNamePadding     string      `json:"origin_name_padding"`
+	EncapSecret           string      `json:"encap_secret"`
+	EncryptedTokenRequest string      `json:"encrypted_token_request"`
+}
+
+type rawAnonOriginIDTestVector struct {
+	ClientSecret  string `json:"sk_sign"`
+	ClientPublic  string `json:"pk_sign"`
+	OriginSecret  string `json:"sk_origin"`
+	RequestBlind  string `json:"request_blind"`
+	IndexRequest  string `json:"request_key"`
+	IndexResponse string `json:"index_key"`
+	Index         string `json:"issuer_origin_alias"`
+}
+
+var (
+	originEncryptionFamily = newFamily("origin-encryption", "type3-origin-encryption-test-vectors.json", generateOriginEncryptionTestVectors, verifyOriginEncryptionTestVector)
+	anonOriginIDFamily     = newFamily("anon-origin-id", "type3-anon-origin-id-test-vectors.json", generateAnonOriginIDTestVectors, verifyAnonOriginIDTestVector)
+)
+
+func generateOriginEncryptionTestVectors() ([]rawOriginEncryptionTestVector, error) {
+	originNames := []string{
+		"test.example",
+		"a-considerably-longer-origin-name-used-to-cross-padding-boundaries.test.example",
+	}
+
+	var vectors []rawOriginEncryptionTestVector
+	for _, padding := range type3.OriginNamePaddingPolicies() {
+		for _, originName := range originNames {
+			for _, bits := range testTokenKeyBits {
+				vector, err := generateOriginEncryptionTestVector(originName, padding, bits/8)
+				if err != nil {
+					return nil, err
+				}
+				vectors = append(vectors, vector)
+			}
+		}
+	}
+
+	return vectors, nil
+}
+
+func generateOriginEncryptionTestVector(originName string, padding type3.OriginNamePadding, tokenKeySize int) (rawOriginEncryptionTestVector, error) {
+	seed := make([]byte, 32)
+	rand.Reader.Read(seed)
+	nameKey, err := type3.CreatePrivateEncapKeyFromSeed(seed)
+	if err != nil {
+		return rawOriginEncryptionTestVector{}, err
+	}
+
+	// Generate random token and index requests
+	requestKey := make([]byte, 49)
+	rand.Reader.Read(requestKey)
+	tokenKeyID := []byte{0x00}
+	rand.Reader.Read(tokenKeyID)
+	blindMessage := make([]byte, tokenKeySize)
+	rand.Reader.Read(blindMessage)
+
+	nameKeyID, encryptedTokenRequest, secret, err := type3.EncryptOriginTokenRequest(nameKey.Public(), tokenKeyID[0], blindMessage, requestKey, originName, padding)
+	if err != nil {
+		return rawOriginEncryptionTestVector{}, err
+	}
+
+	return rawOriginEncryptionTestVector{
+		KEMID:                 hpke.DHKEM_X25519,
+		KDFID:                 hpke.KDF_HKDF_SHA256,
+		AEADID:                hpke.AEAD_AESGCM128,
+		OriginNameKeySeed:     hex.EncodeToString(seed),
+		OriginNameKey:         hex.EncodeToString(nameKey.Public().Marshal()),
+		TokenType:             type3.RateLimitedTokenType,
+		OriginNameKeyID:       hex.EncodeToString(nameKeyID),
+		RequestKey:            hex.EncodeToString(requestKey),
+		TokenKeyID:            tokenKeyID[0],
+		BlindMessage:          hex.EncodeToString(blindMessage),
+		OriginName:            hex.EncodeToString([]byte(originName)),
+		OriginNamePadding:     padding.String(),
+		EncapSecret:           hex.EncodeToString(secret),
+		EncryptedTokenRequest: hex.EncodeToString(encryptedTokenRequest),
+	}, nil
+}
+
+// verifyOriginEncryptionTestVector decrypts the vector's encrypted token
+// request with the name key derived from its seed. Encryption is randomized,
+// so the ciphertext cannot be reproduced; the inner request and encapsulated
+// secret recovered from it are compared instead.
+func verifyOriginEncryptionTestVector(vector rawOriginEncryptionTestVector, d *differ) error {
+	if vector.TokenType != type3.RateLimitedTokenType {
+		return fmt.Errorf("unsupported token type %04x", vector.TokenType)
+	}
+	if vector.KEMID != hpke.DHKEM_X25519 ||
+		vector.KDFID != hpke.KDF_HKDF_SHA256 ||
+		vector.AEADID != hpke.AEAD_AESGCM128 {
+		return fmt.Errorf("unsupported ciphersuite (%04x, %04x, %04x)", vector.KEMID, vector.KDFID, vector.AEADID)
+	}
+
+	h := &hexDecoder{}
+	seed := h.decode("issuer_encap_key_seed", vector.OriginNameKeySeed)
+	nameKeyEnc := h.decode("issuer_encap_key", vector.OriginNameKey)
+	nameKeyID := h.decode("issuer_encap_key_id", vector.OriginNameKeyID)
+	requestKey := h.decode("request_key", vector.RequestKey)
+	blindMessage := h.decode("blinded_msg", vector.BlindMessage)
+	originName := h.decode("origin_name", vector.OriginName)
+	encapSecret := h.decode("encap_secret", vector.EncapSecret)
+	encryptedTokenRequest := h.decode("encrypted_token_request", vector.EncryptedTokenRequest)
+	if h.err != nil {
+		return h.err
+	}
+
+	padding := type3.PadOriginNameBlock32
+	if vector.OriginNamePadding != "" {
+		var err error
+		padding, err = type3.ParseOriginNamePadding(vector.OriginNamePadding)
+		if err != nil {
+			return err
+		}
+	}
+
+	nameKey, err := type3.CreatePrivateEncapKeyFromSeed(seed)
+	if err != nil {
+		return err
+	}
+	actualNameKeyEnc := nameKey.Public().Marshal()
+	actualNameKeyID := sha256.Sum256(actualNameKeyEnc)
+	d.compare("issuer_encap_key", nameKeyEnc, actualNameKeyEnc)
+	d.compare("issuer_encap_key_id", nameKeyID, actualNameKeyID[:])
+
+	if !util.IsSupportedTokenKeySize(len(blindMessage)) {
+		return fmt.Errorf("unsupported blinded_msg length %d", len(blindMessage))
+	}
+	innerRequest, actualEncapSecret, err := type3.DecryptOriginTokenRequestWithKeySize(nameKey, len(blindMessage), requestKey, encryptedTokenRequest)
+	if err != nil {
+		return err
+	}
+	d.compare("encap_secret", encapSecret, actualEncapSecret)
+	d.compare("token_key_id", []byte{vector.TokenKeyID}, []byte{innerRequest.TokenKeyID()})
+	d.compare("blinded_msg", blindMessage, innerRequest.BlindedMessage())
+
+	actualOriginName, err := innerRequest.OriginName()
+	if err != nil {
+		return err
+	}
+	d.compare("origin_name", originName, []byte(actualOriginName))
+
+	expectedPaddedLength, err := padding.PaddedLength(len(originName))
+	if err != nil {
+		return err
+	}
+	if paddedLength := len(innerRequest.PaddedOriginName()); paddedLength != expectedPaddedLength {
+		d.compareString("origin_name_padding", fmt.Sprintf("%s (%d bytes)", padding, expectedPaddedLength), fmt.Sprintf("%d bytes", paddedLength))
+	}
+
+	return nil
+}
+
+func generateAnonOriginIDTestVectors() ([]rawAnonOriginIDTestVector, error) {
+	curve := elliptic.P384()
+	clientSecretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	originSecretKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	requestBlind, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	requestKeyEnc, blindedRequestKeyEnc, index, err := computeAnonOriginID(curve, clientSecretKey, originSecretKey, requestBlind)
+	if err != nil {
+		return nil, err
+	}
+
+	return []rawAnonOriginIDTestVector{{
+		ClientSecret:  hex.EncodeToString(clientSecretKey.D.Bytes()),
+		ClientPublic:  hex.EncodeToString(elliptic.MarshalCompressed(curve, clientSecretKey.X, clientSecretKey.Y)),
+		OriginSecret:  hex.EncodeToString(originSecretKey.D.Bytes()),
+		RequestBlind:  hex.EncodeToString(requestBlind.D.Bytes()),
+		IndexRequest:  hex.EncodeToString(requestKeyEnc),
+		IndexResponse: hex.EncodeToString(blindedRequestKeyEnc),
+		Index:         hex.EncodeToString(index),
+	}}, nil
+}
+
+// computeAnonOriginID runs the client, attester and issuer steps of index
+// computation, returning the encoded request key, the issuer's blinded
+// response, and the resulting anonymous issuer origin ID.
+func computeAnonOriginID(curve elliptic.Curve, clientSecretKey, originSecretKey, requestBlind *ecdsa.PrivateKey) ([]byte, []byte, []byte, error) {
+	requestKey, err := ecdsa.BlindPublicKey(curve, &clientSecretKey.PublicKey, requestBlind)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	blindedRequestKey, err := ecdsa.BlindPublicKey(curve, requestKey, originSecretKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	indexKey, err := ecdsa.UnblindPublicKey(curve, blindedRequestKey, requestBlind)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	requestKeyEnc := elliptic.MarshalCompressed(curve, requestKey.X, requestKey.Y)
+	blindedRequestKeyEnc := elliptic.MarshalCompressed(curve, blindedRequestKey.X, blindedRequestKey.Y)
+	clientPublicKeyEnc := elliptic.MarshalCompressed(curve, clientSecretKey.X, clientSecretKey.Y)
+	indexKeyEnc := elliptic.MarshalCompressed(curve, indexKey.X, indexKey.Y)
+
+	index, err := type3.ComputeIndex(clientPublicKeyEnc, indexKeyEnc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return requestKeyEnc, blindedRequestKeyEnc, index, nil
+}
+
+func verifyAnonOriginIDTestVector(vector rawAnonOriginIDTestVector, d *differ) error {
+	h := &hexDecoder{}
+	clientSecret := h.decode("sk_sign", vector.ClientSecret)
+	clientPublic := h.decode("pk_sign", vector.ClientPublic)
+	originSecret := h.decode("sk_origin", vector.OriginSecret)
+	blind := h.decode("request_blind", vector.RequestBlind)
+	indexRequest := h.decode("request_key", vector.IndexRequest)
+	indexResponse := h.decode("index_key", vector.IndexResponse)
+	index := h.decode("issuer_origin_alias", vector.Index)
+	if h.err != nil {
+		return h.err
+	}
+
+	curve := elliptic.P384()
+	clientSecretKey, err := ecdsa.CreateKey(curve, clientSecret)
+	if err != nil {
+		return fmt.Errorf("invalid sk_sign: %v", err)
+	}
+	originSecretKey, err := ecdsa.CreateKey(curve, originSecret)
+	if err != nil {
+		return fmt.Errorf("invalid sk_origin: %v", err)
+	}
+	requestBlind, err := ecdsa.CreateKey(curve, blind)
+	if err != nil {
+		return fmt.Errorf("invalid request_blind: %v", err)
+	}
+	d.compare("pk_sign", clientPublic, elliptic.MarshalCompressed(curve, clientSecretKey.X, clientSecretKey.Y))
+
+	requestKeyEnc, blindedRequestKeyEnc, actualIndex, err := computeAnonOriginID(curve, clientSecretKey, originSecretKey, requestBlind)
+	if err != nil {
+		return err
+	}
+	d.compare("request_key", indexRequest, requestKeyEnc)
+	d.compare("index_key", indexResponse, blindedRequestKeyEnc)
+	d.compare("issuer_origin_alias", index, actualIndex)
+
+	return nil
+}
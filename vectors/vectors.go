@@ -0,0 +1,236 @@
+// Package vectors generates and verifies the JSON test vectors published for
+// the token types and key blinding schemes implemented in this module.
+//
+// Each vector family is written to its own file, and a directory holding any
+// number of those files can be checked in one pass with VerifyDir. Verification
+// recomputes every value that can be derived from a vector's inputs and reports
+// each mismatching field as a Diff, so a failing vector shows exactly where an
+// implementation disagrees.
+package vectors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Diff describes a field whose recomputed value does not match the vector.
+// Binary values are hex encoded.
+type Diff struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.Field, d.Expected, d.Actual)
+}
+
+// Result is the outcome of verifying a single vector. Index is the position of
+// the vector in its file, or -1 if the file as a whole could not be read or
+// decoded.
+type Result struct {
+	Family string
+	Index  int
+	Diffs  []Diff
+	Err    error
+}
+
+// Passed reports whether the vector was processed without error and every
+// recomputed field matched.
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// Family is a set of test vectors that share a JSON format and the code used
+// to produce and check them.
+type Family struct {
+	// Name identifies the family, e.g. "type2".
+	Name string
+	// FileName is the name of the file the family is written to.
+	FileName string
+
+	generate func() ([]byte, error)
+	verify   func(data []byte) []Result
+}
+
+// Generate produces a fresh set of vectors and returns their JSON encoding.
+func (f Family) Generate() ([]byte, error) {
+	return f.generate()
+}
+
+// Verify checks every vector in the JSON-encoded data and returns one result
+// per vector.
+func (f Family) Verify(data []byte) []Result {
+	return f.verify(data)
+}
+
+// newFamily builds a Family from functions operating on the JSON form V of a
+// single vector. verify recomputes the vector's outputs, recording mismatches
+// in d, and returns an error if the vector could not be processed at all.
+func newFamily[V any](name, fileName string, generate func() ([]V, error), verify func(vector V, d *differ) error) Family {
+	return Family{
+		Name:     name,
+		FileName: fileName,
+		generate: func() ([]byte, error) {
+			vectors, err := generate()
+			if err != nil {
+				return nil, err
+			}
+			encoded, err := json.Marshal(vectors)
+			if err != nil {
+				return nil, err
+			}
+
+			// Make sure the vectors round trip before handing them out
+			for _, result := range verifyAll(name, encoded, verify) {
+				if !result.Passed() {
+					return nil, fmt.Errorf("generated %s vector %d does not verify", name, result.Index)
+				}
+			}
+			return encoded, nil
+		},
+		verify: func(data []byte) []Result {
+			return verifyAll(name, data, verify)
+		},
+	}
+}
+
+func verifyAll[V any](name string, data []byte, verify func(vector V, d *differ) error) []Result {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []Result{{Family: name, Index: -1, Err: fmt.Errorf("decoding vectors: %v", err)}}
+	}
+	if len(raw) == 0 {
+		return []Result{{Family: name, Index: -1, Err: fmt.Errorf("no vectors to verify")}}
+	}
+
+	results := make([]Result, len(raw))
+	for i := range raw {
+		results[i] = Result{Family: name, Index: i}
+
+		var vector V
+		if err := json.Unmarshal(raw[i], &vector); err != nil {
+			results[i].Err = fmt.Errorf("decoding vector: %v", err)
+			continue
+		}
+
+		d := &differ{}
+		results[i].Err = verify(vector, d)
+		results[i].Diffs = d.diffs
+	}
+	return results
+}
+
+// differ accumulates the fields of a vector that do not match their
+// recomputed values.
+type differ struct {
+	diffs []Diff
+}
+
+func (d *differ) compare(field string, expected, actual []byte) {
+	if !bytes.Equal(expected, actual) {
+		d.diffs = append(d.diffs, Diff{field, hex.EncodeToString(expected), hex.EncodeToString(actual)})
+	}
+}
+
+func (d *differ) compareString(field, expected, actual string) {
+	if expected != actual {
+		d.diffs = append(d.diffs, Diff{field, expected, actual})
+	}
+}
+
+// hexDecoder decodes hex fields of a vector, remembering the first failure so
+// that a vector can be decoded without checking every field individually.
+type hexDecoder struct {
+	err error
+}
+
+func (h *hexDecoder) decode(field, value string) []byte {
+	out, err := hex.DecodeString(value)
+	if err != nil && h.err == nil {
+		h.err = fmt.Errorf("invalid %s: %v", field, err)
+	}
+	return out
+}
+
+func (h *hexDecoder) decodeList(field string, values []string) [][]byte {
+	out := make([][]byte, len(values))
+	for i := range values {
+		out[i] = h.decode(fmt.Sprintf("%s[%d]", field, i), values[i])
+	}
+	return out
+}
+
+func hexList(values [][]byte) []string {
+	out := make([]string, len(values))
+	for i := range values {
+		out[i] = hex.EncodeToString(values[i])
+	}
+	return out
+}
+
+// Families returns every vector family, in the order they are generated and
+// verified.
+func Families() []Family {
+	return []Family{
+		tokenFamily,
+		basicPrivateIssuanceFamily,
+		basicPublicIssuanceFamily,
+		batchedPrivateIssuanceFamily,
+		originEncryptionFamily,
+		anonOriginIDFamily,
+		ecdsaBlindingFamily,
+		ed25519BlindingFamily,
+		ed448BlindingFamily,
+		ed25519KeyBlindingFamily,
+	}
+}
+
+// Lookup returns the family with the given name.
+func Lookup(name string) (Family, error) {
+	for _, f := range Families() {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return Family{}, fmt.Errorf("unknown vector family %q", name)
+}
+
+// GenerateDir generates each of the given families and writes them to dir,
+// creating it if necessary.
+func GenerateDir(dir string, families []Family) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range families {
+		encoded, err := f.Generate()
+		if err != nil {
+			return fmt.Errorf("generating %s vectors: %v", f.Name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.FileName), encoded, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyDir verifies the file for each of the given families in dir. A missing
+// or malformed file is reported as a failed result for that family rather than
+// stopping verification of the others.
+func VerifyDir(dir string, families []Family) []Result {
+	var results []Result
+	for _, f := range families {
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.FileName))
+		if err != nil {
+			results = append(results, Result{Family: f.Name, Index: -1, Err: err})
+			continue
+		}
+		results = append(results, f.Verify(data)...)
+	}
+	return results
+}
@@ -0,0 +1,193 @@
+package vectors
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// publishedVectors maps each family to the vectors committed alongside the
+// package that implements it.
+var publishedVectors = map[string]string{
+	"token":                "../tokens/token-test-vectors.json",
+	"type1":                "../tokens/type1/type1-issuance-test-vectors.json",
+	"type2":                "../tokens/type2/type2-issuance-test-vectors.json",
+	"typeF91A":             "../tokens/typeF91A/typeF91A-issuance-test-vectors.json",
+	"origin-encryption":    "../tokens/type3/type3-origin-encryption-test-vectors.json",
+	"anon-origin-id":       "../tokens/type3/type3-anon-origin-id-test-vectors.json",
+	"ecdsa-blinding":       "../tokens/type3/type3-ecdsa-blinding-test-vectors.json",
+	"ed25519-blinding":     "../tokens/type3/type3-ed25519-blinding-test-vectors.json",
+	"ed448-blinding":       "../tokens/type3/type3-ed448-blinding-test-vectors.json",
+	"ed25519-key-blinding": "../ed25519/ed25519-key-blinding-test-vectors.json",
+}
+
+func checkResults(t *testing.T, results []Result) {
+	t.Helper()
+	if len(results) == 0 {
+		t.Fatal("no vectors verified")
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s vector %d: %v", result.Family, result.Index, result.Err)
+		}
+		for _, diff := range result.Diffs {
+			t.Errorf("%s vector %d: %s", result.Family, result.Index, diff)
+		}
+	}
+}
+
+func TestVerifyPublishedVectors(t *testing.T) {
+	for _, f := range Families() {
+		t.Run(f.Name, func(t *testing.T) {
+			path, ok := publishedVectors[f.Name]
+			if !ok {
+				t.Fatalf("no published vectors for %s", f.Name)
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResults(t, f.Verify(data))
+		})
+	}
+}
+
+func TestGenerateAndVerifyDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateDir(dir, Families()); err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, VerifyDir(dir, Families()))
+}
+
+func TestVerifyReportsDiffs(t *testing.T) {
+	f, err := Lookup("type2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors []map[string]interface{}
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	token := vectors[1]["token"].(string)
+	vectors[1]["token"] = token[:len(token)-2] + "00"
+	if token[len(token)-2:] == "00" {
+		vectors[1]["token"] = token[:len(token)-2] + "01"
+	}
+	tampered, err := json.Marshal(vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := f.Verify(tampered)
+	if len(results) != len(vectors) {
+		t.Fatalf("expected %d results, got %d", len(vectors), len(results))
+	}
+	for i, result := range results {
+		if i != 1 {
+			if !result.Passed() {
+				t.Fatalf("untampered vector %d failed: %v %v", i, result.Err, result.Diffs)
+			}
+			continue
+		}
+		if result.Passed() || len(result.Diffs) != 1 || result.Diffs[0].Field != "token" {
+			t.Fatalf("expected a token diff, got %v %v", result.Err, result.Diffs)
+		}
+		if result.Diffs[0].Expected != vectors[1]["token"] || result.Diffs[0].Actual != token {
+			t.Fatalf("unexpected diff %s", result.Diffs[0])
+		}
+	}
+}
+
+func TestVerifyDirReportsMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Lookup("anon-origin-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDir(dir, []Family{f}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, tokenFamily.FileName), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := VerifyDir(dir, []Family{f, tokenFamily, ed448BlindingFamily})
+	if len(results) != 3 || !results[0].Passed() {
+		t.Fatalf("unexpected results %v", results)
+	}
+	for _, result := range results[1:] {
+		if result.Passed() || result.Index != -1 {
+			t.Fatalf("expected a file-level failure, got %+v", result)
+		}
+	}
+	if !strings.Contains(results[1].Err.Error(), "decoding vectors") {
+		t.Fatalf("unexpected error %v", results[1].Err)
+	}
+
+	if _, err := Lookup("type4"); err == nil {
+		t.Fatal("looked up an unknown family")
+	}
+}
+
+// TestVerifyRejectsTamperedFields checks that fields which cannot be
+// recomputed, because they are randomized, are still checked against the
+// vector's other fields.
+func TestVerifyRejectsTamperedFields(t *testing.T) {
+	for _, test := range []struct {
+		family string
+		field  string
+	}{
+		{"type1", "token_response"},
+		{"typeF91A", "token_response"},
+		{"origin-encryption", "encap_secret"},
+	} {
+		t.Run(test.family+"/"+test.field, func(t *testing.T) {
+			f, err := Lookup(test.family)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := f.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var vectors []map[string]interface{}
+			if err := json.Unmarshal(data, &vectors); err != nil {
+				t.Fatal(err)
+			}
+			value := vectors[0][test.field].(string)
+			flipped := "0"
+			if value[len(value)-1] == '0' {
+				flipped = "1"
+			}
+			vectors[0][test.field] = value[:len(value)-1] + flipped
+			tampered, err := json.Marshal(vectors)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result := f.Verify(tampered)[0]; result.Passed() {
+				t.Fatalf("vector with tampered %s passed", test.field)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsEmptyFiles(t *testing.T) {
+	for _, f := range Families() {
+		for _, data := range []string{"[]", "null"} {
+			results := f.Verify([]byte(data))
+			if len(results) != 1 || results[0].Passed() {
+				t.Errorf("%s: %s verified as %v", f.Name, data, results)
+			}
+		}
+	}
+}